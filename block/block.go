@@ -185,6 +185,17 @@ func (bl *Block) GetTXSHash() (result crypto.Hash) {
 	return
 }
 
+// state root committed in Proof, it binds both balance tree and sc meta tree roots
+// xor of the roots cannot be used, since any balance root can be paired with a crafted meta root
+func StateRoot(balance_root, meta_root crypto.Hash) (result crypto.Hash) {
+	h := sha3.New256()
+	h.Write(balance_root[:])
+	h.Write(meta_root[:])
+	r := h.Sum(nil)
+	copy(result[:], r)
+	return
+}
+
 // parse entire block completely
 func (bl *Block) Deserialize(buf []byte) (err error) {
 	done := 0
@@ -47,6 +47,22 @@ func (c *MiniBlocksCollection) PurgeHeight(height int64) (purge_count int) {
 	return purge_count
 }
 
+// get all miniblocks upto this height, these are the ones which will be purged next
+func (c *MiniBlocksCollection) GetMiniBlocksTill(height int64) (mbls []MiniBlock) {
+	if height < 0 {
+		return
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	for k, v := range c.Collection {
+		if k.Height <= uint64(height) {
+			mbls = append(mbls, v...)
+		}
+	}
+	return
+}
+
 func (c *MiniBlocksCollection) Count() int {
 	c.RLock()
 	defer c.RUnlock()
@@ -88,6 +88,13 @@ type Blockchain struct {
 
 	simulator bool // is simulator mode
 
+	clock_skew int64 // time.Duration added to clock, lets tests run several nodes with different clocks in a process, accessed atomically
+
+	cache_block            block.Block     // block template handed out to miners, valid for 100 msec
+	cache_block_mutex      sync.Mutex      // protects cache_block
+	accept_lock            sync.Mutex      // only 1 mined block is accepted at a time
+	duplicate_height_check map[uint64]bool // heights at which we have already accepted a mined block
+
 	P2P_Block_Relayer     func(*block.Complete_Block, uint64) // tell p2p to broadcast any block this daemon hash found
 	P2P_MiniBlock_Relayer func(mbl block.MiniBlock, peerid uint64)
 
@@ -176,6 +183,7 @@ func Blockchain_Start(params map[string]interface{}) (*Blockchain, error) {
 	}
 
 	chain.Exit_Event = make(chan bool) // init exit channel
+	chain.duplicate_height_check = map[uint64]bool{}
 
 	// init mempool before chain starts
 	if chain.Mempool, err = mempool.Init_Mempool(params); err != nil {
@@ -263,6 +271,12 @@ try_again:
 	metrics.Version = config.Version.String()
 	go metrics.Dump_metrics_data_directly(logger, globals.Arguments["--node-tag"]) // enable metrics if someone needs them
 
+	metrics.Set.GetOrCreateGauge("blockchain_height", func() float64 { return float64(chain.Get_Height()) })
+	metrics.Set.GetOrCreateGauge("blockchain_topoheight", func() float64 { return float64(chain.Load_TOPO_HEIGHT()) })
+	metrics.Set.GetOrCreateGauge("blockchain_stable_height", func() float64 { return float64(chain.Get_Stable_Height()) })
+	metrics.Set.GetOrCreateGauge("blockchain_tips_count", func() float64 { return float64(len(chain.Get_TIPS())) })
+	metrics.Set.GetOrCreateGauge("miniblock_pool_count", func() float64 { return float64(chain.MiniBlocks.Count()) })
+
 	chain.Sync = true
 	if chain.Get_Height() <= 1 {
 		if globals.Arguments["--fastsync"] != nil && globals.Arguments["--fastsync"].(bool) {
@@ -320,9 +334,44 @@ try_again:
 		}
 	})
 
+	go chain.Backfill_Supply() // older databases do not have supply records
+
 	return &chain, nil
 }
 
+// whether blocks at this height commit to the state root of their tip in Proof
+// simulator chains always start from genesis, so they commit from the first block
+func (chain *Blockchain) State_Root_Active(height int64) bool {
+	return chain.simulator || height >= globals.Config.STATE_ROOT_HEIGHT
+}
+
+// returns current time as seen by this chain
+func (chain *Blockchain) now() time.Time {
+	return globals.Time().Add(chain.Get_Clock_Skew())
+}
+
+// returns the skew added to the chain clock
+func (chain *Blockchain) Get_Clock_Skew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&chain.clock_skew))
+}
+
+// changes the chain clock skew and expires the cached block template, so the next block uses the new time
+// simulator uses this to move block timestamps forward
+func (chain *Blockchain) Set_Clock_Skew(skew time.Duration) {
+	chain.cache_block_mutex.Lock()
+	defer chain.cache_block_mutex.Unlock()
+	atomic.StoreInt64(&chain.clock_skew, int64(skew))
+	chain.cache_block.Timestamp = 0
+}
+
+// adds to the chain clock skew and returns the new skew, cached block template is expired as in Set_Clock_Skew
+func (chain *Blockchain) Add_Clock_Skew(d time.Duration) time.Duration {
+	chain.cache_block_mutex.Lock()
+	defer chain.cache_block_mutex.Unlock()
+	chain.cache_block.Timestamp = 0
+	return time.Duration(atomic.AddInt64(&chain.clock_skew, int64(d)))
+}
+
 // return integrator address
 func (chain *Blockchain) IntegratorAddress() rpc.Address {
 	return chain.integrator_address
@@ -525,7 +574,7 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 
 	// make sure time is NOT into future,
 	// if clock diff is more than  50 millisecs, reject the block
-	if bl.Timestamp > (uint64(globals.Time().UTC().UnixMilli() + 50)) { // give 50 millisec passing
+	if bl.Timestamp > (uint64(chain.now().UTC().UnixMilli() + 50)) { // give 50 millisec passing
 		block_logger.Error(fmt.Errorf("Rejecting Block, timestamp is too much into future, make sure that system clock is correct"), "")
 		return errormsg.ErrFutureTimestamp, false
 	}
@@ -554,6 +603,20 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 
 	}
 
+	// from STATE_ROOT_HEIGHT, a single tip block must commit to the state root of its tip
+	// before the hard fork, Proof is not checked
+	if chain.State_Root_Active(int64(bl.Height)) && bl.Height >= 1 && len(bl.Tips) == 1 {
+		state_hash, err := chain.Load_Tips_State_Root(bl.Tips)
+		if err != nil {
+			block_logger.Error(err, "Cannot load state root of tip")
+			return err, false
+		}
+		if state_hash != crypto.Hash(bl.Proof) {
+			block_logger.Error(fmt.Errorf("Block has invalid state root"), "rejecting", "expected", state_hash, "actual", fmt.Sprintf("%x", bl.Proof[:]))
+			return errormsg.ErrInvalidBlock, false
+		}
+	}
+
 	// if the block is referencing any past tip too distant into its history
 	for i := range bl.Tips {
 		if int64(bl.Height)-1 != chain.Load_Block_Height(bl.Tips[i]) {
@@ -848,6 +911,7 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 
 	// we are here means everything looks good, proceed and save to chain
 	//skip_checks:
+	metrics.Set.GetOrCreateHistogram("block_verification_duration_histogram_seconds").UpdateDuration(processing_start)
 
 	// save all the txs
 	// and then save the block
@@ -920,6 +984,7 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 		}
 
 		fees_collected := uint64(0)
+		gas_used := uint64(0)
 
 		// side blocks only represent chain strenth , else they are are ignored
 		// this means they donot get any reward , 0 reward
@@ -956,12 +1021,11 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 
 					//fmt.Printf("transaction %s type %s data %+v\n", txhash, tx.TransactionType, tx.SCDATA)
 					if tx.TransactionType == transaction.SC_TX {
-						tx_fees, err = chain.process_transaction_sc(sc_change_cache, ss, bl_current.Height, uint64(current_topo_block), bl_current.Timestamp/1000, bl_current_hash, tx, balance_tree, sc_meta)
+						var tx_gas uint64
+						tx_fees, tx_gas, err = chain.process_transaction_sc(sc_change_cache, ss, bl_current.Height, uint64(current_topo_block), bl_current.Timestamp/1000, bl_current_hash, tx, balance_tree, sc_meta)
 
 						//fmt.Printf("Processsing sc err %s\n", err)
-						if err == nil { // TODO process gasg here
-
-						}
+						gas_used += tx_gas
 					}
 					fees_collected += tx_fees
 				}
@@ -1004,6 +1068,7 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 			}
 
 			chain.process_miner_transaction(bl_current, bl_current.Height == 0, balance_tree, fees_collected, bl_current.Height)
+			metrics.Set.GetOrCreateHistogram("block_dvm_gas_histogram").Update(float64(gas_used))
 		}
 
 		// we are here, means everything is okay, lets commit the update balance tree
@@ -1015,6 +1080,7 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 		}
 
 		chain.StoreBlock(bl_current, commit_version)
+		chain.store_supply(bl_current)
 
 		if height_changed {
 			// we need to write history until the entire chain is fixed
@@ -1110,6 +1176,11 @@ func (chain *Blockchain) Add_Complete_Block(cbl *block.Complete_Block) (err erro
 		block_logger.Info(fmt.Sprintf("Chain Height %d", chain.Get_Height()))
 	}
 
+	orphaned := metrics.Set.GetOrCreateCounter("miniblock_orphaned_total")
+	purged := chain.MiniBlocks.GetMiniBlocksTill(chain.Get_Stable_Height())
+	if len(purged) >= 1 {
+		go func() { orphaned.Add(chain.count_orphan_miniblocks(purged)) }() // loads blocks, so done outside chain lock
+	}
 	purge_count := chain.MiniBlocks.PurgeHeight(chain.Get_Stable_Height()) // purge all miniblocks upto this height
 	logger.V(2).Info("Purged miniblock", "count", purge_count)
 
@@ -1400,6 +1471,9 @@ func (chain *Blockchain) Is_TX_Orphan(hash crypto.Hash) (result bool) {
 func (chain *Blockchain) Rewind_Chain(rewind_count int) (result bool) {
 	defer chain.Initialise_Chain_From_DB()
 
+	chain.accept_lock.Lock() // no mined blocks while we are rewinding
+	defer chain.accept_lock.Unlock()
+
 	chain.Lock()
 	defer chain.Unlock()
 
@@ -1414,12 +1488,14 @@ func (chain *Blockchain) Rewind_Chain(rewind_count int) (result bool) {
 
 	top_block_topo_index := chain.Load_TOPO_HEIGHT()
 	rewinded := int64(0)
+	top_height := int64(0)
 
 	for {
 		r, err := chain.Store.Topo_store.Read(top_block_topo_index - rewinded)
 		if err != nil {
 			panic(err)
 		}
+		top_height = r.Height
 
 		if top_block_topo_index-rewinded < 1 || rewinded >= int64(rewind_count) {
 			break
@@ -1437,6 +1513,15 @@ func (chain *Blockchain) Rewind_Chain(rewind_count int) (result bool) {
 
 	chain.MiniBlocks.PurgeHeight(0xffffffffffffff) // purge all miniblocks upto this height
 
+	for height := range chain.duplicate_height_check { // rewinded heights can be mined again
+		if int64(height) > top_height {
+			delete(chain.duplicate_height_check, height)
+		}
+	}
+	chain.cache_block_mutex.Lock()
+	chain.cache_block.Timestamp = 0 // expire cache block
+	chain.cache_block_mutex.Unlock()
+
 	return true
 }
 
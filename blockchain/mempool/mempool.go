@@ -89,6 +89,14 @@ func Init_Mempool(params map[string]interface{}) (*Mempool, error) {
 		})
 		return count
 	})
+	metrics.Set.GetOrCreateGauge("mempool_bytes", func() float64 {
+		size := float64(0)
+		mempool.txs.Range(func(k, value interface{}) bool {
+			size += float64(value.(*mempool_object).Size)
+			return true
+		})
+		return size
+	})
 
 	return &mempool, nil
 }
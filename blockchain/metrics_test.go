@@ -0,0 +1,45 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package blockchain_test
+
+import "strings"
+import "testing"
+import "net/http/httptest"
+
+import "github.com/deroproject/derohe/metrics"
+import "github.com/deroproject/derohe/simulator"
+
+// chain, pool and execution metrics must be exported once blocks with txs and SC calls are processed
+func Test_Metrics(t *testing.T) {
+	s := simulator.StartTest(t, 2)
+	s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 5000)
+	scid := s.MustInstallSC(t, s.Wallets[0], simulator.Bank_SC)
+	s.MustInvokeSC(t, s.Wallets[1], scid, "Deposit", nil, 700)
+
+	w := httptest.NewRecorder()
+	metrics.WritePrometheus(w, nil)
+	body := w.Body.String()
+	for _, name := range []string{"blockchain_height ", "blockchain_topoheight ", "blockchain_tips_count 1", "mempool_bytes ", "regpool_bytes ", "miniblock_orphaned_total 0",
+		"block_verification_duration_histogram_seconds_count", "transaction_proof_verification_duration_histogram_seconds_count", "block_dvm_gas_histogram_sum"} {
+		if !strings.Contains(body, "\n"+name) {
+			t.Fatalf("metric %q is missing", name)
+		}
+	}
+	if strings.Contains(body, "\nblock_dvm_gas_histogram_sum 0\n") {
+		t.Fatalf("dvm gas must be accounted")
+	}
+}
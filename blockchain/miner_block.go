@@ -19,7 +19,6 @@ package blockchain
 import "fmt"
 import "bytes"
 import "sort"
-import "sync"
 import "runtime/debug"
 import "encoding/binary"
 
@@ -180,6 +179,13 @@ func (chain *Blockchain) Create_new_miner_block(miner_address rpc.Address) (cbl
 	height := chain.Calculate_Height_At_Tips(bl.Tips) // we are 1 higher than previous highest tip
 	history := map[crypto.Hash]crypto.Hash{}
 
+	// commit the state root we are building upon, so light clients can verify balances
+	if len(bl.Tips) == 1 && chain.State_Root_Active(height) {
+		if bl.Proof, err = chain.Load_Tips_State_Root(bl.Tips); err != nil {
+			return
+		}
+	}
+
 	var history_array []crypto.Hash
 	for i := range bl.Tips {
 		h := height - 20
@@ -301,13 +307,13 @@ func (chain *Blockchain) Create_new_miner_block(miner_address rpc.Address) (cbl
 	bl.Major_Version = uint64(chain.Get_Current_Version_at_Height(height))
 	bl.Minor_Version = uint64(chain.Get_Ideal_Version_at_Height(height)) // This is used for hard fork voting,
 	bl.Height = uint64(height)
-	bl.Timestamp = uint64(globals.Time().UTC().UnixMilli())
+	bl.Timestamp = uint64(chain.now().UTC().UnixMilli())
 	bl.Miner_TX.Version = 1
 	bl.Miner_TX.TransactionType = transaction.COINBASE // what about unregistered users
 	copy(bl.Miner_TX.MinerAddress[:], miner_address.Compressed())
 
 	for i := range bl.Tips { // adjust time stamp, only if someone mined a block in extreme precision
-		if chain.Load_Block_Timestamp(bl.Tips[i]) >= uint64(globals.Time().UTC().UnixMilli()) {
+		if chain.Load_Block_Timestamp(bl.Tips[i]) >= uint64(chain.now().UTC().UnixMilli()) {
 			bl.Timestamp = chain.Load_Block_Timestamp(bl.Tips[i]) + 1
 		}
 	}
@@ -387,14 +393,11 @@ func ConvertBlockToMiniblock(bl block.Block, miniblock_miner_address rpc.Address
 // miner tx in hex +
 // 2 bytes ( inhex 4 bytes for number of tx )
 // tx hashes that follow
-var cache_block block.Block
-var cache_block_mutex sync.Mutex
-
 func (chain *Blockchain) Create_new_block_template_mining(miniblock_miner_address rpc.Address) (bl block.Block, mbl block.MiniBlock, miniblock_blob string, reserved_pos int, err error) {
-	cache_block_mutex.Lock()
-	defer cache_block_mutex.Unlock()
+	chain.cache_block_mutex.Lock()
+	defer chain.cache_block_mutex.Unlock()
 
-	if (cache_block.Timestamp+100) < (uint64(globals.Time().UTC().UnixMilli())) || (cache_block.Timestamp > 0 && int64(cache_block.Height) != chain.Get_Height()+1) {
+	if (chain.cache_block.Timestamp+100) < (uint64(chain.now().UTC().UnixMilli())) || (chain.cache_block.Timestamp > 0 && int64(chain.cache_block.Height) != chain.Get_Height()+1) {
 		if chain.simulator {
 			_, bl, err = chain.Create_new_miner_block(miniblock_miner_address) // simulator lets you test everything
 		} else {
@@ -405,10 +408,10 @@ func (chain *Blockchain) Create_new_block_template_mining(miniblock_miner_addres
 			logger.V(1).Error(err, "block template error ")
 			return
 		}
-		cache_block = bl // setup block cache for 100 msec
-		chain.mining_blocks_cache.Add(fmt.Sprintf("%d", cache_block.Timestamp), string(bl.Serialize()))
+		chain.cache_block = bl // setup block cache for 100 msec
+		chain.mining_blocks_cache.Add(fmt.Sprintf("%d", chain.cache_block.Timestamp), string(bl.Serialize()))
 	} else {
-		bl = cache_block
+		bl = chain.cache_block
 	}
 
 	mbl = ConvertBlockToMiniblock(bl, miniblock_miner_address)
@@ -432,8 +435,6 @@ func (chain *Blockchain) Create_new_block_template_mining(miniblock_miner_addres
 // rate limiter is deployed, in case RPC is exposed over internet
 // someone should not be just giving fake inputs and delay chain syncing
 var accept_limiter = rate.NewLimiter(1.0, 4) // 1 block per sec, burst of 4 blocks is okay
-var accept_lock = sync.Mutex{}
-var duplicate_height_check = map[uint64]bool{}
 
 // accept work given by us
 // we should verify that the transaction list supplied back by the miner exists in the mempool
@@ -445,8 +446,8 @@ func (chain *Blockchain) Accept_new_block(tstamp uint64, miniblock_blob []byte)
 		return mblid, blid, false, fmt.Errorf("Please deactivate --sync-node option before mining")
 	}
 
-	accept_lock.Lock()
-	defer accept_lock.Unlock()
+	chain.accept_lock.Lock()
+	defer chain.accept_lock.Unlock()
 
 	cbl := &block.Complete_Block{}
 	bl := block.Block{}
@@ -473,7 +474,7 @@ func (chain *Blockchain) Accept_new_block(tstamp uint64, miniblock_blob []byte)
 			return
 		}
 	} else {
-		logger.V(1).Error(nil, "Job not found in cache", "jobid", fmt.Sprintf("%d", tstamp), "tstamp", uint64(globals.Time().UTC().UnixMilli()))
+		logger.V(1).Error(nil, "Job not found in cache", "jobid", fmt.Sprintf("%d", tstamp), "tstamp", uint64(chain.now().UTC().UnixMilli()))
 		err = fmt.Errorf("job not found in cache")
 		return
 	}
@@ -500,7 +501,7 @@ func (chain *Blockchain) Accept_new_block(tstamp uint64, miniblock_blob []byte)
 			result = true
 
 			// notify peers, we have a miniblock and return to miner
-			if !chain.simulator { // if not in simulator mode, relay miniblock to the chain
+			if chain.P2P_MiniBlock_Relayer != nil { // simulator has no p2p, so nothing to relay
 				go chain.P2P_MiniBlock_Relayer(mbl, 0)
 			}
 
@@ -516,7 +517,7 @@ func (chain *Blockchain) Accept_new_block(tstamp uint64, miniblock_blob []byte)
 	bl.MiniBlocks = append(bl.MiniBlocks, mbl)
 
 	// if a duplicate block is being sent, reject the block
-	if _, ok := duplicate_height_check[bl.Height]; ok {
+	if _, ok := chain.duplicate_height_check[bl.Height]; ok {
 		logger.V(3).Error(nil, "Block %s rejected by chain due to duplicate hwork.", "blid", bl.GetHash())
 		err = fmt.Errorf("Error duplicate work")
 		return
@@ -562,17 +563,17 @@ func (chain *Blockchain) Accept_new_block(tstamp uint64, miniblock_blob []byte)
 	err, result_block = chain.Add_Complete_Block(cbl)
 
 	if result_block {
-		duplicate_height_check[bl.Height] = true
+		chain.duplicate_height_check[bl.Height] = true
 
-		cache_block_mutex.Lock()
-		cache_block.Timestamp = 0 // expire cache block
-		cache_block_mutex.Unlock()
+		chain.cache_block_mutex.Lock()
+		chain.cache_block.Timestamp = 0 // expire cache block
+		chain.cache_block_mutex.Unlock()
 
 		logger.V(1).Info("Block successfully accepted, Notifying Network", "blid", bl.GetHash(), "height", bl.Height)
 
 		result = true // block's pow is valid
 
-		if !chain.simulator { // if not in simulator mode, relay block to the chain
+		if chain.P2P_Block_Relayer != nil { // simulator has no p2p, so nothing to relay
 			go chain.P2P_Block_Relayer(cbl, 0) // lets relay the block to network
 		}
 	} else {
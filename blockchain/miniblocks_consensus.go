@@ -146,3 +146,26 @@ func (chain *Blockchain) InsertMiniBlock(mbl block.MiniBlock) (err error, result
 	}
 	return err, result
 }
+
+// count miniblocks which did not end up in any block at their height
+// callers pass miniblocks which are being purged, so every orphan is counted only once
+// miniblocks are below stable height, their blocks do not change, so this need not hold chain lock
+func (chain *Blockchain) count_orphan_miniblocks(mbls []block.MiniBlock) (count int) {
+	included := map[uint64]map[crypto.Hash]bool{}
+	for _, mbl := range mbls {
+		if _, ok := included[mbl.Height]; !ok {
+			included[mbl.Height] = map[crypto.Hash]bool{}
+			for _, blid := range chain.Get_Blocks_At_Height(int64(mbl.Height)) {
+				if bl, err := chain.Load_BL_FROM_ID(blid); err == nil {
+					for i := range bl.MiniBlocks {
+						included[mbl.Height][bl.MiniBlocks[i].GetHash()] = true
+					}
+				}
+			}
+		}
+		if !included[mbl.Height][mbl.GetHash()] {
+			count++
+		}
+	}
+	return
+}
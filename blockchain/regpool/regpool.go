@@ -144,6 +144,14 @@ func Init_Regpool(params map[string]interface{}) (*Regpool, error) {
 		})
 		return count
 	})
+	metrics.Set.GetOrCreateGauge("regpool_bytes", func() float64 {
+		size := float64(0)
+		regpool.txs.Range(func(k, value interface{}) bool {
+			size += float64(value.(*regpool_object).Size)
+			return true
+		})
+		return size
+	})
 
 	// initialize maps
 	//regpool.txs = map[crypto.Hash]*regpool_object{}
@@ -38,6 +38,9 @@ type storage struct {
 func (s *storage) Initialize(params map[string]interface{}) (err error) {
 
 	current_path := filepath.Join(globals.GetDataDirectory())
+	if params["data_directory"] != nil { // used when several chains run within a single process
+		current_path = params["data_directory"].(string)
+	}
 
 	if s.Balance_store, err = graviton.NewDiskStore(filepath.Join(current_path, "balances")); err == nil {
 		if err = s.Topo_store.Open(current_path); err == nil {
@@ -301,6 +304,22 @@ func (chain *Blockchain) Load_Merkle_Hash(version uint64) (hash crypto.Hash, err
 		return
 	}
 
+	balance_merkle_hash, meta_merkle_hash, err := chain.load_tree_roots(version)
+	if err != nil {
+		return
+	}
+	for i := range balance_merkle_hash {
+		hash[i] = balance_merkle_hash[i] ^ meta_merkle_hash[i]
+	}
+
+	if chain.cache_enabled { //set in cache
+		chain.cache_VersionMerkle.Add(version, hash)
+	}
+	return hash, nil
+}
+
+// balance tree and sc meta tree roots of a snapshot
+func (chain *Blockchain) load_tree_roots(version uint64) (balance_root, meta_root crypto.Hash, err error) {
 	ss, err := chain.Store.Balance_store.LoadSnapshot(version)
 	if err != nil {
 		return
@@ -314,22 +333,29 @@ func (chain *Blockchain) Load_Merkle_Hash(version uint64) (hash crypto.Hash, err
 	if err != nil {
 		return
 	}
-	balance_merkle_hash, err := balance_tree.Hash()
-	if err != nil {
+	if balance_root, err = balance_tree.Hash(); err != nil {
 		return
 	}
-	meta_merkle_hash, err := sc_meta_tree.Hash()
-	if err != nil {
+	meta_root, err = sc_meta_tree.Hash()
+	return
+}
+
+// load state root of the state a block builds upon, this is committed in block header as Proof
+// since only 1 tip is allowed, this is the state after the tip was executed
+func (chain *Blockchain) Load_Tips_State_Root(tips []crypto.Hash) (hash crypto.Hash, err error) {
+	if len(tips) != 1 {
+		err = fmt.Errorf("state commitment requires exactly 1 tip, actual %d", len(tips))
 		return
 	}
-	for i := range balance_merkle_hash {
-		hash[i] = balance_merkle_hash[i] ^ meta_merkle_hash[i]
+	version, err := chain.ReadBlockSnapshotVersion(tips[0])
+	if err != nil {
+		return
 	}
-
-	if chain.cache_enabled { //set in cache
-		chain.cache_VersionMerkle.Add(version, hash)
+	balance_root, meta_root, err := chain.load_tree_roots(version)
+	if err != nil {
+		return
 	}
-	return hash, nil
+	return block.StateRoot(balance_root, meta_root), nil
 }
 
 // loads a complete block from disk
@@ -330,6 +330,24 @@ func (s *storefs) DeleteTX(h [32]byte) (err error) {
 	return os.Remove(file)
 }
 
+// supply records are stored alongside the block as hex block id.supply
+func (s *storefs) ReadSupply(h [32]byte) ([]byte, error) {
+	dir := s.getpath(h)
+	file := filepath.Join(dir, fmt.Sprintf("%x.supply", h[:]))
+	return ioutil.ReadFile(file)
+}
+
+func (s *storefs) WriteSupply(h [32]byte, data []byte) (err error) {
+	dir := s.getpath(h)
+	file := filepath.Join(dir, fmt.Sprintf("%x.supply", h[:]))
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, 0600)
+}
+
 // migrate old tx folder structure to new structure
 func (s *storefs) migrate_old_tx() {
 	var h [32]byte
@@ -0,0 +1,385 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package blockchain
+
+// this file keeps track of DERO emission ( premine, registrations, block rewards) and sinks ( burns, SCs)
+// a cumulative record is stored for every block, each record is parent record ( Tips[0]) + changes caused by the block
+// missing records ( older databases) are backfilled in background at startup and filled lazily on request
+// a full scan recomputes everything from genesis to verify records
+// records are built from genesis, so supply needs an unpruned node, pruned chains report an error
+
+import "fmt"
+import "time"
+import "bufio"
+import "strings"
+import "strconv"
+import "encoding/binary"
+
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/transaction"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/premine"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/graviton"
+
+// all amounts are cumulative till and including the block
+type Supply_Record struct {
+	Premine      uint64 // premine given in genesis
+	Registration uint64 // amounts given to newly registered accounts
+	Mined        uint64 // base block rewards, excluding fees
+	Fees         uint64 // fees paid by txs, these are given to miners alongwith base reward
+	Integrator   uint64 // rewards given to block integrators ( share + leftover)
+	MiniBlocks   uint64 // rewards given to miniblock miners
+	Burned       uint64 // DERO burned by BURN_TX/normal txs, lost forever
+	SCDeposits   uint64 // DERO burned into SCs by SC txs
+	SCHeld       uint64 // DERO currently held by SCs
+}
+
+const supply_record_size = 9 * 8
+
+// total DERO ever created
+func (r Supply_Record) Emitted() uint64 {
+	return r.Premine + r.Registration + r.Mined
+}
+
+// DERO held by accounts, excludes burned and SC held
+func (r Supply_Record) Circulating() uint64 {
+	return r.Emitted() - r.Burned - r.SCHeld
+}
+
+func (r *Supply_Record) fields() []*uint64 {
+	return []*uint64{&r.Premine, &r.Registration, &r.Mined, &r.Fees, &r.Integrator, &r.MiniBlocks, &r.Burned, &r.SCDeposits, &r.SCHeld}
+}
+
+// SCHeld may wrap around in a change ( SC paid out more than received), the sum is still correct
+func (r *Supply_Record) add(change Supply_Record) {
+	dst, src := r.fields(), change.fields()
+	for i := range dst {
+		*dst[i] += *src[i]
+	}
+}
+
+// returns names of fields which differ
+func (r Supply_Record) Diff(o Supply_Record) (mismatches []string) {
+	names := []string{"premine", "registration", "mined", "fees", "integrator", "miniblocks", "burned", "scdeposits", "scheld"}
+	a, b := r.fields(), o.fields()
+	for i := range a {
+		if *a[i] != *b[i] {
+			mismatches = append(mismatches, names[i])
+		}
+	}
+	return
+}
+
+func (r Supply_Record) MarshalBinary() []byte {
+	buf := make([]byte, supply_record_size)
+	for i, v := range r.fields() {
+		binary.BigEndian.PutUint64(buf[i*8:], *v)
+	}
+	return buf
+}
+
+func (r *Supply_Record) UnmarshalBinary(buf []byte) error {
+	if len(buf) != supply_record_size {
+		return fmt.Errorf("invalid supply record length %d", len(buf))
+	}
+	for i, v := range r.fields() {
+		*v = binary.BigEndian.Uint64(buf[i*8:])
+	}
+	return nil
+}
+
+// total premine given at genesis, testnet/simulator also process premine list
+func premine_total(bl *block.Block) (total uint64) {
+	total = bl.Miner_TX.Value
+	if globals.IsMainnet() {
+		return
+	}
+	scanner := bufio.NewScanner(strings.NewReader(premine.List))
+	for scanner.Scan() {
+		data := strings.Split(scanner.Text(), ",")
+		if ramount, err := strconv.ParseUint(data[0], 10, 64); err == nil {
+			total += ramount
+		}
+	}
+	return
+}
+
+// DERO balance of an SC within a snapshot, missing SCs have 0 balance
+func sc_dero_balance(ss *graviton.Snapshot, scid crypto.Hash) uint64 {
+	var zerohash crypto.Hash
+	tree, err := ss.GetTree(string(scid[:]))
+	if err != nil {
+		return 0
+	}
+	balance_bytes, err := tree.Get(zerohash[:])
+	if err != nil || len(balance_bytes) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(balance_bytes)
+}
+
+func (chain *Blockchain) load_block_snapshot(blid crypto.Hash) (*graviton.Snapshot, error) {
+	version, err := chain.ReadBlockSnapshotVersion(blid)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Store.Balance_store.LoadSnapshot(version)
+}
+
+// changes caused by a block, this mirrors the execution in Add_Complete_Block/process_miner_transaction
+func (chain *Blockchain) supply_change(bl *block.Block) (change Supply_Record, err error) {
+	if bl.Height == 0 {
+		change.Premine = premine_total(bl)
+		return
+	}
+
+	touched := map[crypto.Hash]bool{} // SCs whose balance may have changed
+	for _, txhash := range bl.Tx_hashes {
+		var tx_bytes []byte
+		if tx_bytes, err = chain.Store.Block_tx_store.ReadTX(txhash); err != nil {
+			return
+		}
+		var tx transaction.Transaction
+		if err = tx.Deserialize(tx_bytes); err != nil {
+			return
+		}
+
+		if tx.IsRegistration() {
+			change.Registration += registration_grant(bl.Height)
+			continue
+		}
+		change.Fees += tx.Fees()
+
+		for _, payload := range tx.Payloads {
+			if !payload.SCID.IsZero() {
+				touched[payload.SCID] = true
+				continue
+			}
+			if tx.TransactionType == transaction.SC_TX {
+				change.SCDeposits += payload.BurnValue
+			} else {
+				change.Burned += payload.BurnValue
+			}
+		}
+
+		if tx.TransactionType == transaction.SC_TX && tx.SCDATA.Has(rpc.SCACTION, rpc.DataUint64) {
+			switch rpc.SC_ACTION(tx.SCDATA.Value(rpc.SCACTION, rpc.DataUint64).(uint64)) {
+			case rpc.SC_INSTALL:
+				touched[txhash] = true
+			case rpc.SC_CALL:
+				if tx.SCDATA.Has(rpc.SCID, rpc.DataHash) {
+					touched[tx.SCDATA.Value(rpc.SCID, rpc.DataHash).(crypto.Hash)] = true
+				}
+			}
+		}
+	}
+
+	// same split as process_miner_transaction
+	full_reward := CalcBlockReward(bl.Height) + change.Fees
+	share := full_reward / uint64(len(bl.MiniBlocks))
+	leftover := full_reward - (share * uint64(len(bl.MiniBlocks)))
+	change.Mined = CalcBlockReward(bl.Height)
+	change.Integrator = share + leftover
+	for _, mbl := range bl.MiniBlocks {
+		if !mbl.Final {
+			change.MiniBlocks += share
+		}
+	}
+
+	if len(touched) >= 1 {
+		var ss_before, ss_after *graviton.Snapshot
+		if ss_before, err = chain.load_block_snapshot(bl.Tips[0]); err != nil {
+			return
+		}
+		if ss_after, err = chain.load_block_snapshot(bl.GetHash()); err != nil {
+			return
+		}
+		for scid := range touched {
+			change.SCHeld += sc_dero_balance(ss_after, scid) - sc_dero_balance(ss_before, scid)
+		}
+	}
+	return
+}
+
+// called after a block has been executed and stored, if parent has no record, it is left for backfill
+func (chain *Blockchain) store_supply(bl *block.Block) {
+	var record Supply_Record
+	if bl.Height != 0 {
+		var ok bool
+		if record, ok = chain.read_supply(bl.Tips[0]); !ok {
+			logger.V(1).Info("supply record of parent missing, skipping", "blid", bl.GetHash(), "parent", bl.Tips[0])
+			return
+		}
+	}
+	change, err := chain.supply_change(bl)
+	if err != nil {
+		logger.Error(err, "could not calculate supply change", "blid", bl.GetHash())
+		return
+	}
+	record.add(change)
+	if err = chain.Store.Block_tx_store.WriteSupply(bl.GetHash(), record.MarshalBinary()); err != nil {
+		logger.Error(err, "could not store supply record", "blid", bl.GetHash())
+	}
+}
+
+// pruned chains miss the blocks and txs needed to build records
+func (chain *Blockchain) supply_unavailable() error {
+	return fmt.Errorf("supply records not available, chain is pruned till topoheight %d, supply needs an unpruned node", chain.Pruned)
+}
+
+// stored supply record of a block, if any
+func (chain *Blockchain) read_supply(blid crypto.Hash) (record Supply_Record, ok bool) {
+	data, err := chain.Store.Block_tx_store.ReadSupply(blid)
+	if err != nil {
+		return
+	}
+	return record, record.UnmarshalBinary(data) == nil
+}
+
+// Backfill_Supply fills missing supply records in topological order, so every record is built from its parent record
+// it is run in background at startup, blocks added while it runs are covered as top is rechecked
+func (chain *Blockchain) Backfill_Supply() {
+	if _, ok := chain.read_supply(chain.Get_Top_ID()); ok { // top has record, so has its whole lineage
+		return
+	}
+	if chain.Pruned >= 1 {
+		logger.Info("supply records cannot be backfilled", "err", chain.supply_unavailable())
+		return
+	}
+
+	logger.Info("supply records missing, backfilling from genesis")
+	start, filled := time.Now(), 0
+	for topo := int64(0); topo <= chain.Load_TOPO_HEIGHT() && !globals.Exit_In_Progress; topo++ {
+		blid, err := chain.Load_Block_Topological_order_at_index(topo)
+		if err != nil {
+			logger.Error(err, "supply backfill stopped", "topoheight", topo)
+			return
+		}
+		if _, ok := chain.read_supply(blid); ok {
+			continue
+		}
+		if _, err = chain.Load_Supply(blid); err != nil {
+			logger.Error(err, "supply backfill stopped", "topoheight", topo)
+			return
+		}
+		filled++
+	}
+	logger.Info("supply records backfilled", "count", filled, "took", time.Since(start))
+}
+
+// Load_Supply returns the cumulative supply record at a block
+// missing records are filled from the nearest ancestor having a record ( or genesis) and stored
+func (chain *Blockchain) Load_Supply(blid crypto.Hash) (record Supply_Record, err error) {
+	var pending []*block.Block // blocks without records, newest first
+	for {
+		var ok bool
+		if record, ok = chain.read_supply(blid); ok {
+			break
+		}
+		if chain.Pruned >= 1 {
+			return record, chain.supply_unavailable()
+		}
+		var bl *block.Block
+		if bl, err = chain.Load_BL_FROM_ID(blid); err != nil {
+			return
+		}
+		pending = append(pending, bl)
+		if bl.Height == 0 {
+			record = Supply_Record{}
+			break
+		}
+		blid = bl.Tips[0]
+	}
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		var change Supply_Record
+		if change, err = chain.supply_change(pending[i]); err != nil {
+			return
+		}
+		record.add(change)
+		if err = chain.Store.Block_tx_store.WriteSupply(pending[i].GetHash(), record.MarshalBinary()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Scan_Supply recomputes supply at a block from genesis without using stored records
+// mined amount follows the emission schedule, premine is read from genesis and SC held amount is obtained by
+// summing balances of all SCs in the block state, remaining amounts are collected from txs of every block
+// miner rewards must add up to mined amount and fees, else an error is returned
+// this is slow and is used to verify the incremental records
+func (chain *Blockchain) Scan_Supply(blid crypto.Hash) (record Supply_Record, err error) {
+	if chain.Pruned >= 1 {
+		return record, chain.supply_unavailable()
+	}
+
+	for hash := blid; ; {
+		var bl *block.Block
+		if bl, err = chain.Load_BL_FROM_ID(hash); err != nil {
+			return
+		}
+		if hash == blid {
+			for h := uint64(1); h <= bl.Height; h++ {
+				record.Mined += CalcBlockReward(h)
+			}
+		}
+		if bl.Height == 0 {
+			record.Premine = premine_total(bl)
+			break
+		}
+
+		var change Supply_Record
+		if change, err = chain.supply_change(bl); err != nil {
+			return
+		}
+		record.Registration += change.Registration
+		record.Fees += change.Fees
+		record.Integrator += change.Integrator
+		record.MiniBlocks += change.MiniBlocks
+		record.Burned += change.Burned
+		record.SCDeposits += change.SCDeposits
+		hash = bl.Tips[0]
+	}
+
+	if record.Integrator+record.MiniBlocks != record.Mined+record.Fees {
+		err = fmt.Errorf("miner rewards %d do not match mined %d + fees %d", record.Integrator+record.MiniBlocks, record.Mined, record.Fees)
+		return
+	}
+
+	var ss *graviton.Snapshot
+	var sc_meta *graviton.Tree
+	if ss, err = chain.load_block_snapshot(blid); err != nil {
+		return
+	}
+	if sc_meta, err = ss.GetTree(config.SC_META); err != nil {
+		return
+	}
+	c := sc_meta.Cursor()
+	for k, _, err1 := c.First(); err1 == nil; k, _, err1 = c.Next() {
+		var scid crypto.Hash
+		if len(k) != len(scid) {
+			continue
+		}
+		copy(scid[:], k)
+		record.SCHeld += sc_dero_balance(ss, scid)
+	}
+	return
+}
@@ -0,0 +1,118 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package blockchain_test
+
+import "strings"
+import "testing"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/simulator"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// records must account premine, rewards, burns and SC deposits/payouts, and agree with a full scan
+func Test_Supply_Records(t *testing.T) {
+	var zeroscid crypto.Hash
+
+	s := simulator.StartTest(t, 3)
+	s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 5000)
+	scid := s.MustInstallSC(t, s.Wallets[2], simulator.Bank_SC)
+	s.MustInvokeSC(t, s.Wallets[1], scid, "Deposit", nil, 700)
+	s.MustInvokeSC(t, s.Wallets[2], scid, "Withdraw", rpc.Arguments{{Name: "amount", DataType: rpc.DataUint64, Value: uint64(300)}}, 0)
+	s.AssertSCBalance(t, scid, 400)
+
+	burn_ring := s.Wallets[0].Random_ring_members(zeroscid)
+	tx, err := s.Wallets[0].TransferPayload0([]rpc.Transfer{{Destination: burn_ring[0], Amount: 0, Burn: 123}}, simulator.RINGSIZE, false, rpc.Arguments{}, 0, false)
+	if err != nil {
+		t.Fatalf("cannot build burn tx err %s", err)
+	}
+	if err = s.Wallets[0].SendTransaction(tx); err != nil {
+		t.Fatalf("cannot send burn tx err %s", err)
+	}
+	if err = s.MineBlock(); err != nil {
+		t.Fatalf("cannot mine err %s", err)
+	}
+
+	top := s.Chain.Get_Top_ID()
+	record, err := s.Chain.Load_Supply(top)
+	if err != nil {
+		t.Fatalf("cannot load supply err %s", err)
+	}
+	mined := uint64(0)
+	for h := uint64(1); h <= uint64(s.Chain.Get_Height()); h++ {
+		mined += blockchain.CalcBlockReward(h)
+	}
+	if record.Premine != 4*simulator.TEST_FUNDS || record.Mined != mined || record.Burned != 123 || record.SCDeposits != 700 || record.SCHeld != 400 {
+		t.Fatalf("unexpected supply %+v mined %d", record, mined)
+	}
+	if record.Integrator+record.MiniBlocks != record.Mined+record.Fees {
+		t.Fatalf("miner rewards must equal mined + fees %+v", record)
+	}
+	if scanned, err := s.Chain.Scan_Supply(top); err != nil || len(record.Diff(scanned)) != 0 {
+		t.Fatalf("full scan mismatches err %v scanned %+v record %+v", err, scanned, record)
+	}
+
+	blid, _ := s.Chain.Load_Block_Topological_order_at_index(1)
+	if old, err := s.Chain.Load_Supply(blid); err != nil || old.Mined != blockchain.CalcBlockReward(1) || old.SCHeld != 0 {
+		t.Fatalf("unexpected supply at topoheight 1 err %v %+v", err, old)
+	}
+}
+
+// records missing in older databases must be rebuilt from genesis
+func Test_Supply_Backfill(t *testing.T) {
+	s := simulator.StartTest(t, 2)
+	s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 5000)
+	if err := s.MineBlocks(3); err != nil {
+		t.Fatalf("cannot mine err %s", err)
+	}
+
+	top := s.Chain.Get_Top_ID()
+	expected, err := s.Chain.Load_Supply(top)
+	if err != nil {
+		t.Fatalf("cannot load supply err %s", err)
+	}
+
+	for topo := int64(0); topo <= s.Chain.Load_TOPO_HEIGHT(); topo++ { // simulate a database without records
+		blid, _ := s.Chain.Load_Block_Topological_order_at_index(topo)
+		s.Chain.Store.Block_tx_store.WriteSupply(blid, nil)
+	}
+	s.Chain.Backfill_Supply()
+
+	for topo := int64(0); topo <= s.Chain.Load_TOPO_HEIGHT(); topo++ {
+		blid, _ := s.Chain.Load_Block_Topological_order_at_index(topo)
+		if _, err = s.Chain.Store.Block_tx_store.ReadSupply(blid); err != nil {
+			t.Fatalf("supply record at topoheight %d missing err %s", topo, err)
+		}
+	}
+	if data, _ := s.Chain.Store.Block_tx_store.ReadSupply(top); len(data) == 0 {
+		t.Fatalf("supply record of top was not rebuilt")
+	}
+	if record, err := s.Chain.Load_Supply(top); err != nil || len(record.Diff(expected)) != 0 {
+		t.Fatalf("backfilled record mismatch err %v record %+v expected %+v", err, record, expected)
+	}
+
+	// pruned chains cannot rebuild records
+	s.Chain.Pruned = 2
+	defer func() { s.Chain.Pruned = 0 }()
+	s.Chain.Store.Block_tx_store.WriteSupply(top, nil)
+	if _, err = s.Chain.Load_Supply(top); err == nil || !strings.Contains(err.Error(), "unpruned node") {
+		t.Fatalf("pruned chain must report supply is unavailable err %v", err)
+	}
+	if _, err = s.Chain.Scan_Supply(top); err == nil {
+		t.Fatalf("pruned chain must not be scanned")
+	}
+}
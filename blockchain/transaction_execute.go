@@ -161,6 +161,21 @@ func (chain *Blockchain) process_miner_transaction(bl *block.Block, genesis bool
 
 }
 
+// amount given to every newly registered account
+func registration_grant(height uint64) uint64 {
+	if !globals.IsMainnet() { // give testnet users a dummy amount to play
+		return 800000 // add fix amount to every wallet to users balance for more testing
+	}
+
+	// give new wallets generated in initial month a balance
+	// so they can claim previous chain balance safely/securely without revealing themselves
+	// 144000= 86400/18 *30
+	if height < 144000 {
+		return 200
+	}
+	return 0
+}
+
 // process the tx, giving fees, miner rewatd etc
 // this should be atomic, either all should be done or none at all
 func (chain *Blockchain) process_transaction(changed map[crypto.Hash]*graviton.Tree, tx transaction.Transaction, balance_tree *graviton.Tree, height uint64) uint64 {
@@ -182,16 +197,7 @@ func (chain *Blockchain) process_transaction(changed map[crypto.Hash]*graviton.T
 
 		zerobalance := crypto.ConstructElGamal(acckey.G1(), crypto.ElGamal_BASE_G)
 
-		if !globals.IsMainnet() { // give testnet users a dummy amount to play
-			zerobalance = zerobalance.Plus(new(big.Int).SetUint64(800000)) // add fix amount to every wallet to users balance for more testing
-		}
-
-		// give new wallets generated in initial month a balance
-		// so they can claim previous chain balance safely/securely without revealing themselves
-		// 144000= 86400/18 *30
-		if globals.IsMainnet() && height < 144000 {
-			zerobalance = zerobalance.Plus(new(big.Int).SetUint64(200))
-		}
+		zerobalance = zerobalance.Plus(new(big.Int).SetUint64(registration_grant(height)))
 
 		nb := crypto.NonceBalance{NonceHeight: 0, Balance: zerobalance}
 
@@ -255,18 +261,16 @@ func (chain *Blockchain) process_transaction(changed map[crypto.Hash]*graviton.T
 
 // does additional processing for SC
 // all processing occurs in wrapped trees, if any error occurs we dicard all trees
-func (chain *Blockchain) process_transaction_sc(cache map[crypto.Hash]*graviton.Tree, ss *graviton.Snapshot, bl_height, bl_topoheight, bl_timestamp uint64, blid crypto.Hash, tx transaction.Transaction, balance_tree *graviton.Tree, sc_tree *graviton.Tree) (gas uint64, err error) {
+func (chain *Blockchain) process_transaction_sc(cache map[crypto.Hash]*graviton.Tree, ss *graviton.Snapshot, bl_height, bl_topoheight, bl_timestamp uint64, blid crypto.Hash, tx transaction.Transaction, balance_tree *graviton.Tree, sc_tree *graviton.Tree) (gas uint64, gas_used uint64, err error) {
 
 	if len(tx.SCDATA) == 0 {
-		return tx.Fees(), nil
+		return tx.Fees(), 0, nil
 	}
 
 	gas = tx.Fees()
 
 	var gascompute, gasstorage uint64
-
-	_ = gascompute
-	_ = gasstorage
+	defer func() { gas_used = gascompute + gasstorage }() // dvm gas used, only for metrics
 
 	w_sc_tree := &dvm.Tree_Wrapper{Tree: sc_tree, Entries: map[string][]byte{}}
 	var w_sc_data_tree *dvm.Tree_Wrapper
@@ -282,7 +286,7 @@ func (chain *Blockchain) process_transaction_sc(cache map[crypto.Hash]*graviton.
 	}()
 
 	if !tx.SCDATA.Has(rpc.SCACTION, rpc.DataUint64) { //  tx doesn't have sc action
-		return tx.Fees(), nil
+		return tx.Fees(), 0, nil
 	}
 
 	incoming_value := map[crypto.Hash]uint64{}
@@ -416,7 +420,7 @@ func (chain *Blockchain) process_transaction_sc(cache map[crypto.Hash]*graviton.
 	//h, err := data_tree.Hash()
 	//fmt.Printf("%s successfully executed sc_call data_tree hash %x %s\n", scid, h, err)
 
-	return tx.Fees(), nil
+	return tx.Fees(), 0, nil
 }
 
 // func extract signer from a tx, if possible
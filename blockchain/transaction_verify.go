@@ -35,6 +35,7 @@ import "github.com/deroproject/derohe/config"
 import "github.com/deroproject/derohe/block"
 import "github.com/deroproject/derohe/rpc"
 import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/metrics"
 import "github.com/deroproject/derohe/cryptography/crypto"
 import "github.com/deroproject/derohe/transaction"
 import "github.com/deroproject/derohe/cryptography/bn256"
@@ -432,6 +433,7 @@ func (chain *Blockchain) verify_Transaction_NonCoinbase_internal(skip_proof bool
 	}
 
 	// at this point TX has been completely expanded, verify the tx statement
+	proof_start := time.Now()
 	scid_map := map[crypto.Hash]int{}
 	for t := range tx.Payloads {
 
@@ -445,6 +447,7 @@ func (chain *Blockchain) verify_Transaction_NonCoinbase_internal(skip_proof bool
 
 		scid_map[tx.Payloads[t].SCID] = scid_map[tx.Payloads[t].SCID] + 1 // increment scid counter
 	}
+	metrics.Set.GetOrCreateHistogram("transaction_proof_verification_duration_histogram_seconds").UpdateDuration(proof_start)
 
 	// these transactions are done
 	if tx.TransactionType == transaction.NORMAL || tx.TransactionType == transaction.BURN_TX || tx.TransactionType == transaction.SC_TX {
@@ -0,0 +1,184 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package blockchain
+
+// this file verifies that topo store, block/tx store and balance state agree with each other
+// this is used after crashes to find out which topoheights need to be popped/resynced
+
+import "os"
+import "fmt"
+import "encoding/json"
+import "path/filepath"
+
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/transaction"
+
+const verify_checkpoint_interval = 1000 // checkpoint is written after these many topoheights
+
+// consecutive topoheights failing for the same reason
+type Verify_Range struct {
+	Start  int64  `json:"start"`
+	End    int64  `json:"end"`
+	Reason string `json:"reason"`
+}
+
+type Verify_Checkpoint struct {
+	TopoHeight int64          `json:"topoheight"` // verified till this topoheight, -1 if nothing has been verified
+	BLID       crypto.Hash    `json:"blid"`       // block at topoheight, if it changes ( pop/resync), verification restarts
+	Ranges     []Verify_Range `json:"ranges"`
+}
+
+// default location of checkpoint file
+func Verify_Checkpoint_File() string {
+	return filepath.Join(globals.GetDataDirectory(), "verify_db.json")
+}
+
+// first topoheight which failed, -1 if everything is good
+func (cp Verify_Checkpoint) FirstBad() int64 {
+	if len(cp.Ranges) == 0 {
+		return -1
+	}
+	return cp.Ranges[0].Start
+}
+
+func (cp *Verify_Checkpoint) add(topo int64, reason string) {
+	if l := len(cp.Ranges); l >= 1 && cp.Ranges[l-1].End == topo-1 && cp.Ranges[l-1].Reason == reason {
+		cp.Ranges[l-1].End = topo
+		return
+	}
+	cp.Ranges = append(cp.Ranges, Verify_Range{Start: topo, End: topo, Reason: reason})
+}
+
+// Verify_Topo checks a single topoheight, errors are generic so that consecutive failures can be merged into ranges
+func (chain *Blockchain) Verify_Topo(topo int64) error {
+	record, err := chain.Store.Topo_store.Read(topo)
+	if err != nil || record.IsClean() {
+		return fmt.Errorf("topo record missing")
+	}
+	if record.Height != topo {
+		return fmt.Errorf("topo record height mismatch")
+	}
+
+	block_data, err := chain.Store.Block_tx_store.ReadBlock(record.BLOCK_ID)
+	if err != nil {
+		return fmt.Errorf("block missing")
+	}
+	var bl block.Block
+	if err = bl.Deserialize(block_data); err != nil {
+		return fmt.Errorf("block corrupted")
+	}
+	if bl.GetHash() != crypto.Hash(record.BLOCK_ID) {
+		return fmt.Errorf("block hash mismatch")
+	}
+	if int64(bl.Height) != record.Height {
+		return fmt.Errorf("block height mismatch")
+	}
+
+	for _, txhash := range bl.Tx_hashes {
+		tx_bytes, err := chain.Store.Block_tx_store.ReadTX(txhash)
+		if err != nil {
+			return fmt.Errorf("tx missing")
+		}
+		var tx transaction.Transaction
+		if err = tx.Deserialize(tx_bytes); err != nil {
+			return fmt.Errorf("tx corrupted")
+		}
+		if tx.GetHash() != txhash {
+			return fmt.Errorf("tx hash mismatch")
+		}
+	}
+
+	if topo < chain.Pruned { // history state has been pruned, nothing more to check
+		return nil
+	}
+
+	version, err := chain.ReadBlockSnapshotVersion(record.BLOCK_ID)
+	if err != nil {
+		return fmt.Errorf("block snapshot version missing")
+	}
+	if version != record.State_Version {
+		return fmt.Errorf("snapshot version mismatch")
+	}
+	if _, err = chain.Load_Merkle_Hash(version); err != nil {
+		return fmt.Errorf("state missing")
+	}
+
+	// header commits to state of parent, blocks before STATE_ROOT_HEIGHT do not have it
+	if topo >= 1 && chain.State_Root_Active(int64(bl.Height)) && len(bl.Tips) == 1 && topo-1 >= chain.Pruned {
+		if hash, err := chain.Load_Tips_State_Root(bl.Tips); err != nil {
+			return fmt.Errorf("parent state missing")
+		} else if hash != crypto.Hash(bl.Proof) {
+			return fmt.Errorf("state root mismatch")
+		}
+	}
+	return nil
+}
+
+// Verify_DB verifies all topoheights, resuming from checkpoint_file if possible
+// checkpoint is written periodically so that an interrupted verification can continue
+// progress if not nil, is called after every topoheight
+func (chain *Blockchain) Verify_DB(checkpoint_file string, restart bool, progress func(topo, top int64)) (cp Verify_Checkpoint, err error) {
+	cp.TopoHeight = -1
+	top := chain.Load_TOPO_HEIGHT()
+
+	if !restart {
+		if data, err := os.ReadFile(checkpoint_file); err == nil {
+			var saved Verify_Checkpoint
+			if err = json.Unmarshal(data, &saved); err == nil && saved.TopoHeight <= top {
+				if record, err := chain.Store.Topo_store.Read(saved.TopoHeight); saved.TopoHeight < 0 || (err == nil && crypto.Hash(record.BLOCK_ID) == saved.BLID) {
+					cp = saved
+				}
+			}
+		}
+	}
+	if cp.TopoHeight >= 0 {
+		logger.Info("resuming db verification", "topoheight", cp.TopoHeight+1)
+	}
+
+	save := func() error {
+		data, err := json.Marshal(cp)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(checkpoint_file, data, 0600)
+	}
+
+	for topo := cp.TopoHeight + 1; topo <= top; topo++ {
+		if err := chain.Verify_Topo(topo); err != nil {
+			cp.add(topo, err.Error())
+		}
+		cp.TopoHeight = topo
+		if record, err := chain.Store.Topo_store.Read(topo); err == nil {
+			cp.BLID = record.BLOCK_ID
+		} else {
+			cp.BLID = crypto.Hash{}
+		}
+
+		if progress != nil {
+			progress(topo, top)
+		}
+		if topo%verify_checkpoint_interval == 0 {
+			if err = save(); err != nil {
+				return
+			}
+		}
+	}
+	err = save()
+	return
+}
@@ -0,0 +1,51 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package blockchain_test
+
+import "testing"
+import "path/filepath"
+
+import "github.com/deroproject/derohe/simulator"
+
+// corruption is found only when verification is restarted, and reported at the exact topoheight
+func Test_Verify_DB(t *testing.T) {
+	s := simulator.StartTest(t, 2)
+	txid := s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 5000)
+	if err := s.MineBlocks(3); err != nil {
+		t.Fatalf("cannot mine err %s", err)
+	}
+
+	checkpoint := filepath.Join(t.TempDir(), "verify_db.json")
+	cp, err := s.Chain.Verify_DB(checkpoint, false, nil)
+	if err != nil || cp.FirstBad() != -1 || cp.TopoHeight != s.Chain.Load_TOPO_HEIGHT() {
+		t.Fatalf("healthy db must verify err %v checkpoint %+v", err, cp)
+	}
+
+	// verification resumes from checkpoint, so the corruption is not noticed until restarted
+	if err = s.Chain.Store.Block_tx_store.DeleteTX(txid); err != nil {
+		t.Fatalf("cannot delete tx err %s", err)
+	}
+	if cp, err = s.Chain.Verify_DB(checkpoint, false, nil); err != nil || cp.FirstBad() != -1 {
+		t.Fatalf("resumed verification must not recheck err %v checkpoint %+v", err, cp)
+	}
+	if cp, err = s.Chain.Verify_DB(checkpoint, true, nil); err != nil || len(cp.Ranges) != 1 || cp.Ranges[0].Reason != "tx missing" || cp.Ranges[0].Start != cp.Ranges[0].End {
+		t.Fatalf("missing tx must be reported err %v checkpoint %+v", err, cp)
+	}
+	if topo := s.Chain.Load_TOPO_HEIGHT() - 3; cp.FirstBad() != topo {
+		t.Fatalf("expected bad topoheight %d actual %d", topo, cp.FirstBad())
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "fmt"
+import "net"
+import "sync"
+import "time"
+import "strconv"
+import "crypto/subtle"
+import "net/http"
+import "sync/atomic"
+import "encoding/json"
+
+import "github.com/VictoriaMetrics/metrics"
+
+import "github.com/deroproject/derohe/config"
+
+// this file implements local status api, so headless miners can be monitored and controlled
+// GET /status returns json status, GET /metrics returns prometheus metrics
+// POST /pause, /resume and /threads?count=N control mining, these require api token if one is set
+
+const MAX_THREADS = 255
+
+var paused int32         // 1 if mining is paused
+var active_threads int32 // threads with tid below this mine, others idle
+var started_threads int  // mining goroutines started so far, they are never stopped
+var threads_mutex sync.Mutex
+
+var thread_counter [MAX_THREADS]uint64 // hashes done per thread
+
+var rates_mutex sync.Mutex
+var thread_rates [MAX_THREADS]float64 // hashes per second per thread, updated by sampler
+
+var start_time = time.Now()
+
+// returns whether thread should mine
+func mining_allowed(tid int) bool {
+	return atomic.LoadInt32(&paused) == 0 && int32(tid) < atomic.LoadInt32(&active_threads)
+}
+
+// changes number of mining threads, new threads are started if required
+func set_threads(count int) error {
+	if count < 1 || count > MAX_THREADS {
+		return fmt.Errorf("threads must be between 1 and %d", MAX_THREADS)
+	}
+	threads_mutex.Lock()
+	defer threads_mutex.Unlock()
+	for ; started_threads < count; started_threads++ {
+		go mineblock(started_threads)
+	}
+	threads = count
+	atomic.StoreInt32(&active_threads, int32(count))
+	return nil
+}
+
+// samples per thread hash rates every few seconds
+func sample_rates() {
+	var last [MAX_THREADS]uint64
+	last_time := time.Now()
+	for {
+		time.Sleep(5 * time.Second)
+		elapsed := time.Since(last_time).Seconds()
+		last_time = time.Now()
+		rates_mutex.Lock()
+		for i := range thread_counter {
+			current := atomic.LoadUint64(&thread_counter[i])
+			thread_rates[i] = float64(current-last[i]) / elapsed
+			last[i] = current
+		}
+		rates_mutex.Unlock()
+	}
+}
+
+type thread_status struct {
+	ID       int     `json:"id"`
+	Hashes   uint64  `json:"hashes"`
+	HashRate float64 `json:"hashrate"`
+}
+
+type daemon_status struct {
+	Address    string `json:"address"`
+	Priority   int    `json:"priority"`
+	Active     bool   `json:"active"`
+	Connects   uint64 `json:"connects"`
+	Failures   uint64 `json:"failures"`
+	Submitted  uint64 `json:"submitted"`
+	Blocks     uint64 `json:"blocks"`
+	MiniBlocks uint64 `json:"miniblocks"`
+	Rejected   uint64 `json:"rejected"`
+	LastError  string `json:"lasterror,omitempty"`
+}
+
+type miner_status struct {
+	Version    string          `json:"version"`
+	Wallet     string          `json:"wallet_address"`
+	Uptime     uint64          `json:"uptime"`
+	Paused     bool            `json:"paused"`
+	Threads    int             `json:"threads"`
+	HashRate   float64         `json:"hashrate"`
+	Hashes     uint64          `json:"hashes"`
+	Height     int64           `json:"height"`
+	Difficulty uint64          `json:"difficulty"`
+	Blocks     uint64          `json:"blocks"`
+	MiniBlocks uint64          `json:"miniblocks"`
+	Rejected   uint64          `json:"rejected"`
+	Thread     []thread_status `json:"thread_hashrates"`
+	Daemons    []daemon_status `json:"daemons"`
+}
+
+func get_status() (s miner_status) {
+	s.Version = config.Version.String()
+	s.Wallet = wallet_address
+	s.Uptime = uint64(time.Since(start_time).Seconds())
+	s.Paused = atomic.LoadInt32(&paused) != 0
+	s.Threads = int(atomic.LoadInt32(&active_threads))
+	s.Hashes = atomic.LoadUint64(&counter)
+	s.Height = our_height
+	s.Difficulty = Difficulty
+	s.Blocks, s.MiniBlocks, s.Rejected = endpoints.totals()
+
+	rates_mutex.Lock()
+	for i := 0; i < s.Threads; i++ {
+		s.Thread = append(s.Thread, thread_status{ID: i, Hashes: atomic.LoadUint64(&thread_counter[i]), HashRate: thread_rates[i]})
+		s.HashRate += thread_rates[i]
+	}
+	rates_mutex.Unlock()
+
+	endpoints.Lock()
+	for _, e := range endpoints.list {
+		s.Daemons = append(s.Daemons, daemon_status{Address: e.Address, Priority: e.Priority, Active: e == endpoints.current, Connects: e.Connects,
+			Failures: e.Failures, Submitted: e.Submitted, Blocks: e.Blocks + e.session.Blocks, MiniBlocks: e.MiniBlocks + e.session.MiniBlocks,
+			Rejected: e.Rejected + e.session.Rejected, LastError: e.LastError})
+	}
+	endpoints.Unlock()
+	return
+}
+
+// all miner metrics are sampled from miner state, so they are gauges and carry no _total suffix
+var api_metrics = metrics.NewSet()
+
+func register_metrics() {
+	api_metrics.NewGauge("miner_hashrate", func() float64 { return get_status().HashRate })
+	api_metrics.NewGauge("miner_threads", func() float64 { return float64(atomic.LoadInt32(&active_threads)) })
+	api_metrics.NewGauge("miner_paused", func() float64 { return float64(atomic.LoadInt32(&paused)) })
+	api_metrics.NewGauge("miner_height", func() float64 { return float64(our_height) })
+	api_metrics.NewGauge("miner_difficulty", func() float64 { return float64(Difficulty) })
+	api_metrics.NewGauge("miner_hashes", func() float64 { return float64(atomic.LoadUint64(&counter)) })
+	api_metrics.NewGauge("miner_blocks", func() float64 { b, _, _ := endpoints.totals(); return float64(b) })
+	api_metrics.NewGauge("miner_miniblocks", func() float64 { _, m, _ := endpoints.totals(); return float64(m) })
+	api_metrics.NewGauge("miner_rejected", func() float64 { _, _, r := endpoints.totals(); return float64(r) })
+}
+
+func write_metrics(w http.ResponseWriter, r *http.Request) {
+	metrics.WritePrometheus(w, true)
+	api_metrics.WritePrometheus(w)
+
+	// per thread and per daemon metrics need labels, so they are written directly
+	s := get_status()
+	for _, t := range s.Thread {
+		fmt.Fprintf(w, "miner_thread_hashrate{thread=\"%d\"} %g\n", t.ID, t.HashRate)
+	}
+	for _, d := range s.Daemons {
+		fmt.Fprintf(w, "miner_daemon_submitted{daemon=%q} %d\n", d.Address, d.Submitted)
+		fmt.Fprintf(w, "miner_daemon_rejected{daemon=%q} %d\n", d.Address, d.Rejected)
+		fmt.Fprintf(w, "miner_daemon_failures{daemon=%q} %d\n", d.Address, d.Failures)
+	}
+}
+
+func write_json(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func write_error(w http.ResponseWriter, code int, err error) {
+	write_json(w, code, map[string]string{"error": err.Error()})
+}
+
+// wraps control handlers so as they only accept POST with a valid token
+func control(token string, f func(r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			write_error(w, http.StatusMethodNotAllowed, fmt.Errorf("only POST is allowed"))
+			return
+		}
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			write_error(w, http.StatusUnauthorized, fmt.Errorf("invalid api token"))
+			return
+		}
+		if err := f(r); err != nil {
+			write_error(w, http.StatusBadRequest, err)
+			return
+		}
+		write_json(w, http.StatusOK, get_status())
+	}
+}
+
+func api_handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		write_json(w, http.StatusOK, get_status())
+	})
+	mux.HandleFunc("/metrics", write_metrics)
+	mux.HandleFunc("/pause", control(token, func(r *http.Request) error {
+		atomic.StoreInt32(&paused, 1)
+		logger.Info("mining paused by api")
+		return nil
+	}))
+	mux.HandleFunc("/resume", control(token, func(r *http.Request) error {
+		atomic.StoreInt32(&paused, 0)
+		logger.Info("mining resumed by api")
+		return nil
+	}))
+	mux.HandleFunc("/threads", control(token, func(r *http.Request) error {
+		count, err := strconv.Atoi(r.URL.Query().Get("count"))
+		if err != nil {
+			return fmt.Errorf("count parameter is invalid")
+		}
+		if err = set_threads(count); err == nil {
+			logger.Info("mining threads changed by api", "threads", count)
+		}
+		return err
+	}))
+	return mux
+}
+
+// starts api server, it does not expose anything unless user asks for it
+// without a token, anyone who can reach the api could control the miner, so only loopback is allowed
+func start_api(address string, token string) error {
+	if token == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("api bind %s is not loopback, --api-token is required", address)
+		}
+	}
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	register_metrics()
+	logger.Info("Status API started", "address", l.Addr().String())
+	go func() {
+		if err := http.Serve(l, api_handler(token)); err != nil {
+			logger.Error(err, "Status API stopped")
+		}
+	}()
+	return nil
+}
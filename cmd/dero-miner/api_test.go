@@ -0,0 +1,94 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "io"
+import "strings"
+import "testing"
+import "sync/atomic"
+import "encoding/json"
+import "net/http"
+import "net/http/httptest"
+
+import "github.com/go-logr/logr"
+
+func Test_API(t *testing.T) {
+	logger = logr.Discard()
+	srv := httptest.NewServer(api_handler(""))
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "/pause"); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("control commands must require POST")
+	}
+
+	resp, err := http.Post(srv.URL+"/pause", "", nil)
+	if err != nil || resp.StatusCode != http.StatusOK || atomic.LoadInt32(&paused) != 1 {
+		t.Fatalf("pause failed err %s", err)
+	}
+	var s miner_status
+	if err = json.NewDecoder(resp.Body).Decode(&s); err != nil || !s.Paused {
+		t.Fatalf("status must report paused err %s", err)
+	}
+	resp.Body.Close()
+
+	if resp, err = http.Post(srv.URL+"/resume", "", nil); err != nil || atomic.LoadInt32(&paused) != 0 {
+		t.Fatalf("resume failed err %s", err)
+	}
+	if resp, err = http.Post(srv.URL+"/threads?count=1000", "", nil); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid thread count must be rejected")
+	}
+
+	if resp, err = http.Get(srv.URL + "/metrics"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("metrics failed err %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "go_goroutines") {
+		t.Fatalf("metrics are not in prometheus format")
+	}
+
+	if mining_allowed(0) {
+		t.Fatalf("no threads are active, so none must mine")
+	}
+}
+
+func Test_API_Token(t *testing.T) {
+	logger = logr.Discard()
+	srv := httptest.NewServer(api_handler("secret"))
+	defer srv.Close()
+	defer atomic.StoreInt32(&paused, 0)
+
+	if resp, err := http.Post(srv.URL+"/pause", "", nil); err != nil || resp.StatusCode != http.StatusUnauthorized || atomic.LoadInt32(&paused) != 0 {
+		t.Fatalf("control without token must be rejected")
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/pause", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("control with wrong token must be rejected")
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK || atomic.LoadInt32(&paused) != 1 {
+		t.Fatalf("control with token failed err %v", err)
+	}
+	if resp, err := http.Get(srv.URL + "/status"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("status must not require token err %v", err)
+	}
+
+	if err := start_api("0.0.0.0:0", ""); err == nil {
+		t.Fatalf("non loopback bind without token must be refused")
+	}
+}
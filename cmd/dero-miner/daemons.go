@@ -0,0 +1,268 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "io"
+import "fmt"
+import "sort"
+import "sync"
+import "time"
+import "strings"
+import "strconv"
+import "net/url"
+import "crypto/tls"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/gorilla/websocket"
+
+// this file implements failover between multiple daemons
+// daemons are tried in priority order, if a daemon fails or stops sending jobs, miner switches to next one
+// and keeps probing higher priority daemons so as it can switch back when they recover
+
+const ENDPOINT_PROBE_INTERVAL = 30 * time.Second
+const ENDPOINT_BACKOFF_MIN = 10 * time.Second
+const ENDPOINT_BACKOFF_MAX = 5 * time.Minute
+
+var job_timeout = 60 * time.Second // if no job is received within this time, daemon is considered stalled
+
+type endpoint struct {
+	Address  string
+	Priority int // lower is preferred
+
+	Connects  uint64
+	Failures  uint64 // total failures
+	Submitted uint64 // shares/miniblocks submitted to this daemon
+	Jobs      uint64
+
+	// counters as reported by daemon, accumulated across sessions
+	Blocks     uint64
+	MiniBlocks uint64
+	Rejected   uint64
+
+	LastJob   time.Time
+	LastError string
+
+	session     rpc.GetBlockTemplate_Result // last counters of current session
+	consecutive uint                        // consecutive failures, used for backoff
+	retry_after time.Time
+}
+
+type endpoint_list struct {
+	sync.Mutex
+	list    []*endpoint
+	current *endpoint
+}
+
+var endpoints endpoint_list
+
+// parses daemon addresses, each can be host:port or host:port@priority, default priority is the order given
+func parse_endpoints(addresses []string) (list []*endpoint, err error) {
+	for _, a := range addresses {
+		for _, s := range strings.Split(a, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			e := &endpoint{Address: s, Priority: len(list)}
+			if i := strings.LastIndex(s, "@"); i >= 0 {
+				if e.Priority, err = strconv.Atoi(s[i+1:]); err != nil {
+					return nil, fmt.Errorf("invalid priority in daemon address %q", s)
+				}
+				e.Address = s[:i]
+			}
+			if !strings.Contains(e.Address, ":") {
+				return nil, fmt.Errorf("daemon address %q must be host:port", s)
+			}
+			list = append(list, e)
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no daemon address given")
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Priority < list[j].Priority })
+	return
+}
+
+// chooses best available endpoint, if all are backing off, returns the one which recovers earliest
+func (el *endpoint_list) choose(now time.Time) (e *endpoint) {
+	el.Lock()
+	defer el.Unlock()
+	for _, c := range el.list {
+		if !now.Before(c.retry_after) {
+			return c
+		}
+		if e == nil || c.retry_after.Before(e.retry_after) {
+			e = c
+		}
+	}
+	return
+}
+
+// marks endpoint as failed, it will not be used until backoff expires
+func (el *endpoint_list) failed(e *endpoint, err error, now time.Time) {
+	el.Lock()
+	defer el.Unlock()
+	e.Failures++
+	e.consecutive++
+	if err != nil {
+		e.LastError = err.Error()
+	}
+	backoff := ENDPOINT_BACKOFF_MIN << (e.consecutive - 1)
+	if e.consecutive > 6 || backoff > ENDPOINT_BACKOFF_MAX {
+		backoff = ENDPOINT_BACKOFF_MAX
+	}
+	e.retry_after = now.Add(backoff)
+	el.release(e)
+}
+
+func (el *endpoint_list) disconnected(e *endpoint) {
+	el.Lock()
+	defer el.Unlock()
+	el.release(e)
+}
+
+// must be called with lock held
+func (el *endpoint_list) release(e *endpoint) {
+	e.end_session()
+	if el.current == e {
+		el.current = nil
+	}
+}
+
+func (el *endpoint_list) connected(e *endpoint) {
+	el.Lock()
+	defer el.Unlock()
+	e.Connects++
+	el.current = e
+}
+
+// a job was received from the endpoint, daemon counters are per connection
+func (el *endpoint_list) job(e *endpoint, job rpc.GetBlockTemplate_Result, now time.Time) {
+	el.Lock()
+	defer el.Unlock()
+	e.Jobs++
+	e.LastJob = now
+	e.consecutive = 0
+	e.retry_after = time.Time{}
+	e.session = job
+}
+
+func (el *endpoint_list) submitted(e *endpoint) {
+	el.Lock()
+	defer el.Unlock()
+	e.Submitted++
+}
+
+// must be called with lock held, folds session counters into totals
+func (e *endpoint) end_session() {
+	e.Blocks += e.session.Blocks
+	e.MiniBlocks += e.session.MiniBlocks
+	e.Rejected += e.session.Rejected
+	e.session = rpc.GetBlockTemplate_Result{}
+}
+
+// totals across all endpoints including current sessions
+func (el *endpoint_list) totals() (blocks, miniblocks, rejected uint64) {
+	el.Lock()
+	defer el.Unlock()
+	for _, e := range el.list {
+		blocks += e.Blocks + e.session.Blocks
+		miniblocks += e.MiniBlocks + e.session.MiniBlocks
+		rejected += e.Rejected + e.session.Rejected
+	}
+	return
+}
+
+// returns an endpoint preferred over current one, which is not backing off
+func (el *endpoint_list) preferred(now time.Time) (list []*endpoint) {
+	el.Lock()
+	defer el.Unlock()
+	if el.current == nil {
+		return
+	}
+	for _, e := range el.list {
+		if e == el.current || e.Priority >= el.current.Priority {
+			break
+		}
+		if !now.Before(e.retry_after) {
+			list = append(list, e)
+		}
+	}
+	return
+}
+
+func (el *endpoint_list) print(w io.Writer) {
+	el.Lock()
+	defer el.Unlock()
+	fmt.Fprintf(w, "%-40s %8s %8s %8s %10s %8s %10s %8s %s\n", "Daemon", "Priority", "Connects", "Failures", "Submitted", "Blocks", "MiniBlocks", "Rejected", "State")
+	for _, e := range el.list {
+		state := ""
+		switch {
+		case e == el.current:
+			state = "ACTIVE"
+		case time.Now().Before(e.retry_after):
+			state = fmt.Sprintf("retry in %s (%s)", time.Until(e.retry_after).Round(time.Second), e.LastError)
+		}
+		fmt.Fprintf(w, "%-40s %8d %8d %8d %10d %8d %10d %8d %s\n", e.Address, e.Priority, e.Connects, e.Failures, e.Submitted,
+			e.Blocks+e.session.Blocks, e.MiniBlocks+e.session.MiniBlocks, e.Rejected+e.session.Rejected, state)
+	}
+}
+
+func dial_endpoint(e *endpoint, wallet_address string) (*websocket.Conn, error) {
+	u := url.URL{Scheme: "wss", Host: e.Address, Path: "/ws/" + wallet_address}
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	dialer.HandshakeTimeout = 10 * time.Second
+	conn, _, err := dialer.Dial(u.String(), nil)
+	return conn, err
+}
+
+// checks whether an endpoint is healthy by completing the getwork websocket handshake
+// connection is closed right after the upgrade, so no job is awaited and no share is submitted
+func probe_endpoint(e *endpoint, wallet_address string) error {
+	conn, err := dial_endpoint(e, wallet_address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// keeps probing higher priority endpoints, if one recovers, current connection is dropped so getwork switches back
+func probe_preferred(wallet_address string) {
+	for {
+		select {
+		case <-Exit_In_Progress:
+			return
+		case <-time.After(ENDPOINT_PROBE_INTERVAL):
+		}
+
+		for _, e := range endpoints.preferred(time.Now()) {
+			if err := probe_endpoint(e, wallet_address); err != nil {
+				logger.V(1).Info("daemon still unavailable", "daemon", e.Address, "err", err)
+				endpoints.failed(e, err, time.Now())
+				continue
+			}
+			logger.Info("switching back to preferred daemon", "daemon", e.Address)
+			connection_mutex.Lock()
+			switching = true
+			if connection != nil {
+				connection.Close()
+			}
+			connection_mutex.Unlock()
+			break
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "time"
+import "testing"
+import "fmt"
+import "strings"
+import "net/http"
+import "net/http/httptest"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/gorilla/websocket"
+
+func Test_Parse_Endpoints(t *testing.T) {
+	list, err := parse_endpoints([]string{"a:1,b:2@-1", "c:3"})
+	if err != nil {
+		t.Fatalf("parsing failed err %s", err)
+	}
+	if len(list) != 3 || list[0].Address != "b:2" || list[1].Address != "a:1" || list[2].Address != "c:3" {
+		t.Fatalf("endpoints not sorted by priority %+v", list)
+	}
+	for _, invalid := range [][]string{{}, {"a"}, {"a:1@x"}} {
+		if _, err := parse_endpoints(invalid); err == nil {
+			t.Fatalf("invalid endpoints %v must fail", invalid)
+		}
+	}
+}
+
+func Test_Endpoint_Failover(t *testing.T) {
+	var el endpoint_list
+	el.list, _ = parse_endpoints([]string{"primary:1", "backup:2"})
+	primary, backup := el.list[0], el.list[1]
+	now := time.Now()
+
+	if el.choose(now) != primary {
+		t.Fatalf("primary must be chosen first")
+	}
+	el.connected(primary)
+	el.job(primary, rpc.GetBlockTemplate_Result{MiniBlocks: 2, Rejected: 1}, now)
+	el.failed(primary, fmt.Errorf("stalled"), now)
+
+	if el.choose(now) != backup {
+		t.Fatalf("backup must be chosen when primary fails")
+	}
+	el.connected(backup)
+	el.job(backup, rpc.GetBlockTemplate_Result{MiniBlocks: 3}, now)
+	if _, minis, rejected := el.totals(); minis != 5 || rejected != 1 {
+		t.Fatalf("counters must accumulate across endpoints minis %d rejected %d", minis, rejected)
+	}
+
+	if len(el.preferred(now)) != 0 {
+		t.Fatalf("primary is backing off, it must not be probed")
+	}
+	later := now.Add(ENDPOINT_BACKOFF_MIN)
+	if p := el.preferred(later); len(p) != 1 || p[0] != primary {
+		t.Fatalf("primary must be probed after backoff")
+	}
+	el.disconnected(backup)
+	if el.choose(later) != primary {
+		t.Fatalf("primary must be chosen once it recovers")
+	}
+
+	el.failed(primary, nil, now)
+	el.failed(backup, nil, now)
+	if e := el.choose(now); e != backup || !e.retry_after.After(now) { // backup has shorter backoff
+		t.Fatalf("earliest recovering endpoint must be chosen")
+	}
+}
+
+func Test_Probe_Endpoint(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/miner" { // daemon refuses invalid addresses without upgrading
+			fmt.Fprintf(w, "err: invalid address\n")
+			return
+		}
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	e := &endpoint{Address: strings.TrimPrefix(srv.URL, "https://")}
+	if err := probe_endpoint(e, "miner"); err != nil {
+		t.Fatalf("healthy daemon probe failed err %s", err)
+	}
+	if err := probe_endpoint(e, "invalid"); err == nil {
+		t.Fatalf("refused handshake must fail")
+	}
+	srv.Close()
+	if err := probe_endpoint(e, "miner"); err == nil {
+		t.Fatalf("unreachable daemon must fail")
+	}
+}
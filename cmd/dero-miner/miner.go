@@ -20,9 +20,7 @@ import "io"
 import "os"
 import "fmt"
 import "time"
-import "net/url"
 import "crypto/rand"
-import "crypto/tls"
 import "sync"
 import "runtime"
 import "math/big"
@@ -59,7 +57,6 @@ var threads int
 var iterations int = 100
 var max_pow_size int = 819200 //astrobwt.MAX_LENGTH
 var wallet_address string
-var daemon_rpc_address string
 
 var counter uint64
 var hash_rate uint64
@@ -77,7 +74,7 @@ ONE CPU, ONE VOTE.
 http://wiki.dero.io
 
 Usage:
-  dero-miner  --wallet-address=<wallet_address> [--daemon-rpc-address=<minernode1.dero.live:10100>] [--mining-threads=<threads>] [--testnet] [--debug]
+  dero-miner  --wallet-address=<wallet_address> [--daemon-rpc-address=<minernode1.dero.live:10100>]... [--job-timeout=<60>] [--mining-threads=<threads>] [--api-bind=<127.0.0.1:10300>] [--api-token=<token>] [--testnet] [--debug]
   dero-miner --bench 
   dero-miner -h | --help
   dero-miner --version
@@ -87,8 +84,12 @@ Options:
   --version     Show version.
   --bench  	    Run benchmark mode.
   --daemon-rpc-address=<127.0.0.1:10102>    Miner will connect to daemon RPC on this port (default minernode1.dero.live:10100).
+                                            Can be repeated or comma separated for failover, host:port@priority sets priority (lower is preferred, default is order given).
+  --job-timeout=<60>	If no job is received within these many seconds, miner switches to next daemon.
   --wallet-address=<wallet_address>    This address is rewarded when a block is mined sucessfully.
   --mining-threads=<threads>         Number of CPU threads for mining [default: ` + fmt.Sprintf("%d", runtime.GOMAXPROCS(0)) + `]
+  --api-bind=<127.0.0.1:10300>	Serve json status at /status, prometheus metrics at /metrics and accept POST /pause, /resume, /threads?count=N on this ip:port
+  --api-token=<token>	Control endpoints require "Authorization: Bearer <token>", without a token api can only be bound to loopback
 
 Example Mainnet: ./dero-miner-linux-amd64 --wallet-address dero1qy0ehnqjpr0wxqnknyc66du2fsxyktppkr8m8e6jvplp954klfjz2qqhmy4zf --daemon-rpc-address=minernode1.dero.live:10100
 Example Testnet: ./dero-miner-linux-amd64 --wallet-address deto1qy0ehnqjpr0wxqnknyc66du2fsxyktppkr8m8e6jvplp954klfjz2qqdzcd8p --daemon-rpc-address=127.0.0.1:40402 
@@ -155,14 +156,26 @@ func main() {
 		wallet_address = addr.String()
 	}
 
-	if !globals.Arguments["--testnet"].(bool) {
-		daemon_rpc_address = "minernode1.dero.live:10100"
-	} else {
-		daemon_rpc_address = "127.0.0.1:10100"
+	daemon_addresses := []string{"minernode1.dero.live:10100"}
+	if globals.Arguments["--testnet"].(bool) {
+		daemon_addresses = []string{"127.0.0.1:10100"}
 	}
 
-	if globals.Arguments["--daemon-rpc-address"] != nil {
-		daemon_rpc_address = globals.Arguments["--daemon-rpc-address"].(string)
+	if list, ok := globals.Arguments["--daemon-rpc-address"].([]string); ok && len(list) > 0 {
+		daemon_addresses = list
+	}
+
+	if endpoints.list, err = parse_endpoints(daemon_addresses); err != nil {
+		logger.Error(err, "Daemon address is invalid.")
+		return
+	}
+
+	if globals.Arguments["--job-timeout"] != nil {
+		if s, err := strconv.Atoi(globals.Arguments["--job-timeout"].(string)); err == nil && s > 0 {
+			job_timeout = time.Duration(s) * time.Second
+		} else {
+			logger.Error(err, "Job timeout argument cannot be parsed.")
+		}
 	}
 
 	threads = runtime.GOMAXPROCS(0)
@@ -308,15 +321,24 @@ func main() {
 		}
 	}()
 
-	if threads > 255 {
+	if threads > MAX_THREADS {
 		logger.Error(nil, "This program supports maximum 256 CPU cores.", "available", threads)
-		threads = 255
+		threads = MAX_THREADS
 	}
 
 	go getwork(wallet_address)
+	if len(endpoints.list) > 1 {
+		go probe_preferred(wallet_address)
+	}
 
-	for i := 0; i < threads; i++ {
-		go mineblock(i)
+	set_threads(threads)
+	go sample_rates()
+
+	if globals.Arguments["--api-bind"] != nil {
+		token, _ := globals.Arguments["--api-token"].(string)
+		if err := start_api(globals.Arguments["--api-bind"].(string), token); err != nil {
+			logger.Error(err, "Status API could not be started")
+		}
 	}
 
 	for {
@@ -353,6 +375,8 @@ func main() {
 				fmt.Println("say what?")
 				break
 			}
+		case command == "status":
+			endpoints.print(l.Stderr())
 		case command == "version":
 			fmt.Printf("Version %s OS:%s ARCH:%s \n", config.Version.String(), runtime.GOOS, runtime.GOARCH)
 
@@ -396,36 +420,58 @@ func random_execution(wg *sync.WaitGroup, iterations int) {
 // continuously get work
 
 var connection *websocket.Conn
+var current_endpoint *endpoint
 var connection_mutex sync.Mutex
 
-func getwork(wallet_address string) {
-	var err error
+var switching bool // set while switching back to a preferred daemon, so the drop is not counted as failure
 
+func getwork(wallet_address string) {
 	for {
-
-		u := url.URL{Scheme: "wss", Host: daemon_rpc_address, Path: "/ws/" + wallet_address}
-		logger.Info("connecting to ", "url", u.String())
-
-		dialer := websocket.DefaultDialer
-		dialer.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: true,
+		e := endpoints.choose(time.Now())
+		if wait := time.Until(e.retry_after); wait > 0 {
+			logger.Info("All daemons are unavailable, will retry", "server adress", e.Address, "after", wait.Round(time.Second))
+			time.Sleep(wait)
 		}
-		connection, _, err = websocket.DefaultDialer.Dial(u.String(), nil)
-		if err != nil {
-			logger.Error(err, "Error connecting to server", "server adress", daemon_rpc_address)
-			logger.Info("Will try in 10 secs", "server adress", daemon_rpc_address)
-			time.Sleep(10 * time.Second)
 
+		logger.Info("connecting to ", "server adress", e.Address)
+		conn, err := dial_endpoint(e, wallet_address)
+		if err != nil {
+			logger.Error(err, "Error connecting to server", "server adress", e.Address)
+			endpoints.failed(e, err, time.Now())
 			continue
 		}
 
-		var result rpc.GetBlockTemplate_Result
-	wait_for_another_job:
+		connection_mutex.Lock()
+		connection = conn
+		current_endpoint = e
+		switching = false
+		connection_mutex.Unlock()
+		endpoints.connected(e)
+
+		err = read_jobs(conn, e)
 
-		if err = connection.ReadJSON(&result); err != nil {
-			logger.Error(err, "connection error")
+		connection_mutex.Lock()
+		switched := switching
+		conn.Close()
+		connection_mutex.Unlock()
+
+		if switched {
+			endpoints.disconnected(e) // no backoff, it is still healthy
 			continue
 		}
+		logger.Error(err, "connection error", "server adress", e.Address)
+		endpoints.failed(e, err, time.Now())
+	}
+}
+
+// reads jobs till connection fails or job stalls
+func read_jobs(conn *websocket.Conn, e *endpoint) error {
+	for {
+		var result rpc.GetBlockTemplate_Result
+		conn.SetReadDeadline(time.Now().Add(job_timeout))
+		if err := conn.ReadJSON(&result); err != nil {
+			return err
+		}
 
 		mutex.Lock()
 		job = result
@@ -435,19 +481,16 @@ func getwork(wallet_address string) {
 			logger.Error(nil, "received error", "err", job.LastError)
 		}
 
-		block_counter = job.Blocks
-		mini_block_counter = job.MiniBlocks // note if the miner submits the job late, though his counter
-		// will increase, but a block has been already found, so
+		endpoints.job(e, result, time.Now())
+		// note if the miner submits the job late, though his counter will increase, but a block has been already found, so
 		// orphan miniblocks may be there ( means they will not br rewarded)
-		rejected = job.Rejected
+		block_counter, mini_block_counter, rejected = endpoints.totals()
 		hash_rate = job.Difficultyuint64
 		our_height = int64(job.Height)
 		Difficulty = job.Difficultyuint64
 
 		//fmt.Printf("recv: %+v diff %d\n", result, Difficulty)
-		goto wait_for_another_job
 	}
-
 }
 
 func mineblock(tid int) {
@@ -471,6 +514,11 @@ func mineblock(tid int) {
 	i := uint32(0)
 
 	for {
+		if !mining_allowed(tid) { // paused or thread count reduced
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
 		mutex.RLock()
 		myjob := job
 		local_job_counter = job_counter
@@ -497,12 +545,13 @@ func mineblock(tid int) {
 		}
 
 		if int64(height) < globals.Config.MAJOR_HF2_HEIGHT {
-			for local_job_counter == job_counter { // update job when it comes, expected rate 1 per second
+			for local_job_counter == job_counter && mining_allowed(tid) { // update job when it comes, expected rate 1 per second
 				i++
 				binary.BigEndian.PutUint32(nonce_buf, i)
 
 				powhash := astrobwt_fast.POW_optimized(work[:], scratch)
 				atomic.AddUint64(&counter, 1)
+				atomic.AddUint64(&thread_counter[tid], 1)
 
 				if CheckPowHashBig(powhash, &diff) == true { // note we are doing a local, NW might have moved meanwhile
 					logger.V(1).Info("Successfully found DERO miniblock (going to submit)", "difficulty", myjob.Difficulty, "height", myjob.Height)
@@ -510,19 +559,22 @@ func mineblock(tid int) {
 						defer globals.Recover(1)
 						connection_mutex.Lock()
 						defer connection_mutex.Unlock()
-						connection.WriteJSON(rpc.SubmitBlock_Params{JobID: myjob.JobID, MiniBlockhashing_blob: fmt.Sprintf("%x", work[:])})
+						if connection.WriteJSON(rpc.SubmitBlock_Params{JobID: myjob.JobID, MiniBlockhashing_blob: fmt.Sprintf("%x", work[:])}) == nil {
+							endpoints.submitted(current_endpoint)
+						}
 					}()
 
 				}
 			}
 		} else {
 
-			for local_job_counter == job_counter { // update job when it comes, expected rate 1 per second
+			for local_job_counter == job_counter && mining_allowed(tid) { // update job when it comes, expected rate 1 per second
 				i++
 				binary.BigEndian.PutUint32(nonce_buf, i)
 
 				powhash := astrobwtv3.AstroBWTv3(work[:])
 				atomic.AddUint64(&counter, 1)
+				atomic.AddUint64(&thread_counter[tid], 1)
 
 				if CheckPowHashBig(powhash, &diff) == true { // note we are doing a local, NW might have moved meanwhile
 					logger.V(1).Info("Successfully found DERO miniblock (going to submit)", "difficulty", myjob.Difficulty, "height", myjob.Height)
@@ -530,7 +582,9 @@ func mineblock(tid int) {
 						defer globals.Recover(1)
 						connection_mutex.Lock()
 						defer connection_mutex.Unlock()
-						connection.WriteJSON(rpc.SubmitBlock_Params{JobID: myjob.JobID, MiniBlockhashing_blob: fmt.Sprintf("%x", work[:])})
+						if connection.WriteJSON(rpc.SubmitBlock_Params{JobID: myjob.JobID, MiniBlockhashing_blob: fmt.Sprintf("%x", work[:])}) == nil {
+							endpoints.submitted(current_endpoint)
+						}
 					}()
 
 				}
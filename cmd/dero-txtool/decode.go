@@ -0,0 +1,147 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "fmt"
+import "bytes"
+import "unicode/utf8"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/transaction"
+
+type decoded_arg struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type decoded_statement struct {
+	RingSize               uint64   `json:"ringsize"`
+	Bytes_per_publickey    byte     `json:"bytes_per_publickey"`
+	Publickeylist_pointers []string `json:"publickeylist_pointers"`
+	C                      []string `json:"c"`
+	D                      string   `json:"d"`
+	Fees                   uint64   `json:"fees"`
+	Roothash               string   `json:"roothash"`
+}
+
+type decoded_payload struct {
+	SCID       string            `json:"scid"`
+	BurnValue  uint64            `json:"burn_value"`
+	RPCType    byte              `json:"rpc_type"`
+	RPCPayload string            `json:"rpc_payload"` // encrypted, so only hex
+	Statement  decoded_statement `json:"statement"`
+	ProofSize  int               `json:"proof_size"`
+}
+
+type decoded_tx struct {
+	TXID            string            `json:"txid"`
+	Size            int               `json:"size"`
+	Version         uint64            `json:"version"`
+	SourceNetwork   uint64            `json:"source_network"`
+	DestNetwork     uint64            `json:"dest_network"`
+	TransactionType string            `json:"txtype"`
+	Value           uint64            `json:"value"`
+	MinerAddress    string            `json:"miner_address,omitempty"`
+	C               string            `json:"c,omitempty"`
+	S               string            `json:"s,omitempty"`
+	Height          uint64            `json:"height"`
+	BLID            string            `json:"blid"`
+	Fees            uint64            `json:"fees"`
+	SCDATA          []decoded_arg     `json:"scdata"`
+	Payloads        []decoded_payload `json:"payloads"`
+	RoundTrip       bool              `json:"serialization_roundtrip"`
+	RoundTripError  string            `json:"serialization_error,omitempty"`
+}
+
+// decode every field of the tx, also check that it serializes back to the same bytes
+func decode_tx(tx_bin []byte) (*decoded_tx, error) {
+	tx, err := deserialize_tx(tx_bin)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoded_tx{TXID: tx.GetHash().String(), Size: len(tx_bin), Version: tx.Version, SourceNetwork: tx.SourceNetwork, DestNetwork: tx.DestNetwork,
+		TransactionType: tx.TransactionType.String(), Value: tx.Value, Height: tx.Height, BLID: fmt.Sprintf("%x", tx.BLID[:]), Fees: tx.Fees(),
+		SCDATA: []decoded_arg{}, Payloads: []decoded_payload{}}
+
+	if tx.IsCoinbase() || tx.IsRegistration() {
+		d.MinerAddress = fmt.Sprintf("%x", tx.MinerAddress[:])
+	}
+	if tx.IsRegistration() {
+		d.C, d.S = fmt.Sprintf("%x", tx.C[:]), fmt.Sprintf("%x", tx.S[:])
+	}
+
+	for _, arg := range tx.SCDATA {
+		d.SCDATA = append(d.SCDATA, decode_arg(arg))
+	}
+
+	for t := range tx.Payloads {
+		p := tx.Payloads[t]
+		s := p.Statement
+		dp := decoded_payload{SCID: p.SCID.String(), BurnValue: p.BurnValue, RPCType: p.RPCType, RPCPayload: fmt.Sprintf("%x", p.RPCPayload),
+			Statement: decoded_statement{RingSize: s.RingSize, Bytes_per_publickey: s.Bytes_per_publickey, Publickeylist_pointers: []string{}, C: []string{},
+				Fees: s.Fees, Roothash: fmt.Sprintf("%x", s.Roothash[:])}}
+
+		if s.Bytes_per_publickey > 0 {
+			for i := 0; i+int(s.Bytes_per_publickey) <= len(s.Publickeylist_pointers); i += int(s.Bytes_per_publickey) {
+				dp.Statement.Publickeylist_pointers = append(dp.Statement.Publickeylist_pointers, fmt.Sprintf("%x", s.Publickeylist_pointers[i:i+int(s.Bytes_per_publickey)]))
+			}
+		}
+		for i := range s.C {
+			dp.Statement.C = append(dp.Statement.C, fmt.Sprintf("%x", s.C[i].EncodeCompressed()))
+		}
+		if s.D != nil {
+			dp.Statement.D = fmt.Sprintf("%x", s.D.EncodeCompressed())
+		}
+		if p.Proof != nil {
+			if proof, err := p.MarshalProofs(); err == nil {
+				dp.ProofSize = len(proof)
+			}
+		}
+		d.Payloads = append(d.Payloads, dp)
+	}
+
+	if reserialized, err := reserialize(&tx); err != nil {
+		d.RoundTripError = err.Error()
+	} else if !bytes.Equal(reserialized, tx_bin) {
+		d.RoundTripError = fmt.Sprintf("reserialized tx differs, %d bytes instead of %d", len(reserialized), len(tx_bin))
+	} else {
+		d.RoundTrip = true
+	}
+	return d, nil
+}
+
+func reserialize(tx *transaction.Transaction) (result []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tx could not be serialized: %v", r)
+		}
+	}()
+	return tx.Serialize(), nil
+}
+
+// strings may carry binary data such as SC code or keys, show those as hex
+func decode_arg(arg rpc.Argument) decoded_arg {
+	d := decoded_arg{Name: arg.Name, Type: arg.DataType.String()}
+	if s, ok := arg.Value.(string); ok && !utf8.ValidString(s) {
+		d.Value = fmt.Sprintf("%x", s)
+	} else {
+		d.Value = fmt.Sprintf("%v", arg.Value)
+	}
+	return d
+}
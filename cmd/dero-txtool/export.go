@@ -0,0 +1,111 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "fmt"
+import "bytes"
+import "strings"
+import "net/http"
+import "encoding/hex"
+import "encoding/json"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/errormsg"
+
+// minimal json rpc over http, so the tool has no other dependencies
+func daemon_call(endpoint string, method string, params interface{}, result interface{}) error {
+	request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": "1", "method": method, "params": params})
+
+	resp, err := http.Post("http://"+endpoint+"/json_rpc", "application/json", bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s: invalid response: %s", method, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%s: %s", method, response.Error.Message)
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// collect everything verify needs from a node, tx must be mined
+func export_ring(endpoint string, txid string) (*ring_file, error) {
+	var tx_result rpc.GetTransaction_Result
+	if err := daemon_call(endpoint, "DERO.GetTransaction", rpc.GetTransaction_Params{Tx_Hashes: []string{txid}}, &tx_result); err != nil {
+		return nil, err
+	}
+	if len(tx_result.Txs_as_hex) != 1 || tx_result.Txs_as_hex[0] == "" {
+		return nil, fmt.Errorf("tx %s not found", txid)
+	}
+
+	tx_bin, err := hex.DecodeString(tx_result.Txs_as_hex[0])
+	if err != nil {
+		return nil, err
+	}
+	tx, err := deserialize_tx(tx_bin)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &ring_file{TXID: tx.GetHash().String(), TX: tx_result.Txs_as_hex[0], BLID: fmt.Sprintf("%x", tx.BLID[:]), Payloads: []ring_payload{}}
+	if tx.IsCoinbase() || tx.IsRegistration() || tx.IsPremine() {
+		return ring, nil
+	}
+	if len(tx_result.Txs[0].Ring) != len(tx.Payloads) {
+		return nil, fmt.Errorf("tx %s is not mined yet, ring members are not available", txid)
+	}
+
+	var header rpc.GetBlockHeaderByHash_Result
+	if err = daemon_call(endpoint, "DERO.GetBlockHeaderByHash", rpc.GetBlockHeaderByHash_Params{Hash: ring.BLID}, &header); err != nil {
+		return nil, err
+	}
+	ring.TopoHeight = header.Block_Header.TopoHeight
+
+	for t := range tx.Payloads {
+		payload := ring_payload{SCID: tx.Payloads[t].SCID.String()}
+		for _, address := range tx_result.Txs[0].Ring[t] {
+			addr, err := rpc.NewAddress(address)
+			if err != nil {
+				return nil, err
+			}
+			member := ring_member{Address: address, PublicKey: fmt.Sprintf("%x", addr.PublicKey.EncodeCompressed())}
+
+			var balance rpc.GetEncryptedBalance_Result
+			err = daemon_call(endpoint, "DERO.GetEncryptedBalance", rpc.GetEncryptedBalance_Params{Address: address, SCID: tx.Payloads[t].SCID, TopoHeight: ring.TopoHeight}, &balance)
+			switch {
+			case err == nil:
+				member.Balance = balance.Data
+			case !tx.Payloads[t].SCID.IsZero() && strings.Contains(err.Error(), errormsg.ErrAccountUnregistered.Error()): // no balance in this asset yet
+			default:
+				return nil, fmt.Errorf("balance of %s could not be obtained: %s", address, err)
+			}
+			payload.Ring = append(payload.Ring, member)
+		}
+		ring.Payloads = append(ring.Payloads, payload)
+	}
+	return ring, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+// dero-txtool decodes and verifies raw transactions without a running chain
+// ring members and their encrypted balances can be exported from any node once and verified anywhere
+
+import "os"
+import "fmt"
+import "strings"
+import "io/ioutil"
+import "encoding/hex"
+import "encoding/json"
+
+import "github.com/docopt/docopt-go"
+
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/transaction"
+
+var command_line string = `dero-txtool
+DERO offline transaction decoder and verifier.
+
+Usage:
+  dero-txtool decode (<txhex> | --tx-file=<file>)
+  dero-txtool verify [<txhex> | --tx-file=<file>] --ring-file=<file>
+  dero-txtool export-ring <txid> [--daemon-address=<127.0.0.1:10102>]
+  dero-txtool -h | --help
+  dero-txtool --version
+
+Options:
+  -h --help     Show this screen.
+  --version     Show version.
+  --tx-file=<file>     read raw tx hex from this file, - reads stdin
+  --ring-file=<file>   ring public keys and encrypted balances as written by export-ring, tx is taken from it if not given
+  --daemon-address=<127.0.0.1:10102>   export-ring connects to this daemon RPC
+
+Example:
+  dero-txtool export-ring <txid> --daemon-address=127.0.0.1:10102 > ring.json
+  dero-txtool verify --ring-file=ring.json
+`
+
+func main() {
+	var err error
+	globals.Arguments, err = docopt.Parse(command_line, nil, true, config.Version.String(), false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error while parsing options err: %s\n", err)
+		os.Exit(1)
+	}
+
+	var result interface{}
+	switch {
+	case globals.Arguments["decode"].(bool):
+		var tx_bin []byte
+		if tx_bin, err = read_tx(); err == nil {
+			result, err = decode_tx(tx_bin)
+		}
+	case globals.Arguments["verify"].(bool):
+		var ring ring_file
+		if ring, err = read_ring_file(globals.Arguments["--ring-file"].(string)); err == nil {
+			var tx_bin []byte
+			if globals.Arguments["<txhex>"] == nil && globals.Arguments["--tx-file"] == nil {
+				tx_bin, err = hex.DecodeString(strings.TrimSpace(ring.TX))
+			} else {
+				tx_bin, err = read_tx()
+			}
+			if err == nil {
+				result, err = verify_tx(tx_bin, ring)
+			}
+		}
+	case globals.Arguments["export-ring"].(bool):
+		endpoint := "127.0.0.1:10102"
+		if globals.Arguments["--daemon-address"] != nil {
+			endpoint = globals.Arguments["--daemon-address"].(string)
+		}
+		result, err = export_ring(endpoint, globals.Arguments["<txid>"].(string))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+	if v, ok := result.(*verify_result); ok && !v.Valid {
+		os.Exit(2)
+	}
+}
+
+// tx is taken from command line or a file
+func read_tx() ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case globals.Arguments["<txhex>"] != nil:
+		data = []byte(globals.Arguments["<txhex>"].(string))
+	case globals.Arguments["--tx-file"].(string) == "-":
+		data, err = ioutil.ReadAll(os.Stdin)
+	default:
+		data, err = ioutil.ReadFile(globals.Arguments["--tx-file"].(string))
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx_bin, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("tx is not valid hex: %s", err)
+	}
+	return tx_bin, nil
+}
+
+func deserialize_tx(tx_bin []byte) (tx transaction.Transaction, err error) {
+	defer func() { // malformed input must not crash the tool
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tx could not be deserialized: %v", r)
+		}
+	}()
+	if err = tx.Deserialize(tx_bin); err != nil {
+		err = fmt.Errorf("tx could not be deserialized: %s", err)
+	}
+	return
+}
@@ -0,0 +1,64 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "testing"
+import "encoding/hex"
+
+import "github.com/deroproject/derohe/simulator"
+
+func Test_Export_Decode_Verify(t *testing.T) {
+	s := simulator.StartTest(t, 2)
+	txid := s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 1234)
+
+	ring, err := export_ring(s.RPCAddress, txid.String())
+	if err != nil {
+		t.Fatalf("export failed err %s", err)
+	}
+	if len(ring.Payloads) != 1 || len(ring.Payloads[0].Ring) != 2 || ring.Payloads[0].Ring[0].Balance == "" {
+		t.Fatalf("incomplete ring %+v", ring)
+	}
+
+	tx_bin, _ := hex.DecodeString(ring.TX)
+	decoded, err := decode_tx(tx_bin)
+	if err != nil {
+		t.Fatalf("decode failed err %s", err)
+	}
+	if decoded.TXID != txid.String() || decoded.TransactionType != "NORMAL" || !decoded.RoundTrip || len(decoded.Payloads) != 1 || decoded.Payloads[0].Statement.RingSize != 2 || decoded.Fees == 0 {
+		t.Fatalf("invalid decode %+v", decoded)
+	}
+
+	if result, err := verify_tx(tx_bin, *ring); err != nil || !result.Valid {
+		t.Fatalf("valid tx must verify err %v result %+v", err, result)
+	}
+
+	tampered := *ring
+	tampered.Payloads = []ring_payload{{SCID: ring.Payloads[0].SCID, Ring: append([]ring_member{}, ring.Payloads[0].Ring...)}}
+	tampered.Payloads[0].Ring[0].Balance, tampered.Payloads[0].Ring[1].Balance = ring.Payloads[0].Ring[1].Balance, ring.Payloads[0].Ring[0].Balance
+	if result, err := verify_tx(tx_bin, tampered); err != nil || result.Valid {
+		t.Fatalf("swapped balances must not verify err %v result %+v", err, result)
+	}
+
+	tampered.Payloads[0].Ring[0], tampered.Payloads[0].Ring[1] = ring.Payloads[0].Ring[1], ring.Payloads[0].Ring[0]
+	if result, err := verify_tx(tx_bin, tampered); err != nil || result.Valid || result.Checks[len(result.Checks)-1].Error == "proof verification failed" {
+		t.Fatalf("reordered ring must fail key pointer check err %v result %+v", err, result)
+	}
+
+	if _, err = decode_tx(tx_bin[:len(tx_bin)/2]); err == nil {
+		t.Fatalf("truncated tx must not decode")
+	}
+}
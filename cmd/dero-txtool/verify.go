@@ -0,0 +1,237 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "fmt"
+import "bytes"
+import "strings"
+import "io/ioutil"
+import "encoding/hex"
+import "encoding/json"
+
+import "github.com/deroproject/graviton"
+
+import "github.com/deroproject/derohe/cryptography/bn256"
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/transaction"
+
+// a ring member as seen by the node in the state the tx was built against
+type ring_member struct {
+	Address   string `json:"address,omitempty"`
+	PublicKey string `json:"publickey"` // compressed, hex
+	Balance   string `json:"balance"`   // serialized nonce balance, hex, empty if the account has no balance for this asset yet
+}
+
+type ring_payload struct {
+	SCID string        `json:"scid"`
+	Ring []ring_member `json:"ring"`
+}
+
+// written by export-ring and read by verify
+type ring_file struct {
+	TXID       string         `json:"txid"`
+	TX         string         `json:"tx,omitempty"`
+	BLID       string         `json:"blid"`
+	TopoHeight int64          `json:"topoheight"`
+	Payloads   []ring_payload `json:"payloads"`
+}
+
+type verify_check struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type verify_result struct {
+	TXID   string         `json:"txid"`
+	Valid  bool           `json:"valid"`
+	Checks []verify_check `json:"checks"`
+}
+
+func (v *verify_result) add(name string, err error) {
+	c := verify_check{Name: name, OK: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+		v.Valid = false
+	}
+	v.Checks = append(v.Checks, c)
+}
+
+func read_ring_file(filename string) (ring ring_file, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &ring); err != nil {
+		err = fmt.Errorf("ring file could not be parsed: %s", err)
+	}
+	return
+}
+
+// verifies the tx using only the supplied ring, no chain needed
+// the ring file is trusted to represent the state at the tx BLID, this only proves the tx is consistent with it
+func verify_tx(tx_bin []byte, ring ring_file) (*verify_result, error) {
+	tx, err := deserialize_tx(tx_bin)
+	if err != nil {
+		return nil, err
+	}
+	txid := tx.GetHash()
+	result := &verify_result{TXID: txid.String(), Valid: true}
+
+	if reserialized, err := reserialize(&tx); err != nil {
+		result.add("serialization roundtrip", err)
+	} else if !bytes.Equal(reserialized, tx_bin) {
+		result.add("serialization roundtrip", fmt.Errorf("reserialized tx differs"))
+	} else {
+		result.add("serialization roundtrip", nil)
+	}
+
+	if ring.TXID != "" && !strings.EqualFold(ring.TXID, txid.String()) {
+		result.add("ring file matches tx", fmt.Errorf("ring file is for tx %s", ring.TXID))
+		return result, nil
+	}
+
+	switch {
+	case tx.IsRegistration():
+		if tx.IsRegistrationValid() {
+			result.add("registration signature", nil)
+		} else {
+			result.add("registration signature", fmt.Errorf("registration has invalid signature"))
+		}
+		return result, nil
+	case tx.IsCoinbase() || tx.IsPremine():
+		result.add("no proofs required", nil)
+		return result, nil
+	}
+
+	if len(ring.Payloads) != len(tx.Payloads) {
+		result.add("ring file matches tx", fmt.Errorf("ring file has %d payloads, tx has %d", len(ring.Payloads), len(tx.Payloads)))
+		return result, nil
+	}
+	result.add("ring file matches tx", nil)
+
+	for t := range tx.Payloads {
+		if tx.Payloads[t].Statement.Roothash != tx.Payloads[0].Statement.Roothash {
+			result.add("roothash", fmt.Errorf("payload %d has different roothash", t))
+		}
+	}
+
+	balances := map[string]*crypto.NonceBalance{} // payloads of the same asset modify balances for the next ones
+	scid_map := map[crypto.Hash]int{}
+	for t := range tx.Payloads {
+		name := fmt.Sprintf("payload %d proof", t)
+		index := scid_map[tx.Payloads[t].SCID]
+		scid_map[tx.Payloads[t].SCID]++
+
+		if err := expand_statement(&tx.Payloads[t], ring.Payloads[t], balances); err != nil {
+			result.add(name, err)
+			continue
+		}
+		if verify_proof(&tx.Payloads[t], index, txid) {
+			result.add(name, nil)
+		} else {
+			result.add(name, fmt.Errorf("proof verification failed"))
+		}
+	}
+	return result, nil
+}
+
+// fill public keys and CLn/CRn the same way a node does from its balance tree
+func expand_statement(p *transaction.AssetPayload, ring ring_payload, balances map[string]*crypto.NonceBalance) error {
+	s := &p.Statement
+	if ring.SCID != "" && !strings.EqualFold(ring.SCID, p.SCID.String()) {
+		return fmt.Errorf("ring is for scid %s", ring.SCID)
+	}
+	if s.Bytes_per_publickey == 0 || uint64(len(ring.Ring)) != s.RingSize || len(s.Publickeylist_pointers) != int(s.RingSize)*int(s.Bytes_per_publickey) {
+		return fmt.Errorf("ring has %d members, statement needs %d", len(ring.Ring), s.RingSize)
+	}
+	if len(s.C) != int(s.RingSize) || s.D == nil {
+		return fmt.Errorf("statement is incomplete")
+	}
+
+	s.Publickeylist, s.Publickeylist_compressed = s.Publickeylist[:0], s.Publickeylist_compressed[:0]
+	s.CLn, s.CRn = s.CLn[:0], s.CRn[:0]
+
+	bpp := int(s.Bytes_per_publickey)
+	for i, member := range ring.Ring {
+		key_compressed, err := hex.DecodeString(member.PublicKey)
+		if err != nil || len(key_compressed) != 33 {
+			return fmt.Errorf("ring member %d has invalid public key", i)
+		}
+		hashed_key := graviton.Sum(key_compressed)
+		if !bytes.Equal(hashed_key[:bpp], s.Publickeylist_pointers[i*bpp:(i+1)*bpp]) {
+			return fmt.Errorf("ring member %d does not match key pointer %x", i, s.Publickeylist_pointers[i*bpp:(i+1)*bpp])
+		}
+
+		var key bn256.G1
+		if err = key.DecodeCompressed(key_compressed); err != nil {
+			return fmt.Errorf("ring member %d public key could not be decompressed", i)
+		}
+		var pcopy [33]byte
+		copy(pcopy[:], key_compressed)
+		s.Publickeylist_compressed = append(s.Publickeylist_compressed, pcopy)
+		s.Publickeylist = append(s.Publickeylist, &key)
+
+		balance_key := p.SCID.String() + member.PublicKey
+		nb, ok := balances[balance_key]
+		if !ok {
+			if member.Balance == "" { // account has no balance in this asset, node assumes zero
+				nb = &crypto.NonceBalance{Balance: crypto.ConstructElGamal(&key, crypto.ElGamal_BASE_G)}
+			} else {
+				balance_serialized, err := hex.DecodeString(member.Balance)
+				if err != nil {
+					return fmt.Errorf("ring member %d has invalid balance", i)
+				}
+				if nb, err = deserialize_balance(balance_serialized); err != nil {
+					return fmt.Errorf("ring member %d has invalid balance: %s", i, err)
+				}
+			}
+		}
+
+		var ll, rr bn256.G1
+		ll.Add(nb.Balance.Left, s.C[i])
+		rr.Add(nb.Balance.Right, s.D)
+		s.CLn = append(s.CLn, &ll)
+		s.CRn = append(s.CRn, &rr)
+
+		nb.Balance = nb.Balance.Add(crypto.ConstructElGamal(s.C[i], s.D))
+		balances[balance_key] = nb
+	}
+	return nil
+}
+
+func deserialize_balance(buf []byte) (nb *crypto.NonceBalance, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	nb = new(crypto.NonceBalance).Deserialize(buf)
+	if nb.Balance == nil {
+		err = fmt.Errorf("balance missing")
+	}
+	return
+}
+
+func verify_proof(p *transaction.AssetPayload, index int, txid crypto.Hash) (result bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = false
+		}
+	}()
+	return p.Proof != nil && p.Proof.Verify(p.SCID, index, &p.Statement, txid, p.BurnValue)
+}
@@ -217,6 +217,11 @@ func handle_easymenu_pre_open_command(l *readline.Instance, line string) {
 
 // sets online mode, starts RPC server etc
 func common_processing(wallet *walletapi.Wallet_Disk) {
+	if globals.Arguments["--light"] != nil && globals.Arguments["--light"].(bool) == true {
+		wallet.SetLightMode(true)
+		logger.Info("Wallet will verify balances using merkle proofs (light mode)")
+	}
+
 	if globals.Arguments["--offline"].(bool) == true {
 		//offline_mode = true
 	} else {
@@ -74,8 +74,10 @@ Usage:
   --generate-new-wallet       Generate new wallet
   --restore-deterministic-wallet    Restore wallet from previously saved recovery seed
   --electrum-seed=<recovery-seed>   Seed to use while restoring wallet
+  --restore-from-shares    Restore wallet from seed shares (see seed_shares command)
   --socks-proxy=<socks_ip:port>  Use a proxy to connect to Daemon.
   --remote      use hard coded remote daemon https://rwallet.dero.live
+  --light       Verify balances using merkle proofs and block headers, do not trust daemon
   --daemon-address=<host:port>    Use daemon instance at <host>:<port> or https://domain
   --rpc-server      Run rpc server, so wallet is accessible using api
   --rpc-bind=<127.0.0.1:20209>  Wallet binds on this ip address and port
@@ -222,6 +224,44 @@ func main() {
 		logger.Info("Successfully recovered wallet from seed")
 	}
 
+	// user wants to recover wallet from shares, prompt for shares till an empty line
+	if globals.Arguments["--restore-from-shares"] != nil && globals.Arguments["--restore-from-shares"].(bool) {
+		var shares []string
+		for {
+			share := strings.TrimSpace(read_line_with_prompt(l, fmt.Sprintf("Enter share %d (%d words, empty line when done) : ", len(shares)+1, mnemonics.SHARE_LENGTH)))
+			if share == "" {
+				break
+			}
+			if len(strings.Fields(share)) != mnemonics.SHARE_LENGTH {
+				logger.Error(fmt.Errorf("share must be %d words", mnemonics.SHARE_LENGTH), "Please enter share again")
+				continue
+			}
+			shares = append(shares, share)
+		}
+
+		account, err := walletapi.Generate_Account_From_Recovery_Words(strings.Join(shares, " "))
+		if err != nil {
+			logger.Error(err, "Error while recovering seed from shares.")
+			return
+		}
+
+		// ask user a pass, if not provided on command_line
+		password := ""
+		if wallet_password == "" {
+			password = ReadConfirmedPassword(l, "Enter password", "Confirm password")
+		}
+
+		wallet, err = walletapi.Create_Encrypted_Wallet(wallet_file, password, account.Keys.Secret)
+		if err != nil {
+			logger.Error(err, "Error occurred while restoring wallet")
+			return
+		}
+		wallet.SetSeedLanguage(account.SeedLanguage)
+
+		logger.V(1).Info("Seed Language", "language", account.SeedLanguage)
+		logger.Info("Successfully recovered wallet from shares")
+	}
+
 	// generare new random account if requested
 	if globals.Arguments["--generate-new-wallet"] != nil && globals.Arguments["--generate-new-wallet"].(bool) {
 		var filename string
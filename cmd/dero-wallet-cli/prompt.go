@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -33,10 +34,10 @@ import (
 	"github.com/deroproject/derohe/globals"
 	"github.com/deroproject/derohe/rpc"
 	"github.com/deroproject/derohe/walletapi"
+	"github.com/deroproject/derohe/walletapi/mnemonics"
 )
 
 //import "io/ioutil"
-//import "path/filepath"
 
 var account walletapi.Account
 
@@ -68,11 +69,13 @@ func handle_prompt_command(l *readline.Instance, line string) {
 
 	// handled closed wallet commands
 	switch command {
-	case "address", "rescan_bc", "seed", "set", "password", "get_tx_key", "i8", "payment_id":
+	case "address", "rescan_bc", "seed", "seed_shares", "set", "password", "get_tx_key", "i8", "payment_id":
 		fallthrough
 	case "spendkey", "transfer", "close":
 		fallthrough
-	case "transfer_all", "sweep_all", "show_transfers", "balance", "status":
+	case "transfer_all", "sweep_all", "show_transfers", "export_transfers", "balance", "status":
+		fallthrough
+	case "sc_install", "sc_invoke", "sc_view":
 		if wallet == nil {
 			logger.Error(err, "No wallet available")
 			return
@@ -162,6 +165,21 @@ func handle_prompt_command(l *readline.Instance, line string) {
 		}
 		display_seed(l, wallet) // seed should be given only to authenticated users
 
+	case "seed_shares": // split seed into shares, if password is valid
+		group_threshold, groups, err := parse_share_groups(line_parts[1:])
+		if err != nil {
+			logger.Error(err, "seed_shares needs group threshold followed by groups as input parameter")
+			logger.Info("eg. seed_shares 1 2of3            (any 2 of 3 shares recover the seed)")
+			logger.Info("eg. seed_shares 2 2of3 3of5 1of1  (shares from any 2 groups recover the seed)")
+			break
+		}
+		if !ValidateCurrentPassword(l, wallet) {
+			logger.Error(err, "Invalid password")
+			PressAnyKey(l, wallet)
+			break
+		}
+		display_seed_shares(l, wallet, group_threshold, groups)
+
 	case "spendkey": // give user his spend key
 		display_spend_key(l, wallet)
 
@@ -302,6 +320,9 @@ func handle_prompt_command(l *readline.Instance, line string) {
 			break
 		}
 
+	case "export_transfers":
+		export_transfers(wallet, line_parts[1:])
+
 	case "set": // set/display different settings
 		handle_set_command(l, line)
 	case "close": // close the account
@@ -364,6 +385,15 @@ func handle_prompt_command(l *readline.Instance, line string) {
 			//fmt.Printf("queued tx err %s\n", err)
 			//build_relay_transaction(l, uid, err, offline_tx, amount_list)
 		}
+	case "sc_install":
+		sc_install(l, wallet, line_parts[1:])
+
+	case "sc_invoke":
+		sc_invoke(l, wallet, line_parts[1:])
+
+	case "sc_view":
+		sc_view(l, wallet, line_parts[1:])
+
 	case "transfer":
 		// parse the address, amount pair
 		/*
@@ -488,6 +518,11 @@ func handle_set_command(l *readline.Instance, line string) {
 			help = true
 			break
 		}
+		if strings.ToLower(line_parts[2]) == "auto" {
+			wallet.SetFeeMultiplierAuto(true)
+			logger.Info("Transaction priority follows daemon fee estimate", "priority", wallet.GetFeeMultiplier())
+			break
+		}
 		s, err := strconv.ParseFloat(line_parts[2], 64)
 		if err != nil {
 			logger.Error(err, "Error parsing priority")
@@ -514,6 +549,11 @@ func handle_set_command(l *readline.Instance, line string) {
 
 		fmt.Fprintf(l.Stderr(), color_normal+"Priority: "+color_extra_white+"%0.2f\t"+color_normal+"eg. "+color_extra_white+"set priority 4.0\t"+color_normal+"Transaction priority on DERO network \n", wallet.GetFeeMultiplier())
 		fmt.Fprintf(l.Stderr(), "\t\tMinimum priority is 1.00. High priority = high fees\n")
+		if wallet.GetFeeMultiplierAuto() {
+			fmt.Fprintf(l.Stderr(), "\t\tPriority is auto, it follows daemon fee estimate for next block\n")
+		} else {
+			fmt.Fprint(l.Stderr(), "\t\teg. "+color_extra_white+"set priority auto"+color_normal+" to follow daemon fee estimate\n")
+		}
 
 	}
 }
@@ -951,13 +991,18 @@ var completer = readline.NewPrefixCompleter(
 	readline.PcItem("payment_id"),
 	readline.PcItem("print_height"),
 	readline.PcItem("seed"),
+	readline.PcItem("seed_shares"),
 
 	readline.PcItem("set",
 		readline.PcItem("mixin"),
 		readline.PcItem("seed"),
 		readline.PcItem("priority"),
 	),
+	readline.PcItem("sc_install"),
+	readline.PcItem("sc_invoke"),
+	readline.PcItem("sc_view"),
 	readline.PcItem("show_transfers"),
+	readline.PcItem("export_transfers"),
 	readline.PcItem("spendkey"),
 	readline.PcItem("status"),
 	readline.PcItem("version"),
@@ -981,7 +1026,17 @@ func usage(w io.Writer) {
 	io.WriteString(w, "\t\033[1mpassword\033[0m\tChange wallet password\n")
 	io.WriteString(w, "\t\033[1mpayment_id\033[0m\tPrint random Payment ID (for encrypted version see integrated_address)\n")
 	io.WriteString(w, "\t\033[1mseed\033[0m\t\tDisplay seed\n")
+	io.WriteString(w, "\t\033[1mseed_shares\033[0m\tSplit seed into shares, restore using --restore-from-shares\n")
+	io.WriteString(w, "\t\t\tEg. seed_shares <group threshold> <threshold>of<count> ...\n")
+	io.WriteString(w, "\t\033[1msc_install\033[0m\tInstall smart contract from .bas file, shows gas estimate before sending\n")
+	io.WriteString(w, "\t\t\tEg. sc_install <file.bas> [name:type=value ...] [deposit=<amount>]\n")
+	io.WriteString(w, "\t\033[1msc_invoke\033[0m\tInvoke smart contract entrypoint, types are string, uint64 and hash\n")
+	io.WriteString(w, "\t\t\tEg. sc_invoke <scid> <entrypoint> [name:type=value ...] [deposit=<amount>] [token_deposit=<amount>]\n")
+	io.WriteString(w, "\t\033[1msc_view\033[0m\t\tShow smart contract balance and variables\n")
+	io.WriteString(w, "\t\t\tEg. sc_view <scid> [code]\n")
 	io.WriteString(w, "\t\033[1mshow_transfers\033[0m\tShow all transactions to/from current wallet\n")
+	io.WriteString(w, "\t\033[1mexport_transfers\033[0m\tExport transactions of DERO and all tokens to csv/jsonl file, amounts are in atomic units\n")
+	io.WriteString(w, "\t\t\tEg. export_transfers <file.csv|file.jsonl> [scid=<scid>] [dir=in|out|coinbase] [min_height=N] [max_height=N] [from=2006-01-02] [to=2006-01-02] [dstport=N]\n")
 	io.WriteString(w, "\t\033[1mset\033[0m\t\tSet/get various settings\n")
 	io.WriteString(w, "\t\033[1mstatus\033[0m\t\tShow general information and balance\n")
 	io.WriteString(w, "\t\033[1mspendkey\033[0m\tView secret key\n")
@@ -1003,6 +1058,43 @@ func display_seed(l *readline.Instance, wallet *walletapi.Wallet_Disk) {
 
 }
 
+// parse group threshold and groups such as "2 2of3 3of5"
+func parse_share_groups(args []string) (group_threshold int, groups []mnemonics.Share_Group, err error) {
+	if len(args) < 2 {
+		err = fmt.Errorf("insufficient parameters")
+		return
+	}
+	if group_threshold, err = strconv.Atoi(args[0]); err != nil {
+		return
+	}
+	for _, arg := range args[1:] {
+		var group mnemonics.Share_Group
+		if _, err = fmt.Sscanf(strings.ToLower(arg), "%dof%d", &group.Threshold, &group.Count); err != nil {
+			err = fmt.Errorf("cannot parse group '%s'", arg)
+			return
+		}
+		groups = append(groups, group)
+	}
+	return
+}
+
+// display seed shares to the user in his preferred language
+func display_seed_shares(l *readline.Instance, wallet *walletapi.Wallet_Disk, group_threshold int, groups []mnemonics.Share_Group) {
+	shares, err := wallet.GetSeedShares(group_threshold, groups)
+	if err != nil {
+		logger.Error(err, "Cannot split seed into shares")
+		return
+	}
+
+	fmt.Fprintf(l.Stderr(), color_green+"PLEASE NOTE: shares from any %d group(s) can be used to recover access to your wallet. Hand over each share to a different person and store them separately."+color_white+"\n", group_threshold)
+	for i := range shares {
+		fmt.Fprintf(l.Stderr(), "Group %d: any %d of %d shares\n", i+1, groups[i].Threshold, groups[i].Count)
+		for j := range shares[i] {
+			fmt.Fprintf(os.Stderr, "  Share %d: "+color_red+"%s"+color_white+"\n", j+1, shares[i][j])
+		}
+	}
+}
+
 // display spend key
 // viewable wallet do not have spend secret key
 // TODO wee need to give user a warning if we are printing secret
@@ -1160,3 +1252,95 @@ func show_transfers(l *readline.Instance, wallet *walletapi.Wallet_Disk, scid cr
 	}
 
 }
+
+// export the transfers to a file, format is decided by file extension
+// filters are supplied as key=value pairs
+func export_transfers(wallet *walletapi.Wallet_Disk, args []string) {
+	if len(args) < 1 {
+		logger.Error(nil, "export_transfers needs output file as input parameter", "eg", "export_transfers history.csv dir=in from=2022-01-01")
+		return
+	}
+
+	filename := args[0]
+	format := walletapi.EXPORT_CSV
+	if ext := strings.ToLower(filepath.Ext(filename)); ext == ".json" || ext == ".jsonl" {
+		format = walletapi.EXPORT_JSONL
+	}
+
+	filter := walletapi.Export_Filter{Coinbase: true, In: true, Out: true}
+	for _, arg := range args[1:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			logger.Error(nil, "filters must be key=value pairs", "filter", arg)
+			return
+		}
+
+		var err error
+		switch strings.ToLower(kv[0]) {
+		case "scid":
+			var scid crypto.Hash
+			if err = scid.UnmarshalText([]byte(kv[1])); err == nil {
+				filter.SCIDs = append(filter.SCIDs, scid)
+			}
+		case "dir":
+			switch strings.ToLower(kv[1]) {
+			case "coinbase":
+				filter.In, filter.Out = false, false
+			case "in":
+				filter.Coinbase, filter.Out = false, false
+			case "out":
+				filter.Coinbase, filter.In = false, false
+			default:
+				err = fmt.Errorf("dir must be in, out or coinbase")
+			}
+		case "min_height":
+			filter.Min_Height, err = strconv.ParseUint(kv[1], 10, 64)
+		case "max_height":
+			filter.Max_Height, err = strconv.ParseUint(kv[1], 10, 64)
+		case "from":
+			filter.Min_Time, err = time.Parse("2006-01-02", kv[1])
+		case "to":
+			if filter.Max_Time, err = time.Parse("2006-01-02", kv[1]); err == nil {
+				filter.Max_Time = filter.Max_Time.Add(24*time.Hour - time.Nanosecond) // include entire day
+			}
+		case "dstport":
+			filter.DestinationPort, err = strconv.ParseUint(kv[1], 0, 64)
+		case "format":
+			format = strings.ToLower(kv[1])
+		default:
+			err = fmt.Errorf("unknown filter")
+		}
+		if err != nil {
+			logger.Error(err, "Error parsing filter", "filter", arg)
+			return
+		}
+	}
+
+	if wallet.GetMode() && walletapi.IsDaemonOnline() { // bring history uptodate before exporting
+		scids := filter.SCIDs
+		if len(scids) == 0 {
+			for scid := range wallet.GetAccount().EntriesNative {
+				scids = append(scids, scid)
+			}
+		}
+		for _, scid := range scids {
+			if err := wallet.Sync_Wallet_Memory_With_Daemon_internal(scid); err != nil {
+				logger.Error(err, "Error syncing wallet", "scid", scid.String())
+				return
+			}
+		}
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Error(err, "Cannot create output file", "file", filename)
+		return
+	}
+	defer f.Close()
+
+	if err = wallet.Export_Transfers_To(f, format, filter); err != nil {
+		logger.Error(err, "Error exporting transfers", "file", filename)
+		return
+	}
+	logger.Info("successfully exported transfers", "file", filename, "format", format)
+}
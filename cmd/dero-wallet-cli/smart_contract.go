@@ -0,0 +1,297 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/globals"
+	"github.com/deroproject/derohe/rpc"
+	"github.com/deroproject/derohe/walletapi"
+)
+
+// this file implements smart contract install/invoke/view commands
+
+// parse SC arguments of the form name:type=value, supported types are string(S), uint64(U) and hash(H)
+// deposit=<amount> and token_deposit=<amount> are reserved for DERO and token deposits
+func parse_sc_arguments(args []string) (scargs rpc.Arguments, dero_deposit, token_deposit uint64, err error) {
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			err = fmt.Errorf("cannot parse argument '%s', expected name:type=value", arg)
+			return
+		}
+
+		switch strings.ToLower(kv[0]) {
+		case "deposit":
+			if dero_deposit, err = globals.ParseAmount(kv[1]); err != nil {
+				return
+			}
+			continue
+		case "token_deposit":
+			if token_deposit, err = strconv.ParseUint(kv[1], 10, 64); err != nil {
+				return
+			}
+			continue
+		}
+
+		nt := strings.SplitN(kv[0], ":", 2)
+		if len(nt) != 2 || len(nt[0]) < 1 {
+			err = fmt.Errorf("cannot parse argument '%s', expected name:type=value", arg)
+			return
+		}
+
+		name, value := nt[0], kv[1]
+		switch strings.ToLower(nt[1]) {
+		case "s", "string":
+			scargs = append(scargs, rpc.Argument{Name: name, DataType: rpc.DataString, Value: value})
+		case "u", "uint64":
+			var v uint64
+			if v, err = strconv.ParseUint(value, 0, 64); err != nil {
+				err = fmt.Errorf("cannot parse uint64 argument '%s' err %s", name, err)
+				return
+			}
+			scargs = append(scargs, rpc.Argument{Name: name, DataType: rpc.DataUint64, Value: v})
+		case "h", "hash":
+			var h crypto.Hash
+			if err = h.UnmarshalText([]byte(value)); err != nil {
+				err = fmt.Errorf("cannot parse hash argument '%s' err %s", name, err)
+				return
+			}
+			scargs = append(scargs, rpc.Argument{Name: name, DataType: rpc.DataHash, Value: h})
+		default:
+			err = fmt.Errorf("unknown type '%s' for argument '%s', supported types are string, uint64 and hash", nt[1], name)
+			return
+		}
+	}
+	return
+}
+
+// install an SC from a .bas file, optional arguments are passed to Initialize
+func sc_install(l *readline.Instance, wallet *walletapi.Wallet_Disk, args []string) {
+	if len(args) < 1 {
+		logger.Error(nil, "sc_install needs SC file as input parameter", "eg", "sc_install token.bas")
+		return
+	}
+
+	code, err := os.ReadFile(args[0])
+	if err != nil {
+		logger.Error(err, "Cannot read SC file", "file", args[0])
+		return
+	}
+
+	scargs, dero_deposit, token_deposit, err := parse_sc_arguments(args[1:])
+	if err != nil {
+		logger.Error(err, "Error parsing SC arguments")
+		return
+	}
+	if token_deposit != 0 {
+		logger.Error(nil, "token deposit is not possible while installing SC")
+		return
+	}
+
+	transfers, err := sc_deposit_transfers(wallet, crypto.Hash{}, dero_deposit, 0)
+	if err != nil {
+		logger.Error(err, "Error preparing deposit")
+		return
+	}
+
+	scargs = append(scargs, rpc.Argument{Name: rpc.SCACTION, DataType: rpc.DataUint64, Value: uint64(rpc.SC_INSTALL)})
+	scargs = append(scargs, rpc.Argument{Name: rpc.SCCODE, DataType: rpc.DataString, Value: string(code)})
+
+	gas, err := wallet.GetGasEstimate(rpc.GasEstimate_Params{Transfers: transfers, SC_Code: string(code), SC_RPC: scargs})
+	if err != nil {
+		logger.Error(err, "Error estimating gas, SC will most probably fail to install")
+		return
+	}
+
+	if txid, ok := sc_dispatch(l, wallet, transfers, scargs, gas); ok {
+		logger.Info("SC install dispatched, SCID is same as txid", "scid", txid)
+	}
+}
+
+// invoke an SC entrypoint
+func sc_invoke(l *readline.Instance, wallet *walletapi.Wallet_Disk, args []string) {
+	if len(args) < 2 {
+		logger.Error(nil, "sc_invoke needs scid and entrypoint as input parameter", "eg", "sc_invoke <scid> Transfer amount:uint64=10 deposit=1.5")
+		return
+	}
+
+	var scid crypto.Hash
+	if err := scid.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error(err, "Error parsing scid", "scid", args[0])
+		return
+	}
+
+	scargs, dero_deposit, token_deposit, err := parse_sc_arguments(args[2:])
+	if err != nil {
+		logger.Error(err, "Error parsing SC arguments")
+		return
+	}
+
+	transfers, err := sc_deposit_transfers(wallet, scid, dero_deposit, token_deposit)
+	if err != nil {
+		logger.Error(err, "Error preparing deposit")
+		return
+	}
+
+	scargs = append(scargs, rpc.Argument{Name: "entrypoint", DataType: rpc.DataString, Value: args[1]})
+	scargs = append(scargs, rpc.Argument{Name: rpc.SCACTION, DataType: rpc.DataUint64, Value: uint64(rpc.SC_CALL)})
+	scargs = append(scargs, rpc.Argument{Name: rpc.SCID, DataType: rpc.DataHash, Value: scid})
+
+	gas, err := wallet.GetGasEstimate(rpc.GasEstimate_Params{Transfers: transfers, SC_RPC: scargs})
+	if err != nil {
+		logger.Error(err, "Error estimating gas, SC call will most probably fail")
+		return
+	}
+
+	if txid, ok := sc_dispatch(l, wallet, transfers, scargs, gas); ok {
+		logger.Info("Dispatched tx", "txid", txid)
+	}
+}
+
+// DERO deposits are burnt to a random ring member other than ourself, token deposits are burnt with the SC token
+func sc_deposit_transfers(wallet *walletapi.Wallet_Disk, scid crypto.Hash, dero_deposit, token_deposit uint64) (transfers []rpc.Transfer, err error) {
+	if dero_deposit >= 1 {
+		var zeroscid crypto.Hash
+		for _, k := range wallet.Random_ring_members(zeroscid) {
+			if k != wallet.GetAddress().String() { // make sure random member is not equal to ourself
+				transfers = append(transfers, rpc.Transfer{Destination: k, Amount: 0, Burn: dero_deposit})
+				break
+			}
+		}
+		if len(transfers) < 1 {
+			return nil, fmt.Errorf("could not obtain ring members")
+		}
+	}
+	if token_deposit >= 1 {
+		transfers = append(transfers, rpc.Transfer{SCID: scid, Amount: 0, Burn: token_deposit})
+	}
+	return
+}
+
+// build the tx, display gas and fees, and dispatch it after user confirms
+func sc_dispatch(l *readline.Instance, wallet *walletapi.Wallet_Disk, transfers []rpc.Transfer, scargs rpc.Arguments, gas rpc.GasEstimate_Result) (txid string, ok bool) {
+	tx, err := wallet.TransferPayload0(transfers, 0, false, scargs, 0, false)
+	if err != nil {
+		logger.Error(err, "Error while building Transaction")
+		return
+	}
+
+	if gas.GasStorage > tx.Fees() { // fees must cover storage gas
+		if tx, err = wallet.TransferPayload0(transfers, 0, false, scargs, gas.GasStorage, false); err != nil {
+			logger.Error(err, "Error while building Transaction")
+			return
+		}
+	}
+
+	fmt.Fprintf(l.Stderr(), "Gas Compute: %d  Gas Storage: %d\n", gas.GasCompute, gas.GasStorage)
+	for _, t := range transfers {
+		if t.Burn > 0 && t.SCID.IsZero() {
+			fmt.Fprintf(l.Stderr(), "Deposit DERO: "+color_green+"%s"+color_white+"\n", globals.FormatMoney(t.Burn))
+		} else if t.Burn > 0 { // tokens have no decimal places
+			fmt.Fprintf(l.Stderr(), "Deposit SCID %s: "+color_green+"%d"+color_white+"\n", t.SCID, t.Burn)
+		}
+	}
+	fmt.Fprintf(l.Stderr(), "Fees: "+color_green+"%s"+color_white+"\n", globals.FormatMoney(tx.Fees()))
+
+	if !ConfirmYesNoDefaultNo(l, "Confirm Transaction (y/N)") {
+		return
+	}
+
+	if err = wallet.SendTransaction(tx); err != nil {
+		logger.Error(err, "Error while dispatching Transaction")
+		return
+	}
+	return tx.GetHash().String(), true
+}
+
+// display SC balance, variables and optionally code
+func sc_view(l *readline.Instance, wallet *walletapi.Wallet_Disk, args []string) {
+	if len(args) < 1 {
+		logger.Error(nil, "sc_view needs scid as input parameter", "eg", "sc_view <scid> [code]")
+		return
+	}
+
+	var scid crypto.Hash
+	if err := scid.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error(err, "Error parsing scid", "scid", args[0])
+		return
+	}
+	show_code := len(args) >= 2 && strings.ToLower(args[1]) == "code"
+
+	sc, err := wallet.GetSC(rpc.GetSC_Params{SCID: scid.String(), Code: true, Variables: true})
+	if err != nil {
+		logger.Error(err, "Error obtaining SC", "scid", scid.String())
+		return
+	}
+	if sc.Code == "" {
+		logger.Error(nil, "SC not found", "scid", scid.String())
+		return
+	}
+
+	fmt.Fprintf(l.Stderr(), "SCID %s Balance: "+color_green+"%s"+color_white+"\n", scid, globals.FormatMoney(sc.Balance))
+	for token, balance := range sc.Balances {
+		if token != (crypto.Hash{}).String() {
+			fmt.Fprintf(l.Stderr(), "  Token %s Balance: %d\n", token, balance)
+		}
+	}
+
+	var keys []string
+	for k := range sc.VariableStringKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(l.Stderr(), "  %s = %s\n", k, sc_variable_string(sc.VariableStringKeys[k]))
+	}
+
+	var ukeys []uint64
+	for k := range sc.VariableUint64Keys {
+		ukeys = append(ukeys, k)
+	}
+	sort.Slice(ukeys, func(i, j int) bool { return ukeys[i] < ukeys[j] })
+	for _, k := range ukeys {
+		fmt.Fprintf(l.Stderr(), "  %d = %s\n", k, sc_variable_string(sc.VariableUint64Keys[k]))
+	}
+
+	if show_code {
+		fmt.Fprintf(l.Stderr(), "%s\n", sc.Code)
+	}
+}
+
+// string values are hex encoded by daemon, show them as text if printable
+func sc_variable_string(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		if data, err := hex.DecodeString(value); err == nil && isASCII(string(data)) {
+			return strconv.Quote(string(data))
+		}
+		return value
+	case float64: // json numbers
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -59,7 +59,7 @@ var command_line string = `derod
 DERO : A secure, private blockchain with smart-contracts
 
 Usage:
-  derod [--help] [--version] [--testnet] [--debug]  [--sync-node] [--timeisinsync] [--fastsync] [--socks-proxy=<socks_ip:port>] [--data-dir=<directory>] [--p2p-bind=<0.0.0.0:18089>] [--add-exclusive-node=<ip:port>]... [--add-priority-node=<ip:port>]... [--min-peers=<11>] [--max-peers=<100>] [--rpc-bind=<127.0.0.1:9999>] [--getwork-bind=<0.0.0.0:18089>] [--node-tag=<unique name>] [--prune-history=<50>] [--integrator-address=<address>] [--clog-level=1] [--flog-level=1] [--log-dir=<dir>]
+  derod [--help] [--version] [--testnet] [--debug]  [--sync-node] [--timeisinsync] [--fastsync] [--socks-proxy=<socks_ip:port>] [--data-dir=<directory>] [--p2p-bind=<0.0.0.0:18089>] [--p2p-tcp-bind=<0.0.0.0:18089>] [--add-exclusive-node=<ip:port>]... [--add-priority-node=<ip:port>]... [--dns-seed=<host>]... [--peer-list-file=<file>] [--min-peers=<11>] [--max-peers=<100>] [--rpc-bind=<127.0.0.1:9999>] [--getwork-bind=<0.0.0.0:18089>] [--node-tag=<unique name>] [--prune-history=<50>] [--verify-db] [--integrator-address=<address>] [--pool-mode] [--pool-share-difficulty=<diff>] [--pool-pplns-window=<10000>] [--p2p-upload-limit=<KB/s>] [--p2p-download-limit=<KB/s>] [--p2p-peer-upload-limit=<KB/s>] [--p2p-peer-download-limit=<KB/s>] [--p2p-inbound-filter=<file>] [--clog-level=1] [--flog-level=1] [--log-dir=<dir>]
   derod -h | --help
   derod --version
 
@@ -72,19 +72,31 @@ Options:
   --flog-level=1	Set file log level (0 to 127)
   --fastsync      Fast sync mode (this option has effect only while bootstrapping)
   --timeisinsync  Confirms to daemon that time is in sync, so daemon doesn't try to sync
-  --socks-proxy=<socks_ip:port>  Use a proxy to connect to network.
+  --socks-proxy=<socks_ip:port>  Use a socks5 proxy such as tor to connect to network, connections are made over tcp.
   --data-dir=<directory>    Store blockchain data at this location
   --rpc-bind=<127.0.0.1:9999>    RPC listens on this ip:port
   --p2p-bind=<0.0.0.0:18089>    p2p server listens on this ip:port, specify port 0 to disable listening server
+  --p2p-tcp-bind=<0.0.0.0:18089>    p2p server also accepts tcp connections on this ip:port, needed by nodes connecting through socks proxies, disabled by default
   --getwork-bind=<0.0.0.0:10100>    getwork server listens on this ip:port, specify port 0 to disable listening server
   --add-exclusive-node=<ip:port>	Connect to specific peer only 
   --add-priority-node=<ip:port>	Maintain persistant connection to specified peer
   --sync-node       Sync node automatically with the seeds nodes. This option is for rare use.
   --node-tag=<unique name>	Unique name of node, visible to everyone
   --integrator-address	if this node mines a block,Integrator rewards will be given to address.default is dev's address.
+  --pool-mode	getwork server works as a pool, miners mine for integrator address at share difficulty and are credited PPLNS.
+  --pool-share-difficulty=<diff>	difficulty of pool shares, default is network difficulty / 100
+  --pool-pplns-window=<10000>	pool rewards are split among these many last shares
+  --dns-seed=<host>	resolve A/AAAA/TXT records of this host to find seed nodes, no dns seeds are built in, so dns seeding only works with this option
+  --peer-list-file=<file>	seed nodes from this file, file must be clearsigned with Captain_Dero_pub.txt key
   --min-peers=<31>	  Node will try to maintain atleast this many connections to peers
   --max-peers=<101>	  Node will maintain maximim this many connections to peers and will stop accepting connections
+  --p2p-upload-limit=<KB/s>	limit total p2p upload, block propagation is sent before bootstrap traffic, default unlimited
+  --p2p-download-limit=<KB/s>	limit total p2p download, default unlimited
+  --p2p-peer-upload-limit=<KB/s>	limit p2p upload to each peer, default unlimited
+  --p2p-peer-download-limit=<KB/s>	limit p2p download from each peer, default unlimited
+  --p2p-inbound-filter=<file>	json file with allow/deny lists and subnet caps for inbound peers, reloaded on change
   --prune-history=<50>	prunes blockchain history until the specific topo_height
+  --verify-db	verify topo store, blocks, txs and balance state for every topoheight and exit, resumes from last checkpoint
   --log-dir=<directory> Logs will be placed in this directory
 
   `
@@ -214,6 +226,12 @@ func main() {
 
 	params["chain"] = chain
 
+	if globals.Arguments["--verify-db"] != nil && globals.Arguments["--verify-db"].(bool) {
+		verify_db(chain, false)
+		chain.Shutdown()
+		return
+	}
+
 	// since user is using a proxy, he definitely does not want to give out his IP
 	if globals.Arguments["--socks-proxy"] != nil {
 		globals.Arguments["--p2p-bind"] = ":0"
@@ -460,6 +478,26 @@ restart_loop:
 				logger.Info("will use", "integrator_address", chain.IntegratorAddress().String())
 			}
 
+		case command == "pool_paid": // record a payout done to a pool miner
+			if len(line_parts) < 3 || len(line_parts) > 4 {
+				logger.Error(fmt.Errorf("This function requires 2 or 3 parameters, dero address, amount and optionally txid"), "")
+				continue
+			}
+			amount, err := globals.ParseAmount(line_parts[2])
+			if err != nil {
+				logger.Error(err, "invalid amount")
+				continue
+			}
+			txid := ""
+			if len(line_parts) == 4 {
+				txid = line_parts[3]
+			}
+			if err := derodrpc.PoolRecordPayout(line_parts[1], amount, txid); err != nil {
+				logger.Error(err, "could not record payout")
+				continue
+			}
+			logger.Info("payout recorded", "address", line_parts[1], "amount", globals.FormatMoney(amount))
+
 		case command == "print_bc":
 
 			logger.Info("printing block chain")
@@ -1001,6 +1039,55 @@ restart_loop:
 				logger.Error(fmt.Errorf("POP needs argument n to pop this many blocks from the top"), "")
 			}
 
+		case command == "supply": // supply [topoheight] [scan]
+			topoheight := chain.Load_TOPO_HEIGHT()
+			scan := false
+			for _, arg := range line_parts[1:] {
+				if arg == "scan" {
+					scan = true
+				} else if s, err := strconv.ParseInt(arg, 10, 64); err == nil && s >= 0 && s <= topoheight {
+					topoheight = s
+				} else {
+					fmt.Printf("usage: supply [topoheight] [scan]\n")
+					topoheight = -1
+					break
+				}
+			}
+			if topoheight < 0 {
+				continue
+			}
+
+			hash, err := chain.Load_Block_Topological_order_at_index(topoheight)
+			if err != nil {
+				fmt.Printf("err while loading block at topoheight %d err %s\n", topoheight, err)
+				continue
+			}
+			record, err := chain.Load_Supply(hash)
+			if err != nil {
+				fmt.Printf("err while loading supply err %s\n", err)
+				continue
+			}
+			fmt.Printf("Supply at topoheight %d block %s\n", topoheight, hash)
+			print_supply(record)
+
+			if scan {
+				fmt.Printf("scanning from genesis, this may take a while\n")
+				scanned, err := chain.Scan_Supply(hash)
+				if err != nil {
+					fmt.Printf("err while scanning supply err %s\n", err)
+					continue
+				}
+				if mismatches := record.Diff(scanned); len(mismatches) >= 1 {
+					fmt.Printf("MISMATCH in %s, scanned values\n", strings.Join(mismatches, ","))
+					print_supply(scanned)
+				} else {
+					fmt.Printf("full scan matches the stored record\n")
+				}
+			}
+
+		case command == "verify_db": // verify_db [restart]
+			verify_db(chain, len(line_parts) == 2 && line_parts[1] == "restart")
+
 		case command == "gc":
 			runtime.GC()
 		case command == "heap":
@@ -1051,6 +1138,15 @@ restart_loop:
 		case command == "bans":
 			p2p.BanList_Print() // print ban list
 
+		case command == "inbound_filter":
+			if len(line_parts) == 2 && line_parts[1] == "reload" {
+				if err := p2p.Inbound_Filter_Reload(); err != nil {
+					logger.Error(err, "Could not reload inbound filter")
+					break
+				}
+			}
+			p2p.Inbound_Filter_Print()
+
 		case line == "sleep":
 			logger.Info("console sleeping for 1 second")
 			time.Sleep(1 * time.Second)
@@ -1158,6 +1254,53 @@ func WriteBlockChainTree(chain *blockchain.Blockchain, filename string, start_he
 	return
 }
 
+// verify entire db and report ranges which need to be popped/resynced
+func verify_db(chain *blockchain.Blockchain, restart bool) {
+	checkpoint_file := blockchain.Verify_Checkpoint_File()
+	last := time.Now()
+	cp, err := chain.Verify_DB(checkpoint_file, restart, func(topo, top int64) {
+		if time.Since(last) > 5*time.Second {
+			last = time.Now()
+			fmt.Printf("verified topoheight %d/%d\n", topo, top)
+		}
+	})
+	if err != nil {
+		fmt.Printf("err while verifying db err %s\n", err)
+		return
+	}
+
+	fmt.Printf("verified till topoheight %d, checkpoint %s\n", cp.TopoHeight, checkpoint_file)
+	for _, r := range cp.Ranges {
+		fmt.Printf("topoheight %d - %d : %s\n", r.Start, r.End, r.Reason)
+	}
+	switch first := cp.FirstBad(); {
+	case first < 0 && chain.State_Root_Active(0):
+		fmt.Printf("db is consistent\n")
+	case first < 0 && !chain.State_Root_Active(chain.Get_Height()): // blocks before STATE_ROOT_HEIGHT do not commit to state root
+		fmt.Printf("db is consistent, state roots were not checked since chain is below height %d\n", globals.Config.STATE_ROOT_HEIGHT)
+	case first < 0:
+		fmt.Printf("db is consistent, state roots were checked from height %d only, blocks below do not commit to them\n", globals.Config.STATE_ROOT_HEIGHT)
+	case first == 0:
+		fmt.Printf("genesis is corrupted, delete data directory and resync\n")
+	default: // chain may have grown while verifying, pop count must cover current top
+		fmt.Printf("run \"pop %d\" to remove topoheight %d onwards and let the node resync, then \"verify_db restart\"\n", chain.Load_TOPO_HEIGHT()-first+1, first)
+	}
+}
+
+func print_supply(r blockchain.Supply_Record) {
+	fmt.Printf("Premine      : %s\n", globals.FormatMoney(r.Premine))
+	fmt.Printf("Registration : %s\n", globals.FormatMoney(r.Registration))
+	fmt.Printf("Mined        : %s\n", globals.FormatMoney(r.Mined))
+	fmt.Printf("Fees         : %s\n", globals.FormatMoney(r.Fees))
+	fmt.Printf("Integrator   : %s\n", globals.FormatMoney(r.Integrator))
+	fmt.Printf("MiniBlocks   : %s\n", globals.FormatMoney(r.MiniBlocks))
+	fmt.Printf("Burned       : %s\n", globals.FormatMoney(r.Burned))
+	fmt.Printf("SC Deposits  : %s\n", globals.FormatMoney(r.SCDeposits))
+	fmt.Printf("SC Held      : %s\n", globals.FormatMoney(r.SCHeld))
+	fmt.Printf("Emitted      : %s\n", globals.FormatMoney(r.Emitted()))
+	fmt.Printf("Circulating  : %s\n", globals.FormatMoney(r.Circulating()))
+}
+
 func prettyprint_json(b []byte) []byte {
 	var out bytes.Buffer
 	err := json.Indent(&out, b, "", "  ")
@@ -1179,6 +1322,7 @@ func usage(w io.Writer) {
 	io.WriteString(w, "\t\033[1mban\033[0m\t\tBan specific ip from making any connections\n")
 	io.WriteString(w, "\t\033[1munban\033[0m\t\tRevoke restrictions on previously banned ips\n")
 	io.WriteString(w, "\t\033[1mbans\033[0m\t\tPrint current ban list\n")
+	io.WriteString(w, "\t\033[1minbound_filter\033[0m\tPrint inbound filter and connections per subnet, inbound_filter reload to reload file\n")
 	io.WriteString(w, "\t\033[1mmempool_print\033[0m\t\tprint mempool contents\n")
 	io.WriteString(w, "\t\033[1mmempool_delete_tx\033[0m\t\tDelete specific tx from mempool\n")
 	io.WriteString(w, "\t\033[1mmempool_flush\033[0m\t\tFlush regpool\n")
@@ -1186,6 +1330,9 @@ func usage(w io.Writer) {
 	io.WriteString(w, "\t\033[1mregpool_delete_tx\033[0m\t\tDelete specific tx from regpool\n")
 	io.WriteString(w, "\t\033[1mregpool_flush\033[0m\t\tFlush mempool\n")
 	io.WriteString(w, "\t\033[1msetintegratoraddress\033[0m\t\tChange current integrated address\n")
+	io.WriteString(w, "\t\033[1mverify_db\033[0m\tVerify topo store, blocks, txs and state of every topoheight, verify_db [restart]\n")
+	io.WriteString(w, "\t\033[1msupply\033[0m\t\tShow premine, rewards, burns and SC held DERO, needs an unpruned node, supply [topoheight] [scan]\n")
+	io.WriteString(w, "\t\033[1mpool_paid\033[0m\t\tRecord payout to a pool miner, pool_paid <address> <amount> [txid]\n")
 
 	io.WriteString(w, "\t\033[1mversion\033[0m\t\tShow version\n")
 	io.WriteString(w, "\t\033[1mexit\033[0m\t\tQuit the daemon\n")
@@ -1195,6 +1342,9 @@ func usage(w io.Writer) {
 
 var completer = readline.NewPrefixCompleter(
 	readline.PcItem("help"),
+	readline.PcItem("inbound_filter",
+		readline.PcItem("reload"),
+	),
 	readline.PcItem("diff"),
 	readline.PcItem("gc"),
 	readline.PcItem("mempool_dump"),
@@ -1205,6 +1355,7 @@ var completer = readline.NewPrefixCompleter(
 	readline.PcItem("regpool_delete_tx"),
 	readline.PcItem("regpool_print"),
 	readline.PcItem("peer_list"),
+	readline.PcItem("pool_paid"),
 	readline.PcItem("print_bc"),
 	readline.PcItem("print_block"),
 	readline.PcItem("block_export"),
@@ -1212,6 +1363,10 @@ var completer = readline.NewPrefixCompleter(
 	//	readline.PcItem("print_tx"),
 	readline.PcItem("setintegratoraddress"),
 	readline.PcItem("status"),
+	readline.PcItem("supply"),
+	readline.PcItem("verify_db",
+		readline.PcItem("restart"),
+	),
 	readline.PcItem("syncinfo"),
 	readline.PcItem("version"),
 	readline.PcItem("bye"),
@@ -0,0 +1,493 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "os"
+import "fmt"
+import "sort"
+import "sync"
+import "time"
+import "bytes"
+import "math/big"
+import "path/filepath"
+import "sync/atomic"
+import "encoding/hex"
+import "encoding/json"
+
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/metrics"
+
+// this file implements pool mode of getwork server
+// in pool mode, all miners mine to the integrator address and get work at a much lower share difficulty
+// every valid share is recorded against the miner's address, whenever a share also meets network difficulty
+// it is submitted to chain and its estimated reward is split among last N shares (PPLNS) weighted by difficulty
+// note that credits are estimates, orphaned miniblocks are still credited, payouts are done by the operator
+
+const POOL_SHARE_DIVISOR = 100          // default share difficulty is network difficulty / this
+const POOL_DEFAULT_WINDOW = 10000       // default PPLNS window in shares
+const POOL_MAX_JOBS = 256               // same as blockchain mining cache, older jobs are stale anyway
+const POOL_MAX_FOUND = 1000             // keep this many found miniblocks
+const POOL_HASHRATE_WINDOW = int64(600) // hashrate is estimated over these many seconds
+
+const pool_file = "pool.json"
+
+type pool_job struct {
+	height uint64
+	diff   *big.Int        // network difficulty of the job
+	mbl    block.MiniBlock // template as handed out, only nonce and flags can be changed by miners
+}
+
+type pool_share struct {
+	Address    string `json:"address"`
+	Difficulty uint64 `json:"difficulty"`
+	Time       int64  `json:"time"`
+}
+
+type pool_account struct {
+	Shares    uint64 `json:"shares"` // lifetime valid shares
+	LastShare int64  `json:"lastshare"`
+	Credited  uint64 `json:"credited"`
+	Paid      uint64 `json:"paid"`
+}
+
+type pool_state struct {
+	sync.Mutex
+	share_diff uint64 // fixed share difficulty, 0 means network difficulty / POOL_SHARE_DIVISOR
+	window     int
+
+	jobs map[uint64]pool_job    // job timestamp -> job
+	seen map[crypto.Hash]uint64 // deduplicates shares, mini hash -> job timestamp
+
+	Window   []pool_share             `json:"window"`
+	Accounts map[string]*pool_account `json:"accounts"`
+	Found    []rpc.Pool_Found         `json:"found"`
+	Payouts  []rpc.Pool_Payout        `json:"payouts"`
+
+	SharesValid     uint64 `json:"shares_valid"`
+	SharesInvalid   uint64 `json:"shares_invalid"`
+	SharesStale     uint64 `json:"shares_stale"`
+	SharesDuplicate uint64 `json:"shares_duplicate"`
+
+	dirty bool
+}
+
+var pool *pool_state // nil if pool mode is disabled
+
+func new_pool(share_diff uint64, window int) *pool_state {
+	if window <= 0 {
+		window = POOL_DEFAULT_WINDOW
+	}
+	return &pool_state{share_diff: share_diff, window: window, jobs: map[uint64]pool_job{}, seen: map[crypto.Hash]uint64{}, Accounts: map[string]*pool_account{}}
+}
+
+// returns share difficulty to be used for a job of specified network difficulty
+func (p *pool_state) share_difficulty(network_diff *big.Int) *big.Int {
+	diff := new(big.Int).SetUint64(p.share_diff)
+	if p.share_diff == 0 {
+		diff.Div(network_diff, big.NewInt(POOL_SHARE_DIVISOR))
+	}
+	if diff.Sign() <= 0 {
+		diff.SetUint64(1)
+	}
+	if diff.Cmp(network_diff) > 0 { // shares can never be harder than network
+		diff.Set(network_diff)
+	}
+	return diff
+}
+
+// remember a job, so shares can be verified against it
+func (p *pool_state) add_job(tstamp uint64, height uint64, network_diff *big.Int, mbl block.MiniBlock) {
+	p.Lock()
+	defer p.Unlock()
+	p.jobs[tstamp] = pool_job{height: height, diff: new(big.Int).Set(network_diff), mbl: mbl}
+
+	if len(p.jobs) > POOL_MAX_JOBS { // drop oldest jobs alongwith their dedup data
+		var stamps []uint64
+		for k := range p.jobs {
+			stamps = append(stamps, k)
+		}
+		sort.Slice(stamps, func(i, j int) bool { return stamps[i] < stamps[j] })
+		cutoff := stamps[len(stamps)-POOL_MAX_JOBS]
+		for _, k := range stamps[:len(stamps)-POOL_MAX_JOBS] {
+			delete(p.jobs, k)
+		}
+		for k, v := range p.seen {
+			if v < cutoff {
+				delete(p.seen, k)
+			}
+		}
+	}
+}
+
+// verifies a share, records it and reports whether it also meets network difficulty
+func (p *pool_state) submit(address string, tstamp uint64, mbl_bytes []byte, now time.Time) (network bool, err error) {
+	var mbl block.MiniBlock
+	if err = mbl.Deserialize(mbl_bytes); err != nil {
+		p.count(&p.SharesInvalid)
+		return false, fmt.Errorf("share could not be decoded. err: %s", err)
+	}
+
+	p.Lock()
+	job, found := p.jobs[tstamp]
+	p.Unlock()
+	if !found {
+		p.count(&p.SharesStale)
+		return false, fmt.Errorf("stale share, job %d not found", tstamp)
+	}
+
+	template := job.mbl // miners may only change nonce and flags
+	template.Flags = mbl.Flags
+	template.Nonce = mbl.Nonce
+	if !bytes.Equal(template.Serialize(), mbl.Serialize()) {
+		p.count(&p.SharesInvalid)
+		return false, fmt.Errorf("share does not match job %d", tstamp)
+	}
+
+	share_diff := p.share_difficulty(job.diff)
+	pow := mbl.GetPoWHash() // this is slow, so do it outside the lock
+	if !blockchain.CheckPowHashBig(pow, share_diff) {
+		p.count(&p.SharesInvalid)
+		return false, fmt.Errorf("share does not meet share difficulty %s", share_diff)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	mbl_hash := mbl.GetHash()
+	if _, ok := p.seen[mbl_hash]; ok {
+		p.SharesDuplicate++
+		return false, fmt.Errorf("duplicate share")
+	}
+	p.seen[mbl_hash] = tstamp
+
+	p.Window = append(p.Window, pool_share{Address: address, Difficulty: share_diff.Uint64(), Time: now.Unix()})
+	if len(p.Window) > p.window {
+		p.Window = append(p.Window[:0], p.Window[len(p.Window)-p.window:]...)
+	}
+	acc := p.account(address)
+	acc.Shares++
+	acc.LastShare = now.Unix()
+	p.SharesValid++
+	p.dirty = true
+
+	return blockchain.CheckPowHashBig(pow, job.diff), nil
+}
+
+func (p *pool_state) count(counter *uint64) {
+	p.Lock()
+	defer p.Unlock()
+	*counter++
+}
+
+// must be called with lock held
+func (p *pool_state) account(address string) *pool_account {
+	acc, ok := p.Accounts[address]
+	if !ok {
+		acc = &pool_account{}
+		p.Accounts[address] = acc
+	}
+	return acc
+}
+
+// estimated reward of a miniblock at specific height, fees are not known to us
+func pool_reward(height uint64) uint64 {
+	return blockchain.CalcBlockReward(height) / (config.BLOCK_TIME - config.MINIBLOCK_HIGHDIFF + 1)
+}
+
+// splits reward among shares in the window weighted by difficulty, leftover goes to finder
+func (p *pool_state) credit(height uint64, finder string, full_block bool, reward uint64, now time.Time) {
+	p.Lock()
+	defer p.Unlock()
+
+	weights := map[string]*big.Int{}
+	total := new(big.Int)
+	for _, s := range p.Window {
+		if _, ok := weights[s.Address]; !ok {
+			weights[s.Address] = new(big.Int)
+		}
+		d := new(big.Int).SetUint64(s.Difficulty)
+		weights[s.Address].Add(weights[s.Address], d)
+		total.Add(total, d)
+	}
+
+	distributed := uint64(0)
+	if total.Sign() > 0 {
+		for addr, w := range weights {
+			amount := new(big.Int).Mul(w, new(big.Int).SetUint64(reward))
+			amount.Div(amount, total)
+			p.account(addr).Credited += amount.Uint64()
+			distributed += amount.Uint64()
+		}
+	}
+	p.account(finder).Credited += reward - distributed
+
+	p.Found = append(p.Found, rpc.Pool_Found{Height: height, Time: now.Unix(), Finder: finder, Block: full_block, Reward: reward, Shares: uint64(len(p.Window))})
+	if len(p.Found) > POOL_MAX_FOUND {
+		p.Found = append(p.Found[:0], p.Found[len(p.Found)-POOL_MAX_FOUND:]...)
+	}
+	p.dirty = true
+}
+
+// records a payout done by operator
+func (p *pool_state) payout(address string, amount uint64, txid string, now time.Time) error {
+	p.Lock()
+	defer p.Unlock()
+	acc, ok := p.Accounts[address]
+	if !ok {
+		return fmt.Errorf("address %s has no pool account", address)
+	}
+	if acc.Paid+amount > acc.Credited {
+		return fmt.Errorf("payout %s exceeds balance %s", globals.FormatMoney(amount), globals.FormatMoney(acc.Credited-acc.Paid))
+	}
+	acc.Paid += amount
+	p.Payouts = append(p.Payouts, rpc.Pool_Payout{Address: address, Amount: amount, TXID: txid, Time: now.Unix()})
+	p.dirty = true
+	return nil
+}
+
+func (p *pool_state) stats(now time.Time) (result rpc.GetPoolStats_Result) {
+	p.Lock()
+	defer p.Unlock()
+
+	result.Enabled = true
+	result.ShareDifficulty = p.share_diff
+	result.Window = p.window
+	result.WindowShares = len(p.Window)
+	result.SharesValid = p.SharesValid
+	result.SharesInvalid = p.SharesInvalid
+	result.SharesStale = p.SharesStale
+	result.SharesDuplicate = p.SharesDuplicate
+
+	miners := map[string]bool{}
+	work := uint64(0)
+	for _, s := range p.Window {
+		miners[s.Address] = true
+		if s.Time > now.Unix()-POOL_HASHRATE_WINDOW {
+			work += s.Difficulty
+		}
+	}
+	result.Miners = len(miners)
+	result.Hashrate = work / uint64(POOL_HASHRATE_WINDOW)
+
+	for _, f := range p.Found {
+		if f.Block {
+			result.Blocks++
+		} else {
+			result.MiniBlocks++
+		}
+	}
+	if len(p.Found) > 20 { // only recent ones
+		result.Found = append(result.Found, p.Found[len(p.Found)-20:]...)
+	} else {
+		result.Found = append(result.Found, p.Found...)
+	}
+	return
+}
+
+func (p *pool_state) ledger(address string) (result rpc.GetPoolLedger_Result) {
+	p.Lock()
+	defer p.Unlock()
+	for addr, acc := range p.Accounts {
+		if address != "" && address != addr {
+			continue
+		}
+		result.Entries = append(result.Entries, rpc.Pool_Ledger_Entry{Address: addr, Shares: acc.Shares, LastShare: acc.LastShare, Credited: acc.Credited, Paid: acc.Paid, Balance: acc.Credited - acc.Paid})
+	}
+	sort.Slice(result.Entries, func(i, j int) bool { return result.Entries[i].Address < result.Entries[j].Address })
+	for _, po := range p.Payouts {
+		if address == "" || address == po.Address {
+			result.Payouts = append(result.Payouts, po)
+		}
+	}
+	return
+}
+
+func (p *pool_state) load(filename string) error {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	if err = json.Unmarshal(data, p); err != nil {
+		return err
+	}
+	if p.Accounts == nil {
+		p.Accounts = map[string]*pool_account{}
+	}
+	if len(p.Window) > p.window {
+		p.Window = append(p.Window[:0], p.Window[len(p.Window)-p.window:]...)
+	}
+	return nil
+}
+
+// saves state if anything has changed, file is replaced atomically
+func (p *pool_state) save(filename string) error {
+	p.Lock()
+	defer p.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filename+".tmp", data, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(filename+".tmp", filename); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+func pool_filename() string {
+	return filepath.Join(globals.GetDataDirectory(), pool_file)
+}
+
+// enables pool mode if requested by user
+func pool_setup() {
+	if globals.Arguments["--pool-mode"] == nil || !globals.Arguments["--pool-mode"].(bool) {
+		return
+	}
+
+	var share_diff uint64
+	var window int
+	if globals.Arguments["--pool-share-difficulty"] != nil {
+		if _, err := fmt.Sscan(globals.Arguments["--pool-share-difficulty"].(string), &share_diff); err != nil {
+			logger_getwork.Error(err, "--pool-share-difficulty is invalid")
+			return
+		}
+	}
+	if globals.Arguments["--pool-pplns-window"] != nil {
+		if _, err := fmt.Sscan(globals.Arguments["--pool-pplns-window"].(string), &window); err != nil || window <= 0 {
+			logger_getwork.Error(err, "--pool-pplns-window is invalid")
+			return
+		}
+	}
+
+	p := new_pool(share_diff, window)
+	if err := p.load(pool_filename()); err != nil {
+		logger_getwork.Error(err, "could not load pool state, pool mode is disabled", "file", pool_filename())
+		return
+	}
+	pool = p
+
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			if err := pool.save(pool_filename()); err != nil {
+				logger_getwork.Error(err, "could not save pool state", "file", pool_filename())
+			}
+		}
+	}()
+
+	logger_getwork.Info("Pool mode enabled", "integrator_address", chain.IntegratorAddress().String(), "share_difficulty", share_diff, "pplns_window", p.window)
+}
+
+// process a share submitted by a miner in pool mode
+// share pow is checked without holding client_list_mutex, it is only taken to update session counters
+func pool_submit(sess *user_session, data []byte) {
+	var p rpc.SubmitBlock_Params
+	if err := json.Unmarshal(data, &p); err != nil {
+
+	}
+
+	mbl_bytes, err := hex.DecodeString(p.MiniBlockhashing_blob)
+	if err != nil {
+		client_list_mutex.Lock()
+		sess.lasterr = fmt.Sprintf("Submitted block could not be decoded. err: %s", err)
+		client_list_mutex.Unlock()
+		return
+	}
+
+	var tstamp, extra uint64
+	fmt.Sscanf(p.JobID, "%d.%d", &tstamp, &extra)
+
+	network, err := pool.submit(sess.address.String(), tstamp, mbl_bytes, time.Now())
+	if err != nil {
+		client_list_mutex.Lock()
+		sess.rejected++
+		sess.lasterr = err.Error()
+		client_list_mutex.Unlock()
+		return
+	}
+	client_list_mutex.Lock()
+	sess.shares++
+	client_list_mutex.Unlock()
+	if !network {
+		return
+	}
+
+	var mbl block.MiniBlock
+	mbl.Deserialize(mbl_bytes)
+
+	_, blid, sresult, err := chain.Accept_new_block(tstamp, mbl_bytes)
+	if !sresult || err != nil {
+		client_list_mutex.Lock()
+		sess.rejected++
+		if err != nil {
+			sess.lasterr = err.Error()
+		}
+		client_list_mutex.Unlock()
+		atomic.AddInt64(&CountMinisRejected, 1)
+		metrics.Set.GetOrCreateCounter("getwork_miniblocks_rejected_total").Inc()
+		return
+	}
+
+	client_list_mutex.Lock()
+	if blid.IsZero() {
+		sess.miniblocks++
+	} else {
+		sess.blocks++
+	}
+	client_list_mutex.Unlock()
+
+	if blid.IsZero() {
+		atomic.AddInt64(&CountMinisAccepted, 1)
+		metrics.Set.GetOrCreateCounter("getwork_miniblocks_accepted_total").Inc()
+		rate_lock.Lock()
+		mini_found_time = append(mini_found_time, time.Now().Unix())
+		rate_lock.Unlock()
+	} else {
+		atomic.AddInt64(&CountBlocks, 1)
+		metrics.Set.GetOrCreateCounter("getwork_blocks_total").Inc()
+	}
+
+	pool.credit(mbl.Height, sess.address.String(), !blid.IsZero(), pool_reward(mbl.Height), time.Now())
+	if err := pool.save(pool_filename()); err != nil {
+		logger_getwork.Error(err, "could not save pool state", "file", pool_filename())
+	}
+}
+
+// PoolRecordPayout records a payout made by operator to a miner
+func PoolRecordPayout(address string, amount uint64, txid string) error {
+	if pool == nil {
+		return fmt.Errorf("pool mode is not enabled")
+	}
+	if err := pool.payout(address, amount, txid, time.Now()); err != nil {
+		return err
+	}
+	return pool.save(pool_filename())
+}
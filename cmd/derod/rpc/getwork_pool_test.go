@@ -0,0 +1,107 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "time"
+import "testing"
+import "math/big"
+import "path/filepath"
+
+import "github.com/deroproject/derohe/block"
+
+func Test_Pool_Share_Difficulty(t *testing.T) {
+	p := new_pool(0, 0)
+	if d := p.share_difficulty(big.NewInt(1000)); d.Uint64() != 10 {
+		t.Fatalf("default share difficulty expected 10 actual %s", d)
+	}
+	if d := p.share_difficulty(big.NewInt(50)); d.Uint64() != 1 {
+		t.Fatalf("share difficulty must be atleast 1, actual %s", d)
+	}
+	p.share_diff = 5000
+	if d := p.share_difficulty(big.NewInt(1000)); d.Uint64() != 1000 {
+		t.Fatalf("share difficulty cannot exceed network, actual %s", d)
+	}
+}
+
+func Test_Pool_Submit(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := new_pool(1, 3)
+	mbl := block.MiniBlock{Version: 1, PastCount: 1, Height: 1000, Timestamp: 1234}
+	p.add_job(77, mbl.Height, big.NewInt(1), mbl)
+
+	share := mbl
+	share.Nonce[0] = 1
+	if _, err := p.submit("miner1", 78, share.Serialize(), now); err == nil || p.SharesStale != 1 {
+		t.Fatalf("share for unknown job must be stale")
+	}
+
+	tampered := share
+	tampered.Height++
+	if _, err := p.submit("miner1", 77, tampered.Serialize(), now); err == nil || p.SharesInvalid != 1 {
+		t.Fatalf("share not matching job must be rejected")
+	}
+
+	if network, err := p.submit("miner1", 77, share.Serialize(), now); err != nil || !network {
+		t.Fatalf("valid share rejected network %t err %s", network, err)
+	}
+	if _, err := p.submit("miner1", 77, share.Serialize(), now); err == nil || p.SharesDuplicate != 1 {
+		t.Fatalf("duplicate share must be rejected")
+	}
+
+	for i := uint32(2); i < 6; i++ { // window only keeps 3 shares
+		share.Nonce[0] = i
+		if _, err := p.submit("miner2", 77, share.Serialize(), now); err != nil {
+			t.Fatalf("valid share rejected err %s", err)
+		}
+	}
+	if len(p.Window) != 3 || p.SharesValid != 5 || p.Accounts["miner1"].Shares != 1 || p.Accounts["miner2"].Shares != 4 {
+		t.Fatalf("unexpected share accounting window %d valid %d", len(p.Window), p.SharesValid)
+	}
+}
+
+func Test_Pool_Credit_Payout(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := new_pool(0, 10)
+	p.Window = []pool_share{{"a", 100, 1}, {"b", 100, 1}, {"b", 100, 1}}
+
+	p.credit(10, "a", false, 1000, now)
+	if p.Accounts["a"].Credited != 334 || p.Accounts["b"].Credited != 666 { // leftover goes to finder
+		t.Fatalf("unexpected credit a %d b %d", p.Accounts["a"].Credited, p.Accounts["b"].Credited)
+	}
+
+	if err := p.payout("b", 700, "", now); err == nil {
+		t.Fatalf("payout more than balance must fail")
+	}
+	if err := p.payout("b", 600, "txid", now); err != nil {
+		t.Fatalf("payout failed err %s", err)
+	}
+	if l := p.ledger("b"); len(l.Entries) != 1 || l.Entries[0].Balance != 66 || len(l.Payouts) != 1 {
+		t.Fatalf("unexpected ledger %+v", l)
+	}
+
+	filename := filepath.Join(t.TempDir(), pool_file)
+	if err := p.save(filename); err != nil {
+		t.Fatalf("save failed err %s", err)
+	}
+	restored := new_pool(0, 10)
+	if err := restored.load(filename); err != nil {
+		t.Fatalf("load failed err %s", err)
+	}
+	if restored.Accounts["a"].Credited != 334 || restored.Accounts["b"].Paid != 600 || len(restored.Window) != 3 || len(restored.Found) != 1 {
+		t.Fatalf("state not restored")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "fmt"
+import "bytes"
+import "context"
+import "runtime/debug"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/proof"
+import "github.com/deroproject/derohe/globals"
+
+// verifies a self-contained proof against the on-chain tx, its ring and optionally the receiver
+func CheckTxProof(ctx context.Context, p rpc.CheckTxProof_Params) (result rpc.CheckTxProof_Result, err error) {
+	defer func() { // safety so if anything wrong happens, we return error
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic occured. stack trace %s", debug.Stack())
+		}
+	}()
+
+	txid, err := proof.TXIDFromProof(p.Proof)
+	if err != nil {
+		return
+	}
+
+	// reuse GetTransaction so as ring is expanded exactly as for wallets
+	tx_result, err := GetTransaction(ctx, rpc.GetTransaction_Params{Tx_Hashes: []string{txid.String()}})
+	if err != nil {
+		return
+	}
+	if len(tx_result.Txs_as_hex) != 1 || tx_result.Txs_as_hex[0] == "" {
+		err = fmt.Errorf("tx %s not found", txid)
+		return
+	}
+	related := tx_result.Txs[0]
+	if related.In_pool {
+		err = fmt.Errorf("tx %s is not mined yet", txid)
+		return
+	}
+	if related.ValidBlock == "" || len(related.Ring) == 0 {
+		err = fmt.Errorf("tx %s is not valid in any block", txid)
+		return
+	}
+
+	receivers, amounts, _, payloads, err := proof.Prove(p.Proof, tx_result.Txs_as_hex[0], related.Ring, globals.IsMainnet())
+	if err != nil {
+		return
+	}
+
+	if p.Receiver != "" {
+		var expected, actual *rpc.Address
+		if expected, err = rpc.NewAddress(p.Receiver); err != nil {
+			return
+		}
+		found := false
+		for i := range receivers {
+			if actual, err = rpc.NewAddress(receivers[i]); err != nil {
+				return
+			}
+			if bytes.Equal(expected.Compressed(), actual.Compressed()) {
+				found = true
+			}
+		}
+		if !found {
+			err = fmt.Errorf("proof is not for receiver %s", p.Receiver)
+			return
+		}
+	}
+
+	result.TXID = txid.String()
+	result.ValidBlock = related.ValidBlock
+	result.TopoHeight = related.Block_Height
+	result.Receivers = receivers
+	result.Amounts = amounts
+	result.Payloads = payloads
+	result.Status = "OK"
+	return
+}
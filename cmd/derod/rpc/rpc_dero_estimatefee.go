@@ -0,0 +1,155 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "fmt"
+import "sort"
+import "context"
+import "runtime/debug"
+
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/transaction"
+
+const fee_estimate_recent_blocks = 10 // these many recent blocks are considered
+const fee_estimate_busy_fill = 0.5    // above this average fill, recent fees matter
+const fee_estimate_max_target = 1000  // confirmation targets are limited to these many blocks
+var fee_estimate_default_targets = []uint64{1, 3, 10}
+
+// fee paid per KB by a tx
+type fee_sample struct {
+	FeePerKB uint64
+	Size     uint64
+}
+
+// size is rounded up to KB, same as Calculate_TX_fee
+func fee_per_kb(fees uint64, size uint64) uint64 {
+	kb := (size + 1023) / 1024
+	if kb == 0 {
+		kb = 1
+	}
+	return fees / kb
+}
+
+// suggest fee per KB for confirmation targets using mempool and recent blocks
+func EstimateFee(ctx context.Context, p rpc.EstimateFee_Params) (result rpc.EstimateFee_Result, err error) {
+	defer func() { // safety so if anything wrong happens, we return error
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic occured. stack trace %s", debug.Stack())
+		}
+	}()
+
+	targets := p.Blocks
+	if len(targets) == 0 {
+		targets = fee_estimate_default_targets
+	}
+	for _, n := range targets {
+		if n < 1 || n > fee_estimate_max_target {
+			return result, fmt.Errorf("confirmation target must be between 1 and %d blocks", fee_estimate_max_target)
+		}
+	}
+
+	capacity := config.STARGATE_HE_MAX_BLOCK_SIZE - 102400 // same limit is used while creating blocks
+
+	var pool []fee_sample
+	for _, entry := range chain.Mempool.Mempool_List_TX_SortedInfo() {
+		if tx := chain.Mempool.Mempool_Get_TX(entry.Hash); tx != nil {
+			pool = append(pool, fee_sample{FeePerKB: fee_per_kb(tx.Fees(), entry.Size), Size: entry.Size})
+			result.MempoolTxs++
+			result.MempoolBytes += entry.Size
+		}
+	}
+
+	recent, fill, blocks := recent_block_fees(fee_estimate_recent_blocks, capacity)
+
+	result.Estimates = estimate_fees(pool, recent, fill, capacity, targets)
+	result.MinFeePerKB = config.FEE_PER_KB
+	result.BlockFill = fill
+	result.RecentBlocks = blocks
+	result.Status = "OK"
+	return
+}
+
+// fees of txs in recent blocks and average block fill
+func recent_block_fees(count int64, capacity uint64) (samples []fee_sample, fill float64, blocks int) {
+	top := chain.Load_TOPO_HEIGHT()
+	for topo := top; topo > top-count && topo >= 0; topo-- {
+		hash, err := chain.Load_Block_Topological_order_at_index(topo)
+		if err != nil {
+			continue
+		}
+		bl, err := chain.Load_BL_FROM_ID(hash)
+		if err != nil {
+			continue
+		}
+
+		size := uint64(0)
+		for _, txhash := range bl.Tx_hashes {
+			tx_bytes, err := chain.Store.Block_tx_store.ReadTX(txhash)
+			if err != nil {
+				continue
+			}
+			var tx transaction.Transaction
+			if err = tx.Deserialize(tx_bytes); err != nil {
+				continue
+			}
+			size += uint64(len(tx_bytes))
+			if !tx.IsRegistration() {
+				samples = append(samples, fee_sample{FeePerKB: fee_per_kb(tx.Fees(), uint64(len(tx_bytes))), Size: uint64(len(tx_bytes))})
+			}
+		}
+		fill += float64(size) / float64(capacity)
+		blocks++
+	}
+	if blocks > 0 {
+		fill = fill / float64(blocks)
+	}
+	return
+}
+
+// higher paying txs are mined first, so a tx must outbid whatever does not fit within the target
+// when recent blocks were busy, it must also pay what recently got mined, less so for longer targets
+func estimate_fees(pool []fee_sample, recent []fee_sample, fill float64, capacity uint64, targets []uint64) (estimates []rpc.Fee_Estimate) {
+	pool = append([]fee_sample{}, pool...)
+	recent = append([]fee_sample{}, recent...)
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].FeePerKB > pool[j].FeePerKB })
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].FeePerKB < recent[j].FeePerKB })
+
+	for _, n := range targets {
+		fee := config.FEE_PER_KB
+
+		used := uint64(0)
+		for _, s := range pool {
+			used += s.Size
+			if used > n*capacity {
+				if s.FeePerKB+1 > fee {
+					fee = s.FeePerKB + 1
+				}
+				break
+			}
+		}
+
+		if fill >= fee_estimate_busy_fill && len(recent) > 0 {
+			index := (len(recent) - 1) * 50 / int(100*n) // median for next block, lower percentiles for later
+			if recent[index].FeePerKB > fee {
+				fee = recent[index].FeePerKB
+			}
+		}
+		estimates = append(estimates, rpc.Fee_Estimate{Blocks: n, FeePerKB: fee})
+	}
+	return
+}
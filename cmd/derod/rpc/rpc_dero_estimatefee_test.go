@@ -0,0 +1,59 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "testing"
+
+import "github.com/deroproject/derohe/config"
+
+func Test_Estimate_Fees(t *testing.T) {
+	targets := []uint64{1, 3, 10}
+
+	// idle network, minimum fee everywhere
+	for _, e := range estimate_fees(nil, nil, 0, 1000, targets) {
+		if e.FeePerKB != config.FEE_PER_KB {
+			t.Fatalf("idle network must suggest minimum fee, got %+v", e)
+		}
+	}
+
+	// mempool holds 5 blocks worth of txs, 1 block each at fees 500,400,300,200,100
+	var pool []fee_sample
+	for _, fee := range []uint64{100, 300, 500, 200, 400} {
+		pool = append(pool, fee_sample{FeePerKB: fee, Size: 1000})
+	}
+	estimates := estimate_fees(pool, nil, 0, 1000, targets)
+	if estimates[0].FeePerKB != 401 || estimates[1].FeePerKB != 201 || estimates[2].FeePerKB != config.FEE_PER_KB {
+		t.Fatalf("mempool backlog estimates wrong %+v", estimates)
+	}
+
+	// busy recent blocks raise the floor, less so for longer targets
+	var recent []fee_sample
+	for fee := uint64(1); fee <= 1000; fee++ {
+		recent = append(recent, fee_sample{FeePerKB: fee, Size: 1000})
+	}
+	estimates = estimate_fees(nil, recent, 0.9, 1000, targets)
+	if estimates[0].FeePerKB != 500 || estimates[1].FeePerKB != 167 || estimates[2].FeePerKB != 50 {
+		t.Fatalf("busy network estimates wrong %+v", estimates)
+	}
+	if estimates = estimate_fees(nil, recent, 0.1, 1000, targets); estimates[0].FeePerKB != config.FEE_PER_KB {
+		t.Fatalf("recent fees must be ignored when blocks are not busy %+v", estimates)
+	}
+
+	if fee_per_kb(100, 1025) != 50 || fee_per_kb(100, 0) != 100 {
+		t.Fatalf("fee per kb must round size up to KB")
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "fmt"
+import "context"
+import "encoding/hex"
+import "runtime/debug"
+
+import "golang.org/x/xerrors"
+import "github.com/deroproject/graviton"
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/errormsg"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/rpc"
+
+// how far ahead in topo order we look for a block committing to a state
+const commitment_search_depth = 8
+
+// returns encrypted balance with a graviton proof against the balance tree root
+// the roots are committed in Proof field of the child block, which is returned so that
+// light clients can verify its PoW and chain it to headers they already trust
+func GetEncryptedBalanceProof(ctx context.Context, p rpc.GetEncryptedBalanceProof_Params) (result rpc.GetEncryptedBalanceProof_Result, err error) {
+	defer func() { // safety so if anything wrong happens, we return error
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic occured. stack trace %s", debug.Stack())
+		}
+	}()
+
+	uaddress, err := globals.ParseValidateAddress(p.Address)
+	if err != nil {
+		panic(err)
+	}
+
+	// sc trees are not part of the committed state root, so they cannot be proved
+	if !p.SCID.IsZero() {
+		return result, fmt.Errorf("balance proofs are only available for base DERO balance")
+	}
+
+	top := chain.Load_TOPO_HEIGHT()
+
+	topoheight, commit_bl, err := locate_state_commitment(p.TopoHeight, top)
+	if err != nil {
+		return
+	}
+
+	toporecord, err := chain.Store.Topo_store.Read(topoheight)
+	if err != nil {
+		panic(err)
+	}
+
+	ss, err := chain.Store.Balance_store.LoadSnapshot(toporecord.State_Version)
+	if err != nil {
+		panic(err)
+	}
+
+	balance_tree, err := ss.GetTree(config.BALANCE_TREE)
+	if err != nil {
+		panic(err)
+	}
+	meta_tree, err := ss.GetTree(config.SC_META)
+	if err != nil {
+		panic(err)
+	}
+
+	balance_hash, err := balance_tree.Hash()
+	if err != nil {
+		panic(err)
+	}
+	meta_hash, err := meta_tree.Hash()
+	if err != nil {
+		panic(err)
+	}
+
+	keyname := uaddress.Compressed()
+	proof, err := balance_tree.GenerateProof(keyname)
+	if err != nil {
+		panic(err)
+	}
+
+	var merkle_hash [32]byte
+	for i := range merkle_hash {
+		merkle_hash[i] = balance_hash[i] ^ meta_hash[i]
+	}
+
+	status := "OK"
+	bits, _, balance_serialized, err := balance_tree.GetKeyValueFromKey(keyname)
+	if err != nil {
+		if xerrors.Is(err, graviton.ErrNotFound) { // proof is a non-membership proof
+			status = errormsg.ErrAccountUnregistered.Error()
+			err = nil
+		} else {
+			panic(err)
+		}
+	}
+
+	return rpc.GetEncryptedBalanceProof_Result{ // return success
+		Balance: rpc.GetEncryptedBalance_Result{
+			SCID:                     p.SCID,
+			Data:                     fmt.Sprintf("%x", balance_serialized),
+			Registration:             LocatePointOfRegistration(uaddress),
+			Bits:                     bits,
+			Height:                   toporecord.Height,
+			Topoheight:               topoheight,
+			BlockHash:                toporecord.BLOCK_ID,
+			Merkle_Balance_TreeHash:  fmt.Sprintf("%x", merkle_hash[:]),
+			DHeight:                  chain.Get_Height(),
+			DTopoheight:              top,
+			DMerkle_Balance_TreeHash: fmt.Sprintf("%x", merkle_hash[:]),
+			Status:                   status,
+		},
+		Proof:           hex.EncodeToString(proof.Marshal()),
+		BalanceTreeHash: fmt.Sprintf("%x", balance_hash[:]),
+		MetaTreeHash:    fmt.Sprintf("%x", meta_hash[:]),
+		Block:           hex.EncodeToString(commit_bl.Serialize()),
+		Status:          "OK",
+	}, nil
+}
+
+// find a topoheight whose state is committed by a later block, along with the committing block
+// if requested topoheight is out of range, latest committed state is used
+func locate_state_commitment(requested int64, top int64) (topoheight int64, commit_bl *block.Block, err error) {
+	if requested >= 0 && requested <= top {
+		if commit_bl = find_state_commitment(requested, top); commit_bl == nil {
+			err = fmt.Errorf("state at topoheight %d is not committed by any block yet", requested)
+		}
+		return requested, commit_bl, err
+	}
+
+	for topoheight = top; topoheight >= 0 && topoheight > top-commitment_search_depth; topoheight-- {
+		if commit_bl = find_state_commitment(topoheight, top); commit_bl != nil {
+			return
+		}
+	}
+	return -1, nil, fmt.Errorf("no committed state found near topoheight %d", top)
+}
+
+func find_state_commitment(topoheight int64, top int64) *block.Block {
+	blid, err := chain.Load_Block_Topological_order_at_index(topoheight)
+	if err != nil {
+		return nil
+	}
+	for t := topoheight + 1; t <= top && t <= topoheight+commitment_search_depth; t++ {
+		hash, err := chain.Load_Block_Topological_order_at_index(t)
+		if err != nil {
+			return nil
+		}
+		bl, err := chain.Load_BL_FROM_ID(hash)
+		if err != nil {
+			return nil
+		}
+		if len(bl.Tips) == 1 && bl.Tips[0] == blid && bl.Proof != [32]byte{} {
+			return bl
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import "fmt"
+import "context"
+import "runtime/debug"
+
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/rpc"
+
+func supply_info(r blockchain.Supply_Record) rpc.Supply_Info {
+	return rpc.Supply_Info{
+		Premine:      r.Premine,
+		Registration: r.Registration,
+		Mined:        r.Mined,
+		Fees:         r.Fees,
+		Integrator:   r.Integrator,
+		MiniBlocks:   r.MiniBlocks,
+		Burned:       r.Burned,
+		SCDeposits:   r.SCDeposits,
+		SCHeld:       r.SCHeld,
+		Emitted:      r.Emitted(),
+		Circulating:  r.Circulating(),
+	}
+}
+
+// report emission and sinks at a topoheight
+// full scan from genesis is expensive and only available from daemon console ( supply [topoheight] scan)
+// records are built from genesis, so pruned nodes return an error
+func GetSupply(ctx context.Context, p rpc.GetSupply_Params) (result rpc.GetSupply_Result, err error) {
+	defer func() { // safety so if anything wrong happens, we return error
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic occured. stack trace %s", debug.Stack())
+		}
+	}()
+
+	topoheight := chain.Load_TOPO_HEIGHT()
+	if p.TopoHeight >= 1 {
+		topoheight = p.TopoHeight
+	}
+	if topoheight > chain.Load_TOPO_HEIGHT() {
+		return result, fmt.Errorf("user requested topoheight greater than current topoheight")
+	}
+
+	blid, err := chain.Load_Block_Topological_order_at_index(topoheight)
+	if err != nil {
+		return
+	}
+
+	record, err := chain.Load_Supply(blid)
+	if err != nil {
+		return
+	}
+
+	result.TopoHeight = topoheight
+	result.BLID = blid.String()
+	result.Supply = supply_info(record)
+
+	result.Status = "OK"
+	return
+}
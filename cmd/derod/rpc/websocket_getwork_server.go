@@ -34,6 +34,7 @@ import "encoding/pem"
 import "github.com/deroproject/derohe/globals"
 import "github.com/deroproject/derohe/config"
 import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/metrics"
 import "github.com/deroproject/graviton"
 import "github.com/go-logr/logr"
 
@@ -58,6 +59,7 @@ type user_session struct {
 	blocks        uint64
 	miniblocks    uint64
 	rejected      uint64
+	shares        uint64 // valid shares, only in pool mode
 	lasterr       string
 	address       rpc.Address
 	valid_address bool
@@ -158,6 +160,12 @@ func SendJob() {
 	if mbl_main.HighDiff {
 		diff.Mul(diff, new(big.Int).SetUint64(config.MINIBLOCK_HIGHDIFF))
 	}
+
+	job_diff := diff
+	if pool != nil { // in pool mode, miners work for integrator at share difficulty
+		pool.add_job(bl.Timestamp, bl.Height, diff, mbl_main)
+		job_diff = pool.share_difficulty(diff)
+	}
 	client_list_mutex.Lock()
 	defer client_list_mutex.Unlock()
 
@@ -172,12 +180,12 @@ func SendJob() {
 			params.JobID = fmt.Sprintf("%d.%d.%s", bl.Timestamp, 0, "notified")
 			params.Height = bl.Height
 			params.Prev_Hash = prev_hash
-			params.Difficultyuint64 = diff.Uint64()
-			params.Difficulty = diff.String()
+			params.Difficultyuint64 = job_diff.Uint64()
+			params.Difficulty = job_diff.String()
 
 			mbl := mbl_main
 
-			if !mbl.Final { //write miners address only if possible
+			if !mbl.Final && pool == nil { //write miners address only if possible
 				copy(mbl.KeyHash[:], v.address_sum[:])
 			}
 
@@ -185,7 +193,9 @@ func SendJob() {
 				mbl.Nonce[i] = globals.Global_Random.Uint32() // fill with randomness
 			}
 
-			if !v.valid_address && !chain.IsAddressHashValid(false, v.address_sum) {
+			if pool != nil {
+				params.LastError = v.lasterr
+			} else if !v.valid_address && !chain.IsAddressHashValid(false, v.address_sum) {
 				params.LastError = "unregistered miner or you need to wait 15 mins"
 			} else {
 				v.valid_address = true
@@ -194,6 +204,7 @@ func SendJob() {
 			params.Blocks = v.blocks
 			params.MiniBlocks = v.miniblocks
 			params.Rejected = v.rejected
+			params.Shares = v.shares
 
 			encoder.Encode(params)
 			k.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
@@ -219,6 +230,11 @@ func newUpgrader() *websocket.Upgrader {
 
 		sess := c.Session().(*user_session)
 
+		if pool != nil { // shares are verified without holding client_list_mutex
+			pool_submit(sess, data)
+			return
+		}
+
 		client_list_mutex.Lock()
 		defer client_list_mutex.Unlock()
 
@@ -245,6 +261,7 @@ func newUpgrader() *websocket.Upgrader {
 			if blid.IsZero() {
 				sess.miniblocks++
 				atomic.AddInt64(&CountMinisAccepted, 1)
+				metrics.Set.GetOrCreateCounter("getwork_miniblocks_accepted_total").Inc()
 
 				rate_lock.Lock()
 				defer rate_lock.Unlock()
@@ -252,12 +269,14 @@ func newUpgrader() *websocket.Upgrader {
 			} else {
 				sess.blocks++
 				atomic.AddInt64(&CountBlocks, 1)
+				metrics.Set.GetOrCreateCounter("getwork_blocks_total").Inc()
 			}
 		}
 
 		if !sresult || err != nil {
 			sess.rejected++
 			atomic.AddInt64(&CountMinisRejected, 1)
+			metrics.Set.GetOrCreateCounter("getwork_miniblocks_rejected_total").Inc()
 		}
 
 	})
@@ -308,6 +327,17 @@ func Getwork_server() {
 
 	logger_getwork = globals.Logger.WithName("GETWORK")
 
+	metrics.Set.GetOrCreateGauge("getwork_miners_count", func() float64 {
+		client_list_mutex.Lock()
+		defer client_list_mutex.Unlock()
+		return float64(len(client_list))
+	})
+	metrics.Set.GetOrCreateCounter("getwork_miniblocks_accepted_total")
+	metrics.Set.GetOrCreateCounter("getwork_miniblocks_rejected_total")
+	metrics.Set.GetOrCreateCounter("getwork_blocks_total")
+
+	pool_setup()
+
 	logging.SetLevel(logging.LevelNone) //LevelDebug)//LevelNone)
 
 	tlsConfig := &tls.Config{
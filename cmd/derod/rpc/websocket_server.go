@@ -312,8 +312,14 @@ var historical_apis = handler.Map{"getinfo": handler.New(GetInfo),
 	"getlastblockheader":         handler.New(GetLastBlockHeader),
 	"getblocktemplate":           handler.New(GetBlockTemplate),
 	"getencryptedbalance":        handler.New(GetEncryptedBalance),
+	"getencryptedbalanceproof":   handler.New(GetEncryptedBalanceProof),
 	"getsc":                      handler.New(GetSC),
 	"getgasestimate":             handler.New(GetGasEstimate),
+	"estimatefee":                handler.New(EstimateFee),
+	"getsupply":                  handler.New(GetSupply),
+	"checktxproof":               handler.New(CheckTxProof),
+	"getpoolstats":               handler.New(GetPoolStats),
+	"getpoolledger":              handler.New(GetPoolLedger),
 	"nametoaddress":              handler.New(NameToAddress)}
 
 var servicemux = handler.ServiceMap{
@@ -334,8 +340,14 @@ var servicemux = handler.ServiceMap{
 		"GetLastBlockHeader":         handler.New(GetLastBlockHeader),
 		"GetBlockTemplate":           handler.New(GetBlockTemplate),
 		"GetEncryptedBalance":        handler.New(GetEncryptedBalance),
+		"GetEncryptedBalanceProof":   handler.New(GetEncryptedBalanceProof),
 		"GetSC":                      handler.New(GetSC),
 		"GetGasEstimate":             handler.New(GetGasEstimate),
+		"EstimateFee":                handler.New(EstimateFee),
+		"GetSupply":                  handler.New(GetSupply),
+		"CheckTxProof":               handler.New(CheckTxProof),
+		"GetPoolStats":               handler.New(GetPoolStats),
+		"GetPoolLedger":              handler.New(GetPoolLedger),
 		"NameToAddress":              handler.New(NameToAddress),
 	},
 	"DAEMON": handler.Map{
@@ -343,6 +355,12 @@ var servicemux = handler.ServiceMap{
 	},
 }
 
+// adds an extra service, eg. simulator exposes SIMULATOR.* apis this way
+// must be called before RPCServer_Start
+func RegisterService(name string, handlers handler.Map) {
+	servicemux[name] = handlers
+}
+
 type dummyassigner int
 
 var d dummyassigner
@@ -28,6 +28,7 @@ import "time"
 import "fmt"
 import "os"
 import "runtime"
+import "strconv"
 
 import "github.com/docopt/docopt-go"
 import "github.com/go-logr/logr"
@@ -39,7 +40,7 @@ var command_line string = `dero_explorer
 DERO HE Explorer: A secure, private blockchain with smart-contracts
 
 Usage:
-  dero_explorer [--help] [--version] [--debug] [--daemon-address=<127.0.0.1:18091>] [--http-address=<0.0.0.0:8080>] 
+  dero_explorer [--help] [--version] [--debug] [--daemon-address=<127.0.0.1:18091>] [--http-address=<0.0.0.0:8080>] [--sc-history-from=<topoheight>]
   dero_explorer -h | --help
   dero_explorer --version
 
@@ -48,7 +49,8 @@ Options:
   --version     Show version.
   --debug       Debug mode enabled, print log messages
   --daemon-address=<127.0.0.1:10102>  connect to this daemon port as client
-  --http-address=<0.0.0.0:8080>    explorer listens on this port to serve user requests`
+  --http-address=<0.0.0.0:8080>    explorer listens on this port to serve user requests
+  --sc-history-from=<topoheight>    index SC and asset history from this topoheight, default is from genesis`
 
 var logger logr.Logger
 
@@ -88,6 +90,13 @@ func main() {
 		listen_address = globals.Arguments["--http-address"].(string)
 	}
 
+	if globals.Arguments["--sc-history-from"] != nil {
+		if explorerlib.SC_Index_Start, err = strconv.ParseInt(globals.Arguments["--sc-history-from"].(string), 10, 64); err != nil {
+			logger.Error(err, "invalid --sc-history-from")
+			return
+		}
+	}
+
 	if err = explorerlib.StartServer(logger, endpoint, listen_address); err == nil {
 		for {
 			time.Sleep(time.Second)
@@ -0,0 +1,220 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+// this file implements JSON api, it returns the same data which is used to render the html pages
+// stable blocks and txs never change, so they are served with long lived cache headers
+// fields which keep changing such as depth, age and current sc state are left out of such responses
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/deroproject/derohe/config"
+	"github.com/deroproject/derohe/rpc"
+)
+
+const api_max_blocks = 100 // maximum blocks returned by a single blocks request
+
+const (
+	cache_immutable = "public, max-age=31536000, immutable" // stable data
+	cache_short     = "public, max-age=5"                   // data which changes with every block
+)
+
+type api_error struct {
+	Error string `json:"error"`
+}
+
+type api_blocks struct {
+	From   int64        `json:"from"`
+	Count  int          `json:"count"`
+	Next   int64        `json:"next"` // use as from to get next page, -1 if there are no more blocks
+	Blocks []block_info `json:"blocks"`
+}
+
+type api_txpool struct {
+	Count int      `json:"count"`
+	Txs   []txinfo `json:"txs"`
+}
+
+func api_register() {
+	http.HandleFunc("/api/v1/block/", api_block_handler)
+	http.HandleFunc("/api/v1/tx/", api_tx_handler)
+	http.HandleFunc("/api/v1/txpool", api_txpool_handler)
+	http.HandleFunc("/api/v1/blocks", api_blocks_handler)
+	http.HandleFunc("/api/v1/stats", api_stats_handler)
+}
+
+// writes v as json, etag is only used for cacheable responses
+func api_write(w http.ResponseWriter, r *http.Request, status int, cache string, etag string, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if status == http.StatusOK {
+		w.Header().Set("Cache-Control", cache)
+		if etag != "" {
+			etag = `"` + etag + `"`
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func api_write_error(w http.ResponseWriter, r *http.Request, status int, err error) {
+	api_write(w, r, status, "", "", api_error{Error: err.Error()})
+}
+
+// block is identified by topoheight or hash
+func api_block_handler(w http.ResponseWriter, r *http.Request) {
+	param := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v1/block/")
+	if param == "" {
+		api_write_error(w, r, http.StatusBadRequest, fmt.Errorf("block hash or topoheight required"))
+		return
+	}
+	if len(param) != 64 {
+		if _, err := strconv.ParseUint(param, 10, 64); err != nil {
+			api_write_error(w, r, http.StatusBadRequest, fmt.Errorf("invalid block id %q", param))
+			return
+		}
+	}
+
+	var blinfo block_info
+	if err := load_block_from_rpc(&blinfo, param, true); err != nil {
+		api_write_error(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	cache, etag := cache_short, ""
+	if blinfo.Depth >= config.STABLE_LIMIT {
+		cache = cache_immutable
+		if len(param) == 64 { // topoheight to block mapping can change till stable
+			etag = blinfo.Hash
+		}
+		api_stable_block(&blinfo)
+	}
+	api_write(w, r, http.StatusOK, cache, etag, blinfo)
+}
+
+func api_tx_handler(w http.ResponseWriter, r *http.Request) {
+	param := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v1/tx/")
+	if len(param) != 64 {
+		api_write_error(w, r, http.StatusBadRequest, fmt.Errorf("invalid txid %q", param))
+		return
+	}
+
+	var info txinfo
+	if err := load_tx_from_rpc(&info, param); err != nil {
+		api_write_error(w, r, http.StatusNotFound, err)
+		return
+	}
+	if info.Hash == "" { // daemon does not know this tx
+		api_write_error(w, r, http.StatusNotFound, fmt.Errorf("tx %s not found", param))
+		return
+	}
+
+	cache, etag := cache_short, ""
+	if !info.In_Pool && info.Depth >= config.STABLE_LIMIT {
+		cache, etag = cache_immutable, info.Hash
+		api_stable_tx(&info)
+	}
+	api_write(w, r, http.StatusOK, cache, etag, info)
+}
+
+func api_txpool_handler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{}
+	if err := fill_tx_pool_info(data, 500); err != nil {
+		api_write_error(w, r, http.StatusBadGateway, err)
+		return
+	}
+	txs := data["mempool"].([]txinfo)
+	api_write(w, r, http.StatusOK, cache_short, "", api_txpool{Count: len(txs), Txs: txs})
+}
+
+// blocks in descending topoheight order, ?from=<topoheight>&count=<n>
+// from defaults to chain top and count to 10
+func api_blocks_handler(w http.ResponseWriter, r *http.Request) {
+	var info rpc.GetInfo_Result
+	if err := rpc_client.Call("DERO.GetInfo", nil, &info); err != nil {
+		api_write_error(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	from, count := info.TopoHeight, 10
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+		if from, err = strconv.ParseInt(v, 10, 64); err != nil || from < 0 || from > info.TopoHeight {
+			api_write_error(w, r, http.StatusBadRequest, fmt.Errorf("invalid from %q", v))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("count"); v != "" {
+		var err error
+		if count, err = strconv.Atoi(v); err != nil || count < 1 || count > api_max_blocks {
+			api_write_error(w, r, http.StatusBadRequest, fmt.Errorf("count must be between 1 and %d", api_max_blocks))
+			return
+		}
+	}
+
+	result := api_blocks{From: from, Blocks: []block_info{}}
+	for topo := from; topo >= 0 && topo > from-int64(count); topo-- {
+		var blinfo block_info
+		if err := load_block_from_rpc(&blinfo, strconv.FormatInt(topo, 10), true); err != nil {
+			api_write_error(w, r, http.StatusBadGateway, err)
+			return
+		}
+		result.Blocks = append(result.Blocks, blinfo)
+	}
+	result.Count = len(result.Blocks)
+	result.Next = from - int64(count)
+	if result.Next < 0 {
+		result.Next = -1
+	}
+
+	cache := cache_short
+	if r.URL.Query().Get("from") != "" && from <= info.TopoHeight-config.STABLE_LIMIT { // whole page is stable
+		cache = cache_immutable
+		for i := range result.Blocks {
+			api_stable_block(&result.Blocks[i])
+		}
+	}
+	api_write(w, r, http.StatusOK, cache, "", result)
+}
+
+// clear fields which change even after block is stable, so that cached responses stay correct
+func api_stable_block(blinfo *block_info) {
+	blinfo.Depth, blinfo.Age = 0, ""
+	api_stable_tx(&blinfo.Mtx)
+	for i := range blinfo.Txs {
+		api_stable_tx(&blinfo.Txs[i])
+	}
+}
+
+// clear fields which change even after tx is stable, sc balance and state are current values
+func api_stable_tx(info *txinfo) {
+	info.Depth, info.Age = 0, ""
+	info.SC_Balance, info.SC_Balance_string, info.SC_Keys = 0, "", nil
+	info.SC_State = rpc.GetSC_Result{}
+}
@@ -0,0 +1,100 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/deroproject/derohe/config"
+)
+
+func Test_API(t *testing.T) {
+	s, _ := start_test_explorer(t)
+
+	txid := s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 1234)
+	err := s.MineBlocks(int(config.STABLE_LIMIT) + 1)
+	if err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+
+	resp, body := test_get(t, "/api/v1/tx/"+txid.String())
+	var tx txinfo
+	if err = json.Unmarshal([]byte(body), &tx); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("tx api failed status %d err %v body %s", resp.StatusCode, err, body)
+	}
+	if tx.Hash != txid.String() || tx.Ring_size != 2 || len(tx.Ring) != 1 || len(tx.Ring[0]) != 2 || tx.Fee == "" {
+		t.Fatalf("tx api returned incomplete data %+v", tx)
+	}
+	if !strings.Contains(resp.Header.Get("Cache-Control"), "immutable") || resp.Header.Get("ETag") == "" {
+		t.Fatalf("stable tx must be cacheable, headers %v", resp.Header)
+	}
+
+	if tx.Depth != 0 || tx.Age != "" {
+		t.Fatalf("cached tx must not carry changing fields %+v", tx)
+	}
+	if err = s.MineBlock(); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if _, body_next := test_get(t, "/api/v1/tx/"+txid.String()); body_next != body {
+		t.Fatalf("cached tx response changed with new block")
+	}
+
+	req, _ := http.NewRequest("GET", "http://"+test_address+"/api/v1/tx/"+txid.String(), nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	if resp, err = http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("etag must give not modified, err %v", err)
+	}
+	resp.Body.Close()
+
+	resp, body = test_get(t, "/api/v1/block/"+tx.ValidBlock)
+	var bl block_info
+	if err = json.Unmarshal([]byte(body), &bl); err != nil || bl.Hash != tx.ValidBlock || len(bl.Txs) != bl.Tx_Count || bl.Tx_Count < 1 {
+		t.Fatalf("block api failed err %v body %s", err, body)
+	}
+
+	if resp, _ = test_get(t, "/api/v1/block/1"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("block by topoheight failed status %d", resp.StatusCode)
+	}
+	if resp, _ = test_get(t, "/api/v1/block/notablock"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid block id must fail, status %d", resp.StatusCode)
+	}
+
+	top := s.Chain.Load_TOPO_HEIGHT()
+	resp, body = test_get(t, "/api/v1/blocks?count=3")
+	var blocks api_blocks
+	if err = json.Unmarshal([]byte(body), &blocks); err != nil || blocks.Count != 3 || blocks.From != top || blocks.Next != top-3 || blocks.Blocks[1].TopoHeight != top-1 {
+		t.Fatalf("blocks api failed err %v body %s", err, body)
+	}
+	if strings.Contains(resp.Header.Get("Cache-Control"), "immutable") {
+		t.Fatalf("chain top must not be cached for long")
+	}
+	if resp, _ = test_get(t, "/api/v1/blocks?from=1&count=2"); !strings.Contains(resp.Header.Get("Cache-Control"), "immutable") {
+		t.Fatalf("stable page must be cacheable")
+	}
+	if resp, _ = test_get(t, "/api/v1/blocks?count=1000"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("count must be limited")
+	}
+
+	resp, body = test_get(t, "/api/v1/txpool")
+	var pool api_txpool
+	if err = json.Unmarshal([]byte(body), &pool); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("txpool api failed err %v body %s", err, body)
+	}
+}
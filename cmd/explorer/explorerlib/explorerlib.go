@@ -32,6 +32,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -231,8 +232,15 @@ func StartServer(loggerb logr.Logger, daemon_endpoint string, listen_address str
 	http.HandleFunc("/block/", block_handler)
 	http.HandleFunc("/txpool/", txpool_handler)
 	http.HandleFunc("/tx/", tx_handler)
+	http.HandleFunc("/sc/", sc_handler)
+	http.HandleFunc("/asset/", asset_handler)
+	http.HandleFunc("/name", name_handler)
+	http.HandleFunc("/stats", stats_handler)
+	api_register() // json api under /api/v1/
 	http.HandleFunc("/", root_handler)
 
+	go sc_index_loop() // index SC invocations and asset transfers for history
+
 	go func() {
 		logger.Info("Listening for requests")
 		err = http.ListenAndServe(listen_address, nil)
@@ -303,6 +311,7 @@ type txinfo struct {
 	SC_Code            string            // install SC
 	SC_State           rpc.GetSC_Result  // current SC state
 	SC_Install         bool
+	SC_ID              string // SCID installed or invoked by this tx
 
 	Assets []Asset
 }
@@ -504,7 +513,9 @@ func load_tx_info_from_tx(info *txinfo, tx *transaction.Transaction) (err error)
 
 	if tx.TransactionType == transaction.SC_TX {
 		info.SC_Args = tx.SCDATA
-
+		if scid, _, ok := sc_invocation_from_tx(tx, info.Hash, ""); ok {
+			info.SC_ID = scid
+		}
 	}
 
 	// if outputs cannot be located, do not panic
@@ -868,6 +879,10 @@ func search_handler(w http.ResponseWriter, r *http.Request) {
 	if len(value) != 64 {
 		if s, err := strconv.ParseInt(value, 10, 64); err == nil && s >= 0 && s <= info.TopoHeight {
 			good = true
+		} else if err != nil && name_lookup(value) != "" { // registered name
+			logger.V(1).Info("Redirecting user to name page")
+			http.Redirect(w, r, "/name?name="+url.QueryEscape(value), 302)
+			return
 		}
 	} else { // check whether the string can be hex decoded
 		t, err := hex.DecodeString(value)
@@ -891,6 +906,13 @@ func search_handler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		var sc rpc.GetSC_Result
+		if err = rpc_client.Call("DERO.GetSC", rpc.GetSC_Params{SCID: value, Code: true}, &sc); err == nil && sc.Code != "" {
+			logger.V(1).Info("Redirecting user to sc page")
+			http.Redirect(w, r, "/sc/"+value, 302)
+			return
+		}
+
 		err = load_tx_from_rpc(&tx, value) //TODO handle error
 		if err == nil {
 			logger.V(1).Info("Redirecting user to tx page")
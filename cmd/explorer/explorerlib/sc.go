@@ -0,0 +1,238 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+// this file implements SC, asset and name pages
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/globals"
+	"github.com/deroproject/derohe/rpc"
+)
+
+// name service SC is always installed at this SCID
+const nameservice_scid = "0000000000000000000000000000000000000000000000000000000000000001"
+
+// decoded SC storage entry
+type sc_variable struct {
+	Key   string
+	Type  string // Uint64 or String
+	Value string // decoded value, address, text or hex
+	Raw   string // hex value for strings
+}
+
+type sc_asset_balance struct {
+	SCID    string
+	Balance uint64
+}
+
+// everything shown on sc and asset pages
+type sc_info struct {
+	SCID          string
+	Code          string
+	Installed     bool
+	Balance       string // DERO held by SC
+	Balanceuint64 uint64
+	Assets        []sc_asset_balance // other assets held by SC
+	StringKeys    []sc_variable
+	Uint64Keys    []sc_variable
+	Invocations   []sc_invocation
+	Transfers     []asset_transfer
+	IndexedTill   int64 // history is complete upto this topoheight
+	IndexedFrom   int64
+}
+
+// load SC state from rpc and history from index
+func load_sc_from_rpc(info *sc_info, scid string) (err error) {
+	var r rpc.GetSC_Result
+	if err = rpc_client.Call("DERO.GetSC", rpc.GetSC_Params{SCID: scid, Code: true, Variables: true}, &r); err != nil {
+		return fmt.Errorf("getsc rpc failed err %s", err)
+	}
+
+	info.SCID = scid
+	info.Code = r.Code
+	info.Installed = r.Code != ""
+	info.Balanceuint64 = r.Balance
+	info.Balance = globals.FormatMoney(r.Balance)
+
+	var zerohash crypto.Hash
+	for k, v := range r.Balances {
+		if k != zerohash.String() {
+			info.Assets = append(info.Assets, sc_asset_balance{SCID: k, Balance: v})
+		}
+	}
+	sort.Slice(info.Assets, func(i, j int) bool { return info.Assets[i].SCID < info.Assets[j].SCID })
+
+	for k, v := range r.VariableStringKeys {
+		info.StringKeys = append(info.StringKeys, decode_sc_variable(k, v))
+	}
+	sort.Slice(info.StringKeys, func(i, j int) bool { return info.StringKeys[i].Key < info.StringKeys[j].Key })
+
+	for k, v := range r.VariableUint64Keys {
+		info.Uint64Keys = append(info.Uint64Keys, decode_sc_variable(strconv.FormatUint(k, 10), v))
+	}
+	sort.Slice(info.Uint64Keys, func(i, j int) bool {
+		a, _ := strconv.ParseUint(info.Uint64Keys[i].Key, 10, 64)
+		b, _ := strconv.ParseUint(info.Uint64Keys[j].Key, 10, 64)
+		return a < b
+	})
+
+	info.Invocations = sc_index_invocations(scid)
+	info.Transfers = sc_index_transfers(scid)
+	info.IndexedTill = sc_index_progress()
+	info.IndexedFrom = SC_Index_Start
+	return nil
+}
+
+// daemon returns uint64 values as numbers and string values hex encoded
+// strings are shown as address if they are a valid compressed key, as text if printable, otherwise as hex
+func decode_sc_variable(key string, value interface{}) (v sc_variable) {
+	v.Key = key
+	switch value := value.(type) {
+	case float64: // json numbers
+		v.Type = "Uint64"
+		v.Value = strconv.FormatFloat(value, 'f', -1, 64)
+	case uint64:
+		v.Type = "Uint64"
+		v.Value = strconv.FormatUint(value, 10)
+	case string:
+		v.Type = "String"
+		v.Raw = value
+		v.Value = value
+		raw, err := hex.DecodeString(value)
+		if err != nil {
+			return
+		}
+		if len(raw) == 33 {
+			if addr, err := rpc.NewAddressFromCompressedKeys(raw); err == nil {
+				addr.Mainnet = mainnet
+				v.Value = addr.String()
+				return
+			}
+		}
+		if is_printable(raw) {
+			v.Value = string(raw)
+		}
+	default:
+		v.Value = fmt.Sprintf("%v", value)
+	}
+	return
+}
+
+func is_printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+func scid_from_path(path string, prefix string) (scid string, err error) {
+	var param string
+	fmt.Sscanf(path, prefix+"%s", &param)
+	if raw, err := hex.DecodeString(param); err != nil || len(raw) != 32 {
+		return "", fmt.Errorf("invalid scid %q", param)
+	}
+	return crypto.HashHexToHash(param).String(), nil
+}
+
+func sc_handler(w http.ResponseWriter, r *http.Request) {
+	var info sc_info
+
+	data := map[string]interface{}{}
+	fill_common_info(data, false)
+
+	scid, err := scid_from_path(r.URL.EscapedPath(), "/sc/")
+	if err == nil {
+		err = load_sc_from_rpc(&info, scid)
+	}
+	if err != nil || !info.Installed {
+		logger.V(1).Error(err, "sc not found", "path", r.URL.EscapedPath())
+		all_templates.ExecuteTemplate(w, "notfound_page", data)
+		return
+	}
+
+	data["sc"] = info
+	if err = all_templates.ExecuteTemplate(w, "sc", data); err != nil {
+		fmt.Fprintf(w, "Error occurred err %s", err)
+	}
+}
+
+// asset is a token issued by an SC, its SCID is same as the SCID of the issuing SC
+func asset_handler(w http.ResponseWriter, r *http.Request) {
+	var info sc_info
+
+	data := map[string]interface{}{}
+	fill_common_info(data, false)
+
+	scid, err := scid_from_path(r.URL.EscapedPath(), "/asset/")
+	if err == nil {
+		err = load_sc_from_rpc(&info, scid)
+	}
+	if err != nil {
+		logger.V(1).Error(err, "asset not found", "path", r.URL.EscapedPath())
+		all_templates.ExecuteTemplate(w, "notfound_page", data)
+		return
+	}
+
+	reserve := uint64(0) // tokens still held by the issuing SC
+	for _, a := range info.Assets {
+		if a.SCID == scid {
+			reserve = a.Balance
+		}
+	}
+
+	data["sc"] = info
+	data["reserve"] = reserve
+	if err = all_templates.ExecuteTemplate(w, "asset", data); err != nil {
+		fmt.Fprintf(w, "Error occurred err %s", err)
+	}
+}
+
+// resolve a name registered with name service, returns empty address if not registered
+func name_lookup(name string) (address string) {
+	var result rpc.NameToAddress_Result
+	if err := rpc_client.Call("DERO.NameToAddress", rpc.NameToAddress_Params{Name: name, TopoHeight: -1}, &result); err != nil {
+		return ""
+	}
+	return result.Address
+}
+
+func name_handler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+
+	data := map[string]interface{}{}
+	fill_common_info(data, false)
+	data["name"] = name
+	data["address"] = name_lookup(name)
+	data["nameservice"] = nameservice_scid
+
+	if err := all_templates.ExecuteTemplate(w, "name", data); err != nil {
+		fmt.Fprintf(w, "Error occurred err %s", err)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+// daemon keeps no history of SC invocations or asset transfers, so the explorer builds
+// its own in-memory index by walking the chain over RPC in the background
+// reorgs are handled by remembering recent block hashes and reindexing from the first changed topoheight
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/deroproject/derohe/block"
+	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/globals"
+	"github.com/deroproject/derohe/rpc"
+	"github.com/deroproject/derohe/transaction"
+)
+
+// topoheight from which SC history is indexed, setting it to recent topoheight makes mainnet startup fast
+var SC_Index_Start int64
+
+const sc_index_max_entries = 1000 // per SCID, older entries are dropped
+const sc_index_reorg_depth = 32   // how many recent block hashes are remembered to detect reorgs
+
+type sc_invocation struct {
+	TXID       string
+	Height     int64
+	TopoHeight int64
+	Block_time string
+	Install    bool
+	Entrypoint string
+	Signer     string
+	Deposit    string // DERO deposited to SC
+}
+
+type asset_transfer struct {
+	TXID       string
+	Height     int64
+	TopoHeight int64
+	Block_time string
+	Type       string // tx type
+	Ring_size  int
+	Burn       uint64 // tokens deposited to SC or burned
+}
+
+var sc_index = struct {
+	sync.RWMutex
+	next        int64 // next topoheight to be indexed
+	recent      map[int64]string
+	invocations map[string][]sc_invocation
+	transfers   map[string][]asset_transfer
+}{recent: map[int64]string{}, invocations: map[string][]sc_invocation{}, transfers: map[string][]asset_transfer{}}
+
+// returns index progress as topoheight upto which history is complete
+func sc_index_progress() int64 {
+	sc_index.RLock()
+	defer sc_index.RUnlock()
+	return sc_index.next - 1
+}
+
+// newest entries first
+func sc_index_invocations(scid string) (list []sc_invocation) {
+	sc_index.RLock()
+	defer sc_index.RUnlock()
+	entries := sc_index.invocations[scid]
+	for i := len(entries) - 1; i >= 0; i-- {
+		list = append(list, entries[i])
+	}
+	return
+}
+
+func sc_index_transfers(scid string) (list []asset_transfer) {
+	sc_index.RLock()
+	defer sc_index.RUnlock()
+	entries := sc_index.transfers[scid]
+	for i := len(entries) - 1; i >= 0; i-- {
+		list = append(list, entries[i])
+	}
+	return
+}
+
+// keep indexing as chain grows
+func sc_index_loop() {
+	sc_index.Lock()
+	sc_index.next = SC_Index_Start
+	sc_index.Unlock()
+
+	last_topoheight := int64(-1)
+	for {
+		var info rpc.GetInfo_Result
+		if err := rpc_client.Call("DERO.GetInfo", nil, &info); err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		stats_mempool_add(time.Now(), info.Tx_pool_size)
+
+		if info.TopoHeight != last_topoheight { // reorgs can only happen when chain changes
+			sc_index_check_reorg()
+			last_topoheight = info.TopoHeight
+		}
+
+		for topo := sc_index_progress() + 1; topo <= info.TopoHeight; topo++ {
+			if err := sc_index_block(topo); err != nil {
+				logger.V(1).Error(err, "sc index failed", "topoheight", topo)
+				break
+			}
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// if any recently indexed block changed, drop everything from that topoheight and index again
+func sc_index_check_reorg() {
+	sc_index.RLock()
+	recent := map[int64]string{}
+	for topo, hash := range sc_index.recent {
+		recent[topo] = hash
+	}
+	sc_index.RUnlock()
+
+	changed := int64(-1)
+	for topo, hash := range recent {
+		var result rpc.GetBlockHeaderByHeight_Result
+		if err := rpc_client.Call("DERO.GetBlockHeaderByTopoHeight", rpc.GetBlockHeaderByTopoHeight_Params{TopoHeight: uint64(topo)}, &result); err != nil || result.Block_Header.Hash != hash {
+			if changed == -1 || topo < changed {
+				changed = topo
+			}
+		}
+	}
+	if changed >= 0 {
+		logger.V(1).Info("reindexing SC history due to reorg", "topoheight", changed)
+		sc_index_rewind(changed)
+	}
+}
+
+func sc_index_rewind(topo int64) {
+	sc_index.Lock()
+	defer sc_index.Unlock()
+
+	for scid, entries := range sc_index.invocations {
+		i := len(entries)
+		for i > 0 && entries[i-1].TopoHeight >= topo {
+			i--
+		}
+		sc_index.invocations[scid] = entries[:i]
+	}
+	for scid, entries := range sc_index.transfers {
+		i := len(entries)
+		for i > 0 && entries[i-1].TopoHeight >= topo {
+			i--
+		}
+		sc_index.transfers[scid] = entries[:i]
+	}
+	for t := range sc_index.recent {
+		if t >= topo {
+			delete(sc_index.recent, t)
+		}
+	}
+	if topo < sc_index.next {
+		sc_index.next = topo
+	}
+	stats_rewind(topo)
+}
+
+func sc_index_block(topo int64) (err error) {
+	var bresult rpc.GetBlock_Result
+	if err = rpc_client.Call("DERO.GetBlock", rpc.GetBlock_Params{Height: uint64(topo)}, &bresult); err != nil {
+		return
+	}
+	var bl block.Block
+	block_bin, _ := hex.DecodeString(bresult.Blob)
+	if err = bl.Deserialize(block_bin); err != nil {
+		return
+	}
+
+	var invocations = map[string][]sc_invocation{}
+	var transfers = map[string][]asset_transfer{}
+	var valid_txs []*transaction.Transaction
+
+	if len(bl.Tx_hashes) >= 1 {
+		var tx_params rpc.GetTransaction_Params
+		var tx_result rpc.GetTransaction_Result
+		for i := range bl.Tx_hashes {
+			tx_params.Tx_Hashes = append(tx_params.Tx_Hashes, bl.Tx_hashes[i].String())
+		}
+		if err = rpc_client.Call("DERO.GetTransaction", tx_params, &tx_result); err != nil {
+			return
+		}
+		if len(tx_result.Txs) != len(bl.Tx_hashes) || len(tx_result.Txs_as_hex) != len(bl.Tx_hashes) {
+			return fmt.Errorf("daemon returned %d txs, expected %d", len(tx_result.Txs), len(bl.Tx_hashes))
+		}
+
+		block_time := time.Unix(0, int64(bresult.Block_Header.Timestamp*uint64(time.Millisecond))).Format("2006-01-02 15:04:05")
+		for i := range bl.Tx_hashes {
+			if tx_result.Txs[i].ValidBlock != bresult.Block_Header.Hash { // tx is indexed in the block where it is valid
+				continue
+			}
+
+			var tx transaction.Transaction
+			tx_bin, _ := hex.DecodeString(tx_result.Txs_as_hex[i])
+			if err := tx.Deserialize(tx_bin); err != nil {
+				continue
+			}
+			txid := bl.Tx_hashes[i].String()
+			valid_txs = append(valid_txs, &tx)
+
+			if tx.TransactionType == transaction.SC_TX {
+				if scid, inv, ok := sc_invocation_from_tx(&tx, txid, tx_result.Txs[i].Signer); ok {
+					inv.Height, inv.TopoHeight, inv.Block_time = bresult.Block_Header.Height, topo, block_time
+					invocations[scid] = append(invocations[scid], inv)
+				}
+			}
+
+			for t := range tx.Payloads {
+				if tx.Payloads[t].SCID.IsZero() {
+					continue
+				}
+				scid := tx.Payloads[t].SCID.String()
+				transfers[scid] = append(transfers[scid], asset_transfer{TXID: txid, Height: bresult.Block_Header.Height, TopoHeight: topo, Block_time: block_time,
+					Type: tx.TransactionType.String(), Ring_size: int(tx.Payloads[t].Statement.RingSize), Burn: tx.Payloads[t].BurnValue})
+			}
+		}
+	}
+
+	sc_index.Lock()
+	defer sc_index.Unlock()
+	if topo != sc_index.next { // a reorg rewound the index meanwhile
+		return nil
+	}
+	for scid := range invocations {
+		entries := append(sc_index.invocations[scid], invocations[scid]...)
+		if len(entries) > sc_index_max_entries {
+			entries = append([]sc_invocation{}, entries[len(entries)-sc_index_max_entries:]...)
+		}
+		sc_index.invocations[scid] = entries
+	}
+	for scid := range transfers {
+		entries := append(sc_index.transfers[scid], transfers[scid]...)
+		if len(entries) > sc_index_max_entries {
+			entries = append([]asset_transfer{}, entries[len(entries)-sc_index_max_entries:]...)
+		}
+		sc_index.transfers[scid] = entries
+	}
+	sc_index.recent[topo] = bresult.Block_Header.Hash
+	delete(sc_index.recent, topo-sc_index_reorg_depth)
+	sc_index.next = topo + 1
+	stats_add(stats_sample(topo, bresult.Block_Header.Height, bresult.Block_Header.Timestamp, bresult.Block_Header.Difficulty, len(bl.MiniBlocks), valid_txs))
+	return nil
+}
+
+// extract SCID and call details from an SC tx
+func sc_invocation_from_tx(tx *transaction.Transaction, txid string, signer string) (scid string, inv sc_invocation, ok bool) {
+	inv.TXID = txid
+	inv.Signer = signer
+
+	deposit := uint64(0)
+	for t := range tx.Payloads {
+		if tx.Payloads[t].SCID.IsZero() {
+			deposit += tx.Payloads[t].BurnValue
+		}
+	}
+	inv.Deposit = globals.FormatMoney(deposit)
+
+	if !tx.SCDATA.Has(rpc.SCACTION, rpc.DataUint64) {
+		return
+	}
+	switch rpc.SC_ACTION(tx.SCDATA.Value(rpc.SCACTION, rpc.DataUint64).(uint64)) {
+	case rpc.SC_INSTALL:
+		inv.Install = true
+		inv.Entrypoint = "Initialize"
+		return txid, inv, true
+	case rpc.SC_CALL:
+		if !tx.SCDATA.Has(rpc.SCID, rpc.DataHash) {
+			return
+		}
+		if tx.SCDATA.Has("entrypoint", rpc.DataString) {
+			inv.Entrypoint = tx.SCDATA.Value("entrypoint", rpc.DataString).(string)
+		}
+		return tx.SCDATA.Value(rpc.SCID, rpc.DataHash).(crypto.Hash).String(), inv, true
+	}
+	return
+}
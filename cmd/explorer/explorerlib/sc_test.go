@@ -0,0 +1,142 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deroproject/derohe/rpc"
+	"github.com/deroproject/derohe/simulator"
+	"github.com/go-logr/logr"
+)
+
+const test_sc = `
+	Function Initialize() Uint64
+	10 STORE("owner", SIGNER())
+	20 STORE("title", "hello explorer")
+	30 STORE(7, 42)
+	40 STORE("pings", 0)
+	50 RETURN 0
+	End Function
+
+	Function Ping() Uint64
+	10 STORE("pings", LOAD("pings") + 1)
+	20 RETURN 0
+	End Function
+`
+
+func Test_Decode_SC_Variable(t *testing.T) {
+	if v := decode_sc_variable("k", float64(12345678)); v.Type != "Uint64" || v.Value != "12345678" {
+		t.Fatalf("uint64 decoding failed %+v", v)
+	}
+	if v := decode_sc_variable("k", "68656c6c6f"); v.Type != "String" || v.Value != "hello" {
+		t.Fatalf("text decoding failed %+v", v)
+	}
+	if v := decode_sc_variable("k", "00ff01"); v.Value != "00ff01" {
+		t.Fatalf("binary values must stay hex %+v", v)
+	}
+}
+
+// explorer registers its handlers globally, so all tests share a single simulator and explorer
+var test_sim *simulator.Simulator
+var test_address string
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if test_sim != nil {
+		test_sim.Stop()
+	}
+	os.Exit(code)
+}
+
+func start_test_explorer(t *testing.T) (*simulator.Simulator, string) {
+	if test_sim != nil {
+		return test_sim, test_address
+	}
+
+	s, err := simulator.Start("", 2, simulator.TEST_FUNDS) // outlives a single test, so StartTest cannot be used
+	if err != nil {
+		t.Fatalf("cannot start simulator err %s", err)
+	}
+	test_sim = s
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot find free port err %s", err)
+	}
+	test_address = l.Addr().String()
+	l.Close()
+
+	if err = StartServer(logr.Discard(), s.RPCAddress, test_address); err != nil {
+		t.Fatalf("cannot start explorer err %s", err)
+	}
+	return test_sim, test_address
+}
+
+func test_get(t *testing.T, path string) (*http.Response, string) {
+	resp, err := http.Get("http://" + test_address + path)
+	if err != nil {
+		t.Fatalf("get %s err %s", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func Test_SC_Pages(t *testing.T) {
+	s, _ := start_test_explorer(t)
+
+	scid := s.MustInstallSC(t, s.Wallets[0], test_sc)
+	s.MustInvokeSC(t, s.Wallets[1], scid, "Ping", rpc.Arguments{}, 0)
+
+	get := func(path string) string {
+		_, body := test_get(t, path)
+		return body
+	}
+
+	for i := 0; sc_index_progress() < s.Chain.Load_TOPO_HEIGHT(); i++ { // wait for indexer to catch up
+		if i > 100 {
+			t.Fatalf("sc index did not catch up, indexed %d topoheight %d", sc_index_progress(), s.Chain.Load_TOPO_HEIGHT())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	page := get("/sc/" + scid.String())
+	for _, expected := range []string{"hello explorer", s.Wallets[0].GetAddress().String(), "Ping", "Initialize", "pings", "42"} {
+		if !strings.Contains(page, expected) {
+			t.Fatalf("sc page does not contain %q", expected)
+		}
+	}
+
+	if page = get("/asset/" + scid.String()); !strings.Contains(page, "Issued by smart contract") {
+		t.Fatalf("asset page does not link issuing sc")
+	}
+
+	if page = get("/search?value=" + scid.String()); !strings.Contains(page, "hello explorer") {
+		t.Fatalf("search for scid must show sc page")
+	}
+
+	if page = get("/name?name=unregistered-name"); !strings.Contains(page, "Name is not registered") {
+		t.Fatalf("name page must show unregistered names")
+	}
+}
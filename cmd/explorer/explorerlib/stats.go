@@ -0,0 +1,343 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+// this file implements network statistics
+// samples are collected by the same walker which indexes SC history, so they cover topoheight SC_Index_Start onwards
+// charts are rendered as inline SVG, so no javascript is needed
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deroproject/derohe/rpc"
+	"github.com/deroproject/derohe/transaction"
+)
+
+const stats_max_blocks = 50000  // per block samples kept in memory
+const stats_max_mempool = 1440  // mempool samples, one per minute for a day
+const stats_max_points = 200    // charts are downsampled to these many points
+const stats_height_window = 100 // blocks and miniblocks per height are shown for these many recent heights
+
+// per block sample
+type stats_block struct {
+	TopoHeight int64
+	Height     int64
+	Timestamp  uint64 // block timestamp in millisecs
+	Difficulty uint64
+	MiniBlocks int
+	Txs        int
+	RingSum    int    // sum of ring sizes of all payloads
+	Payloads   int    // number of payloads
+	Fees       uint64 // total fees in atomic units
+	SCInstalls int
+}
+
+type stats_mempool struct {
+	Time time.Time
+	Size uint64
+}
+
+var stats_cache = struct {
+	sync.RWMutex
+	blocks  []stats_block
+	mempool []stats_mempool
+}{}
+
+// build sample from block and its valid txs
+func stats_sample(topo int64, height int64, timestamp uint64, difficulty string, miniblocks int, txs []*transaction.Transaction) (s stats_block) {
+	s.TopoHeight, s.Height, s.Timestamp, s.MiniBlocks = topo, height, timestamp, miniblocks
+	s.Difficulty, _ = strconv.ParseUint(difficulty, 10, 64)
+	s.Txs = len(txs)
+	for _, tx := range txs {
+		for t := range tx.Payloads {
+			s.RingSum += int(tx.Payloads[t].Statement.RingSize)
+			s.Payloads++
+		}
+		s.Fees += tx.Fees()
+		if tx.TransactionType == transaction.SC_TX && tx.SCDATA.Has(rpc.SCCODE, rpc.DataString) {
+			s.SCInstalls++
+		}
+	}
+	return
+}
+
+func stats_add(s stats_block) {
+	stats_cache.Lock()
+	defer stats_cache.Unlock()
+	stats_cache.blocks = append(stats_cache.blocks, s)
+	if len(stats_cache.blocks) > stats_max_blocks {
+		stats_cache.blocks = append([]stats_block{}, stats_cache.blocks[len(stats_cache.blocks)-stats_max_blocks:]...)
+	}
+}
+
+// drop samples from topoheight onwards
+func stats_rewind(topo int64) {
+	stats_cache.Lock()
+	defer stats_cache.Unlock()
+	i := len(stats_cache.blocks)
+	for i > 0 && stats_cache.blocks[i-1].TopoHeight >= topo {
+		i--
+	}
+	stats_cache.blocks = stats_cache.blocks[:i]
+}
+
+// mempool is sampled at most once a minute
+func stats_mempool_add(now time.Time, size uint64) {
+	stats_cache.Lock()
+	defer stats_cache.Unlock()
+	if l := len(stats_cache.mempool); l > 0 && now.Sub(stats_cache.mempool[l-1].Time) < time.Minute {
+		return
+	}
+	stats_cache.mempool = append(stats_cache.mempool, stats_mempool{Time: now, Size: size})
+	if len(stats_cache.mempool) > stats_max_mempool {
+		stats_cache.mempool = append([]stats_mempool{}, stats_cache.mempool[len(stats_cache.mempool)-stats_max_mempool:]...)
+	}
+}
+
+// a chart series, labels and values are of same length
+type stats_series struct {
+	Title  string    `json:"title"`
+	Unit   string    `json:"unit"`
+	Labels []string  `json:"labels"`
+	Values []float64 `json:"values"`
+	Bars   bool      `json:"-"`
+}
+
+// per day aggregate
+type stats_day struct {
+	Txs        int
+	RingSum    int
+	Payloads   int
+	Fees       uint64
+	SCInstalls int
+}
+
+// compute all series from cache
+func stats_compute() (series []stats_series) {
+	stats_cache.RLock()
+	blocks := append([]stats_block{}, stats_cache.blocks...)
+	mempool := append([]stats_mempool{}, stats_cache.mempool...)
+	stats_cache.RUnlock()
+
+	// difficulty over time, downsampled by averaging
+	difficulty := stats_series{Title: "Difficulty (daemon reports this as hash rate in H/s)", Unit: "H/s"}
+	step := (len(blocks) + stats_max_points - 1) / stats_max_points
+	if step < 1 {
+		step = 1
+	}
+	for i := 0; i < len(blocks); i += step {
+		end := i + step
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		sum := float64(0)
+		for _, b := range blocks[i:end] {
+			sum += float64(b.Difficulty)
+		}
+		difficulty.Labels = append(difficulty.Labels, fmt.Sprintf("topo %d %s", blocks[i].TopoHeight, stats_time(blocks[i].Timestamp)))
+		difficulty.Values = append(difficulty.Values, sum/float64(end-i))
+	}
+
+	// blocks and miniblocks per height for recent heights
+	per_height := map[int64][2]int{}
+	var heights []int64
+	for _, b := range blocks {
+		v, ok := per_height[b.Height]
+		if !ok {
+			heights = append(heights, b.Height)
+		}
+		per_height[b.Height] = [2]int{v[0] + 1, v[1] + b.MiniBlocks}
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	if len(heights) > stats_height_window {
+		heights = heights[len(heights)-stats_height_window:]
+	}
+	blocks_height := stats_series{Title: "Blocks per height", Unit: "blocks", Bars: true}
+	miniblocks_height := stats_series{Title: "Miniblocks per height", Unit: "miniblocks", Bars: true}
+	for _, h := range heights {
+		label := fmt.Sprintf("height %d", h)
+		blocks_height.Labels = append(blocks_height.Labels, label)
+		blocks_height.Values = append(blocks_height.Values, float64(per_height[h][0]))
+		miniblocks_height.Labels = append(miniblocks_height.Labels, label)
+		miniblocks_height.Values = append(miniblocks_height.Values, float64(per_height[h][1]))
+	}
+
+	// per day aggregates
+	days := map[string]*stats_day{}
+	var day_list []string
+	for _, b := range blocks {
+		day := time.UnixMilli(int64(b.Timestamp)).UTC().Format("2006-01-02")
+		d, ok := days[day]
+		if !ok {
+			d = &stats_day{}
+			days[day] = d
+			day_list = append(day_list, day)
+		}
+		d.Txs += b.Txs
+		d.RingSum += b.RingSum
+		d.Payloads += b.Payloads
+		d.Fees += b.Fees
+		d.SCInstalls += b.SCInstalls
+	}
+	sort.Strings(day_list)
+
+	txs := stats_series{Title: "Transactions per day", Unit: "txs", Bars: true}
+	ring := stats_series{Title: "Average ring size per day", Unit: "ring members"}
+	fees := stats_series{Title: "Average fee per tx per day", Unit: "DERO"}
+	scs := stats_series{Title: "SC deployments per day", Unit: "SCs", Bars: true}
+	for _, day := range day_list {
+		d := days[day]
+		txs.Labels, txs.Values = append(txs.Labels, day), append(txs.Values, float64(d.Txs))
+		scs.Labels, scs.Values = append(scs.Labels, day), append(scs.Values, float64(d.SCInstalls))
+
+		avg_ring, avg_fee := float64(0), float64(0)
+		if d.Payloads > 0 {
+			avg_ring = float64(d.RingSum) / float64(d.Payloads)
+		}
+		if d.Txs > 0 {
+			avg_fee = float64(d.Fees) / float64(d.Txs) / 100000
+		}
+		ring.Labels, ring.Values = append(ring.Labels, day), append(ring.Values, avg_ring)
+		fees.Labels, fees.Values = append(fees.Labels, day), append(fees.Values, avg_fee)
+	}
+
+	pool := stats_series{Title: "Mempool size", Unit: "txs"}
+	for _, m := range mempool {
+		pool.Labels = append(pool.Labels, m.Time.UTC().Format("2006-01-02 15:04"))
+		pool.Values = append(pool.Values, float64(m.Size))
+	}
+
+	return []stats_series{difficulty, blocks_height, miniblocks_height, txs, ring, fees, pool, scs}
+}
+
+func stats_time(timestamp uint64) string {
+	return time.UnixMilli(int64(timestamp)).UTC().Format("2006-01-02 15:04")
+}
+
+// renders series as a self contained svg chart
+func svg_chart(s stats_series) template.HTML {
+	const width, height, pad_left, pad_bottom, pad_top = 800.0, 200.0, 70.0, 20.0, 10.0
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" style="background:#fff;border:1px solid #ccc">`, int(width), int(height), int(width), int(height))
+
+	if len(s.Values) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12">no data yet</text></svg>`, int(width/2-30), int(height/2))
+		return template.HTML(b.String())
+	}
+
+	max := 0.0
+	for _, v := range s.Values {
+		max = math.Max(max, v)
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	plot_w, plot_h := width-pad_left-10, height-pad_bottom-pad_top
+	x := func(i int) float64 {
+		if len(s.Values) == 1 {
+			return pad_left + plot_w/2
+		}
+		return pad_left + float64(i)*plot_w/float64(len(s.Values)-1)
+	}
+	y := func(v float64) float64 { return pad_top + plot_h - v/max*plot_h }
+
+	// axes and scale
+	fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#888"/>`, pad_left, pad_top, pad_left, pad_top+plot_h)
+	fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#888"/>`, pad_left, pad_top+plot_h, pad_left+plot_w, pad_top+plot_h)
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="end">%s</text>`, pad_left-4, pad_top+8, template.HTMLEscapeString(stats_format(max)))
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="end">0</text>`, pad_left-4, pad_top+plot_h)
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10">%s</text>`, pad_left, height-4, template.HTMLEscapeString(s.Labels[0]))
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="end">%s</text>`, pad_left+plot_w, height-4, template.HTMLEscapeString(s.Labels[len(s.Labels)-1]))
+
+	if s.Bars {
+		bar_w := math.Max(1, plot_w/float64(len(s.Values))-1)
+		for i, v := range s.Values {
+			bx := pad_left + float64(i)*plot_w/float64(len(s.Values))
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#3a7bd5"><title>%s: %s %s</title></rect>`,
+				bx, y(v), bar_w, pad_top+plot_h-y(v), template.HTMLEscapeString(s.Labels[i]), stats_format(v), template.HTMLEscapeString(s.Unit))
+		}
+	} else {
+		var points []string
+		for i, v := range s.Values {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(v)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="#3a7bd5" stroke-width="1.5" points="%s"/>`, strings.Join(points, " "))
+		for i, v := range s.Values {
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2" fill="#3a7bd5"><title>%s: %s %s</title></circle>`,
+				x(i), y(v), template.HTMLEscapeString(s.Labels[i]), stats_format(v), template.HTMLEscapeString(s.Unit))
+		}
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func stats_format(v float64) string {
+	switch {
+	case v >= 1e12:
+		return fmt.Sprintf("%.2fT", v/1e12)
+	case v >= 1e9:
+		return fmt.Sprintf("%.2fG", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.2fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.2fK", v/1e3)
+	case v == math.Trunc(v):
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.5f", v)
+}
+
+type stats_chart struct {
+	Title string
+	SVG   template.HTML
+}
+
+func stats_handler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{}
+	fill_common_info(data, false)
+
+	var charts []stats_chart
+	for _, s := range stats_compute() {
+		charts = append(charts, stats_chart{Title: s.Title, SVG: svg_chart(s)})
+	}
+
+	stats_cache.RLock()
+	data["samples"] = len(stats_cache.blocks)
+	stats_cache.RUnlock()
+	data["charts"] = charts
+	data["indexed_from"] = SC_Index_Start
+	data["indexed_till"] = sc_index_progress()
+
+	if err := all_templates.ExecuteTemplate(w, "stats", data); err != nil {
+		fmt.Fprintf(w, "Error occurred err %s", err)
+	}
+}
+
+// same series as json
+func api_stats_handler(w http.ResponseWriter, r *http.Request) {
+	api_write(w, r, http.StatusOK, cache_short, "", stats_compute())
+}
@@ -0,0 +1,82 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package explorerlib
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_SVG_Chart(t *testing.T) {
+	if svg := string(svg_chart(stats_series{Title: "empty"})); !strings.Contains(svg, "no data yet") {
+		t.Fatalf("empty chart must say so, got %s", svg)
+	}
+
+	line := string(svg_chart(stats_series{Unit: "txs", Labels: []string{"a", "<b>"}, Values: []float64{1, 2500}}))
+	if !strings.HasPrefix(line, "<svg") || !strings.Contains(line, "<polyline") || !strings.Contains(line, "2.50K") || strings.Contains(line, "<b>") {
+		t.Fatalf("invalid line chart %s", line)
+	}
+
+	bars := string(svg_chart(stats_series{Labels: []string{"a", "b", "c"}, Values: []float64{0, 1, 2}, Bars: true}))
+	if strings.Count(bars, "<rect") != 3 || strings.Contains(bars, "<polyline") {
+		t.Fatalf("invalid bar chart %s", bars)
+	}
+}
+
+func Test_Stats_Page(t *testing.T) {
+	s, _ := start_test_explorer(t)
+
+	s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 1000)
+	for i := 0; sc_index_progress() < s.Chain.Load_TOPO_HEIGHT(); i++ { // wait for indexer to catch up
+		if i > 100 {
+			t.Fatalf("stats did not catch up, indexed %d topoheight %d", sc_index_progress(), s.Chain.Load_TOPO_HEIGHT())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	_, body := test_get(t, "/stats")
+	if strings.Count(body, "<svg") != 8 || !strings.Contains(body, "Transactions per day") || strings.Contains(body, "<script") {
+		t.Fatalf("stats page must render all charts without scripts, body %s", body)
+	}
+
+	_, body = test_get(t, "/api/v1/stats")
+	var series []stats_series
+	if err := json.Unmarshal([]byte(body), &series); err != nil || len(series) != 8 {
+		t.Fatalf("stats api failed err %v body %s", err, body)
+	}
+	txs, ring := 0.0, 0.0
+	for _, v := range series[3].Values {
+		txs += v
+	}
+	for _, v := range series[4].Values {
+		ring = v
+	}
+	if len(series[0].Values) == 0 || len(series[1].Values) == 0 || txs < 1 || ring != 2 {
+		t.Fatalf("stats missing data %+v", series)
+	}
+
+	top := s.Chain.Load_TOPO_HEIGHT()
+	stats_rewind(top)
+	stats_cache.RLock()
+	last := stats_cache.blocks[len(stats_cache.blocks)-1].TopoHeight
+	stats_cache.RUnlock()
+	if last >= top {
+		t.Fatalf("rewind must drop samples from topoheight %d, last %d", top, last)
+	}
+}
@@ -68,7 +68,7 @@ DERO : A secure, private blockchain with smart-contracts
 Simulates DERO block single node which helps in development and tests
 
 Usage:
-  simulator [--help] [--version] [--testnet] [--debug] [--noautomine] [--sync-node] [--data-dir=<directory>] [--rpc-bind=<127.0.0.1:9999>] [--http-address=<0.0.0.0:8080>] [--clog-level=1] [--flog-level=1]
+  simulator [--help] [--version] [--testnet] [--debug] [--noautomine] [--sync-node] [--seed=<seed>] [--data-dir=<directory>] [--rpc-bind=<127.0.0.1:9999>] [--http-address=<0.0.0.0:8080>] [--clog-level=1] [--flog-level=1]
   simulator -h | --help
   simulator --version
 
@@ -78,6 +78,7 @@ Options:
   --testnet  	Run in testnet mode.
   --debug       Debug mode enabled, print more log messages
   --noautomine  No blocks will be mined (except genesis), used for testing, supported only on linux
+  --seed=<seed>  Derive genesis and wallet keys from this seed, so runs are reproducible with different keys
   --clog-level=1	Set console log level (0 to 127) 
   --flog-level=1	Set file log level (0 to 127)
   --data-dir=<directory>    Store blockchain data at this location
@@ -100,6 +101,9 @@ const wallet_ports_start = 30000 // all wallets will rpc activated on ports
 func Mine_block_single(chain *blockchain.Blockchain, miner_address rpc.Address) error {
 	var blid crypto.Hash
 
+	mining_lock.Lock()
+	defer mining_lock.Unlock()
+
 	//if !chain.simulator{
 	//	return fmt.Errorf("this function can only run in simulator mode")
 	//}
@@ -198,6 +202,7 @@ func main() {
 	logger.Info("Disabled P2P server since we are a simulator")
 	p2p.P2P_Init(params)
 
+	derodrpc.RegisterService("SIMULATOR", simulator_apis(chain))
 	rpcserver, _ := derodrpc.RPCServer_Start(params)
 
 	register_wallets(chain)                               // setup 22 wallets
@@ -404,6 +409,62 @@ func main() {
 				logger.Error(fmt.Errorf("mempool_delete_tx  needs a single transaction id as argument"), "")
 			}
 
+		case command == "mine": // mine blocks right now, mine <count>
+			count := 1
+			if len(line_parts) == 2 {
+				if count, err = strconv.Atoi(line_parts[1]); err != nil || count < 1 {
+					logger.Error(fmt.Errorf("mine needs a positive block count"), "")
+					continue
+				}
+			}
+			if err := mine_blocks(chain, count); err != nil {
+				logger.Error(err, "error while mining")
+			} else {
+				logger.Info("mined blocks", "count", count, "height", chain.Get_Height(), "topoheight", chain.Load_TOPO_HEIGHT())
+			}
+
+		case command == "snapshot": // snapshot <name>, without name lists snapshots
+			if len(line_parts) != 2 {
+				snapshot_print()
+				continue
+			}
+			if s, err := snapshot_take(chain, line_parts[1]); err != nil {
+				logger.Error(err, "snapshot failed")
+			} else {
+				logger.Info("snapshot taken", "name", line_parts[1], "topoheight", s.TopoHeight, "blid", s.Hash)
+			}
+
+		case command == "restore": // restore <name>
+			if len(line_parts) != 2 {
+				logger.Error(fmt.Errorf("restore needs a snapshot name as argument"), "")
+				continue
+			}
+			if s, err := snapshot_restore(chain, line_parts[1]); err != nil {
+				logger.Error(err, "restore failed")
+			} else {
+				logger.Info("snapshot restored", "name", line_parts[1], "topoheight", s.TopoHeight, "blid", s.Hash)
+			}
+
+		case command == "warp": // warp <seconds> or warp <duration> such as 1h30m
+			if len(line_parts) != 2 {
+				logger.Info("clock", "skew", chain.Get_Clock_Skew())
+				continue
+			}
+			d, err := time.ParseDuration(line_parts[1])
+			if err != nil {
+				var seconds int64
+				if seconds, err = strconv.ParseInt(line_parts[1], 10, 64); err != nil {
+					logger.Error(fmt.Errorf("warp needs seconds or a duration such as 1h30m"), "")
+					continue
+				}
+				d = time.Duration(seconds) * time.Second
+			}
+			if err = warp(chain, d); err != nil {
+				logger.Error(err, "warp failed")
+			} else {
+				logger.Info("clock moved forward, next block will use it", "by", d, "skew", chain.Get_Clock_Skew())
+			}
+
 		case command == "version":
 			logger.Info("", "OS", runtime.GOOS, "ARCH", runtime.GOARCH, "GOMAXPROCS", runtime.GOMAXPROCS(0))
 			logger.Info("", "Version", config.Version.String())
@@ -627,6 +688,10 @@ func usage(w io.Writer) {
 	io.WriteString(w, "\t\033[1mregpool_print\033[0m\t\tprint regpool contents\n")
 	io.WriteString(w, "\t\033[1mregpool_delete_tx\033[0m\t\tDelete specific tx from regpool\n")
 	io.WriteString(w, "\t\033[1mregpool_flush\033[0m\t\tFlush mempool\n")
+	io.WriteString(w, "\t\033[1mmine\033[0m\t\tMine blocks now, mine <count>\n")
+	io.WriteString(w, "\t\033[1msnapshot\033[0m\tSnapshot chain and wallet state, snapshot <name>, without name lists snapshots\n")
+	io.WriteString(w, "\t\033[1mrestore\033[0m\t\tRestore chain and wallet state to a snapshot, restore <name>\n")
+	io.WriteString(w, "\t\033[1mwarp\033[0m\t\tMove block timestamps forward, warp <seconds> or <duration> such as 1h30m\n")
 	io.WriteString(w, "\t\033[1mversion\033[0m\t\tShow version\n")
 	io.WriteString(w, "\t\033[1mexit\033[0m\t\tQuit the daemon\n")
 	io.WriteString(w, "\t\033[1mquit\033[0m\t\tQuit the daemon\n")
@@ -644,6 +709,10 @@ var completer = readline.NewPrefixCompleter(
 	readline.PcItem("regpool_delete_tx"),
 	readline.PcItem("regpool_print"),
 	readline.PcItem("status"),
+	readline.PcItem("mine"),
+	readline.PcItem("snapshot"),
+	readline.PcItem("restore"),
+	readline.PcItem("warp"),
 	readline.PcItem("version"),
 	readline.PcItem("bye"),
 	readline.PcItem("exit"),
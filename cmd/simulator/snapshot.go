@@ -0,0 +1,189 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "fmt"
+import "sort"
+import "sync"
+import "time"
+import "context"
+
+import "github.com/creachadair/jrpc2/handler"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/walletapi"
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/simulator"
+
+// chain snapshots are light weight, chain state is versioned by topoheight
+// so restoring is just rewinding the chain and letting the wallets resync
+type snapshot struct {
+	TopoHeight int64
+	Hash       crypto.Hash
+	ClockSkew  time.Duration
+	Created    time.Time
+}
+
+var snapshots = map[string]snapshot{}
+var snapshots_lock sync.Mutex
+
+var mining_lock sync.Mutex // no blocks are mined while a snapshot is being restored
+
+// wallet keys are derived from --seed if provided, otherwise the fixed seeds are used
+func wallet_seed(index int, fixed string) string {
+	if globals.Arguments["--seed"] == nil {
+		return fixed
+	}
+	return fmt.Sprintf("%064x", simulator.Wallet_Seed(globals.Arguments["--seed"].(string), index).BigInt())
+}
+
+// mine count blocks one after another
+func mine_blocks(chain *blockchain.Blockchain, count int) error {
+	for i := 0; i < count; i++ {
+		if err := Mine_block_single(chain, genesis_wallet.GetAddress()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func snapshot_take(chain *blockchain.Blockchain, name string) (s snapshot, err error) {
+	mining_lock.Lock()
+	defer mining_lock.Unlock()
+
+	s.TopoHeight = chain.Load_TOPO_HEIGHT()
+	if s.Hash, err = chain.Load_Block_Topological_order_at_index(s.TopoHeight); err != nil {
+		return
+	}
+	s.ClockSkew = chain.Get_Clock_Skew()
+	s.Created = time.Now()
+
+	snapshots_lock.Lock()
+	snapshots[name] = s
+	snapshots_lock.Unlock()
+	return
+}
+
+// rewind chain to snapshot, flush pools, restore clock and resync all wallets
+func snapshot_restore(chain *blockchain.Blockchain, name string) (s snapshot, err error) {
+	mining_lock.Lock()
+	defer mining_lock.Unlock()
+
+	snapshots_lock.Lock()
+	s, ok := snapshots[name]
+	snapshots_lock.Unlock()
+	if !ok {
+		return s, fmt.Errorf("snapshot %s not found", name)
+	}
+
+	top := chain.Load_TOPO_HEIGHT()
+	if s.TopoHeight > top {
+		return s, fmt.Errorf("snapshot %s topoheight %d is ahead of chain topoheight %d", name, s.TopoHeight, top)
+	}
+	if hash, err := chain.Load_Block_Topological_order_at_index(s.TopoHeight); err != nil || hash != s.Hash {
+		return s, fmt.Errorf("snapshot %s is no longer part of chain", name)
+	}
+
+	if top > s.TopoHeight && !chain.Rewind_Chain(int(top-s.TopoHeight)) {
+		return s, fmt.Errorf("chain could not be rewinded to topoheight %d", s.TopoHeight)
+	}
+	if chain.Load_TOPO_HEIGHT() != s.TopoHeight {
+		return s, fmt.Errorf("chain rewinded to topoheight %d instead of %d", chain.Load_TOPO_HEIGHT(), s.TopoHeight)
+	}
+
+	chain.Mempool.Mempool_flush()
+	chain.Regpool.Regpool_flush()
+	chain.Set_Clock_Skew(s.ClockSkew)
+
+	for _, w := range append([]*walletapi.Wallet_Disk{genesis_wallet}, wallets...) {
+		w.Clean()
+		if !w.GetMode() { // offline wallets will resync when they go online
+			continue
+		}
+		if err = w.Sync_Wallet_Memory_With_Daemon(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// moves block timestamps forward, only forward jumps are allowed
+func warp(chain *blockchain.Blockchain, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("warp duration must be positive")
+	}
+	chain.Add_Clock_Skew(d)
+	return nil
+}
+
+func snapshot_print() {
+	snapshots_lock.Lock()
+	defer snapshots_lock.Unlock()
+
+	var names []string
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%-20s %-12s %-64s %s\n", "Name", "TopoHeight", "Block", "Clock skew")
+	for _, name := range names {
+		s := snapshots[name]
+		fmt.Printf("%-20s %-12d %-64s %s\n", name, s.TopoHeight, s.Hash, s.ClockSkew)
+	}
+}
+
+// rpc apis registered under SIMULATOR service
+func simulator_apis(chain *blockchain.Blockchain) handler.Map {
+	return handler.Map{
+		"Mine": handler.New(func(ctx context.Context, p rpc.SimulatorMine_Params) (result rpc.SimulatorMine_Result, err error) {
+			if p.Count <= 0 {
+				p.Count = 1
+			}
+			if err = mine_blocks(chain, p.Count); err != nil {
+				return
+			}
+			result.Height, result.TopoHeight, result.Status = chain.Get_Height(), chain.Load_TOPO_HEIGHT(), "OK"
+			return
+		}),
+		"Snapshot": handler.New(func(ctx context.Context, p rpc.SimulatorSnapshot_Params) (result rpc.SimulatorSnapshot_Result, err error) {
+			if p.Name == "" {
+				return result, fmt.Errorf("snapshot name cannot be empty")
+			}
+			s, err := snapshot_take(chain, p.Name)
+			if err != nil {
+				return
+			}
+			return rpc.SimulatorSnapshot_Result{Name: p.Name, TopoHeight: s.TopoHeight, Hash: s.Hash.String(), Status: "OK"}, nil
+		}),
+		"Restore": handler.New(func(ctx context.Context, p rpc.SimulatorRestore_Params) (result rpc.SimulatorRestore_Result, err error) {
+			s, err := snapshot_restore(chain, p.Name)
+			if err != nil {
+				return
+			}
+			return rpc.SimulatorRestore_Result{Name: p.Name, TopoHeight: s.TopoHeight, Hash: s.Hash.String(), Status: "OK"}, nil
+		}),
+		"Warp": handler.New(func(ctx context.Context, p rpc.SimulatorWarp_Params) (result rpc.SimulatorWarp_Result, err error) {
+			if err = warp(chain, time.Duration(p.Seconds)*time.Second); err != nil {
+				return
+			}
+			return rpc.SimulatorWarp_Result{ClockSkew: int64(chain.Get_Clock_Skew() / time.Second), Status: "OK"}, nil
+		}),
+	}
+}
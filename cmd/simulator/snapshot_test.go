@@ -0,0 +1,131 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import "os"
+import "fmt"
+import "time"
+import "testing"
+import "path/filepath"
+
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/walletapi"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/transaction"
+
+func Test_Snapshot_Restore_Warp(t *testing.T) {
+	walletapi.Initialize_LookupTable(1, 1<<17)
+
+	wgenesis_temp_db := filepath.Join(os.TempDir(), "dero_temporary_test_wallet_genesis.db")
+	os.Remove(wgenesis_temp_db)
+	defer os.Remove(wgenesis_temp_db)
+
+	wgenesis, err := walletapi.Create_Encrypted_Wallet_From_Recovery_Words(wgenesis_temp_db, "QWER", "perfil lujo faja puma favor pedir detalle doble carbón neón paella cuarto ánimo cuento conga correr dental moneda león donar entero logro realidad acceso doble")
+	if err != nil {
+		t.Fatalf("Cannot create encrypted wallet, err %s", err)
+	}
+	genesis_wallet, wallets = wgenesis, nil
+	wgenesis.SetDaemonAddress(rpcport_test)
+	wgenesis.SetOnlineMode()
+
+	genesis_tx := transaction.Transaction{Transaction_Prefix: transaction.Transaction_Prefix{Version: 1, Value: 2012345}}
+	copy(genesis_tx.MinerAddress[:], wgenesis.GetAddress().PublicKey.EncodeCompressed())
+	config.Testnet.Genesis_Tx = fmt.Sprintf("%x", genesis_tx.Serialize())
+	config.Mainnet.Genesis_Tx = fmt.Sprintf("%x", genesis_tx.Serialize())
+	genesis_block := blockchain.Generate_Genesis_Block()
+	config.Testnet.Genesis_Block_Hash = genesis_block.GetHash()
+	config.Mainnet.Genesis_Block_Hash = genesis_block.GetHash()
+
+	chain, rpcserver, _ := simulator_chain_start()
+	defer simulator_chain_stop(chain, rpcserver)
+	time.Sleep(100 * time.Millisecond)
+	if err = walletapi.Connect(rpcport_test); err != nil {
+		t.Fatalf("cannot connect to daemon err %s", err)
+	}
+
+	if err = mine_blocks(chain, 2); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	snap, err := snapshot_take(chain, "start")
+	if err != nil {
+		t.Fatalf("snapshot failed err %s", err)
+	}
+	wgenesis.Sync_Wallet_Memory_With_Daemon()
+	snap_balance, _ := wgenesis.Get_Balance()
+
+	if err = warp(chain, -time.Hour); err == nil {
+		t.Fatalf("backward warp must fail")
+	}
+	if err = warp(chain, time.Hour); err != nil {
+		t.Fatalf("warp failed err %s", err)
+	}
+	if err = mine_blocks(chain, 3); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if chain.Load_TOPO_HEIGHT() != snap.TopoHeight+3 {
+		t.Fatalf("expected topoheight %d actual %d", snap.TopoHeight+3, chain.Load_TOPO_HEIGHT())
+	}
+	if ts := chain.Load_Block_Timestamp(chain.Get_Top_ID()); ts < uint64(time.Now().Add(59*time.Minute).UnixMilli()) {
+		t.Fatalf("block timestamp %d was not warped", ts)
+	}
+
+	if _, err = snapshot_restore(chain, "missing"); err == nil {
+		t.Fatalf("restoring unknown snapshot must fail")
+	}
+	for i := 0; i < 2; i++ { // restoring twice and mining in between must work
+		if _, err = snapshot_restore(chain, "start"); err != nil {
+			t.Fatalf("restore failed err %s", err)
+		}
+		if chain.Load_TOPO_HEIGHT() != snap.TopoHeight || chain.Get_Top_ID() != snap.Hash {
+			t.Fatalf("chain not restored, topoheight %d expected %d", chain.Load_TOPO_HEIGHT(), snap.TopoHeight)
+		}
+		if chain.Get_Clock_Skew() != 0 {
+			t.Fatalf("clock skew not restored %s", chain.Get_Clock_Skew())
+		}
+		if balance, _ := wgenesis.Get_Balance(); balance != snap_balance {
+			t.Fatalf("wallet balance not restored, expected %d actual %d", snap_balance, balance)
+		}
+		if err = mine_blocks(chain, 2); err != nil {
+			t.Fatalf("mining after restore failed err %s", err)
+		}
+		if chain.Load_TOPO_HEIGHT() != snap.TopoHeight+2 {
+			t.Fatalf("expected topoheight %d actual %d", snap.TopoHeight+2, chain.Load_TOPO_HEIGHT())
+		}
+	}
+}
+
+func Test_Wallet_Seed(t *testing.T) {
+	defer delete(globals.Arguments, "--seed")
+	if globals.Arguments == nil {
+		globals.Arguments = map[string]interface{}{}
+	}
+
+	delete(globals.Arguments, "--seed")
+	if wallet_seed(1, wallets_seeds[0]) != wallets_seeds[0] {
+		t.Fatalf("fixed seed must be used without --seed")
+	}
+
+	globals.Arguments["--seed"] = "bug-1234"
+	a, b := wallet_seed(1, wallets_seeds[0]), wallet_seed(1, wallets_seeds[0])
+	if a != b || a == wallets_seeds[0] || len(a) != 64 {
+		t.Fatalf("seeded keys are not deterministic %s %s", a, b)
+	}
+	if wallet_seed(2, wallets_seeds[1]) == a {
+		t.Fatalf("each wallet must get a different key")
+	}
+}
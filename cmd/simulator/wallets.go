@@ -91,7 +91,7 @@ func create_wallet(name string, seed string) (wallet *walletapi.Wallet_Disk) {
 }
 
 func create_genesis_wallet() {
-	genesis_wallet = create_wallet("genesis", genesis_seed)
+	genesis_wallet = create_wallet("genesis", wallet_seed(0, genesis_seed))
 	fix_startup() // fixup genesis
 }
 
@@ -110,7 +110,7 @@ func fix_startup() {
 // genesis wallet already exists, register other wallet by sending registratuin tx, then mining them
 func register_wallets(chain *blockchain.Blockchain) {
 	for i := range wallets_seeds {
-		wallets = append(wallets, create_wallet(fmt.Sprintf("wallet_%d.db", i), wallets_seeds[i]))
+		wallets = append(wallets, create_wallet(fmt.Sprintf("wallet_%d.db", i), wallet_seed(i+1, wallets_seeds[i])))
 	}
 	for i := range wallets { // first register wallets
 		err := chain.Add_TX_To_Pool(wallets[i].GetRegistrationTX())
@@ -125,7 +125,7 @@ func register_wallets(chain *blockchain.Blockchain) {
 		if r, err := rpcserver.RPCServer_Start(wallets[i], fmt.Sprintf("wallet_%d", i)); err != nil {
 			logger.Error(err, "Error starting rpc server")
 		} else {
-			logger.Info(fmt.Sprintf("wallet %d", i), "seed", wallets_seeds[i])
+			logger.Info(fmt.Sprintf("wallet %d", i), "seed", wallet_seed(i+1, wallets_seeds[i]))
 			wallets_rpcservers = append(wallets_rpcservers, r)
 		}
 		time.Sleep(17 * time.Millisecond) // enough delay to start a go routine
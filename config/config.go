@@ -16,6 +16,7 @@
 
 package config
 
+import "math"
 import "github.com/satori/go.uuid"
 
 //import "github.com/caarlos0/env/v6"
@@ -90,6 +91,11 @@ type CHAIN_CONFIG struct {
 	HF2_HEIGHT       int64 // second HF applie here
 	MAJOR_HF2_HEIGHT int64 // MAJOR HF2 applies here, changes pow
 
+	// from this height, every single tip block commits to the state root of its tip in Proof
+	// light wallets verify balances against it, older blocks are not checked
+	// simulator chains commit from genesis irrespective of this
+	STATE_ROOT_HEIGHT int64
+
 	Dev_Address        string // to which address the integrator rewatd will go, if user doesn't specify integrator address'
 	Genesis_Tx         string
 	Genesis_Block_Hash crypto.Hash
@@ -104,6 +110,7 @@ var Mainnet = CHAIN_CONFIG{Name: "mainnet",
 	HF1_HEIGHT:              21480,
 	HF2_HEIGHT:              29000,
 	MAJOR_HF2_HEIGHT:        481600,
+	STATE_ROOT_HEIGHT:       math.MaxInt64, // not scheduled yet, will be set when the hard fork is announced
 
 	Genesis_Tx: "" +
 		"01" + // version
@@ -126,6 +133,8 @@ var Testnet = CHAIN_CONFIG{Name: "testnet", // testnet will always have last 3 b
 	HF2_HEIGHT:       0, // on testnet apply at genesis
 	MAJOR_HF2_HEIGHT: 4, // on testnet apply at 4
 
+	STATE_ROOT_HEIGHT: math.MaxInt64, // not scheduled yet, existing testnet blocks carry no state root
+
 	Genesis_Tx: "" +
 		"01" + // version
 		"00" + // Source is DERO network
@@ -203,6 +203,7 @@ func Initialize() {
 		Dialer, err = proxy.FromURL(uri, proxy.Direct)
 		if err != nil {
 			Logger.Error(err, "Error creating socks proxy", "address", Arguments["--socks-proxy"].(string))
+			os.Exit(-1)
 		}
 	}
 
@@ -0,0 +1,283 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+/* this file implements bandwidth accounting and limits
+ * every frame read or written is accounted per peer and globally
+ * uploads and downloads can be capped globally and per peer, limits are token buckets with 1 sec burst
+ * when upload is constrained, block/miniblock propagation goes ahead of bootstrap traffic
+ */
+import "fmt"
+import "sync"
+import "time"
+import "strings"
+import "sync/atomic"
+
+import "github.com/dustin/go-humanize"
+
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/metrics"
+
+const BW_SMALL_FRAME = 4 * 1024 // frames smaller than this are never delayed, so pings and notifications keep flowing
+
+// traffic classes
+const (
+	BW_CLASS_CONTROL     = iota // handshake, ping etc
+	BW_CLASS_PROPAGATION        // blocks, miniblocks, chunks, txs
+	BW_CLASS_BOOTSTRAP          // tree sections, changesets, chain requests
+)
+
+var bw_class_names = []string{"control", "propagation", "bootstrap"}
+
+// classifies traffic by rpc method
+func bw_class(method string) int {
+	switch method {
+	case "Peer.NotifyINV", "Peer.NotifyMiniBlock", "Peer.GetObject":
+		return BW_CLASS_PROPAGATION
+	case "Peer.TreeSection", "Peer.ChangeSet", "Peer.Chain":
+		return BW_CLASS_BOOTSTRAP
+	}
+	return BW_CLASS_CONTROL
+}
+
+// frame size histogram buckets
+var bw_buckets = []int{1024, 16 * 1024, 256 * 1024}
+var bw_bucket_names = []string{"<1K", "<16K", "<256K", ">=256K"}
+
+func bw_bucket(n int) int {
+	for i := range bw_buckets {
+		if n < bw_buckets[i] {
+			return i
+		}
+	}
+	return len(bw_buckets)
+}
+
+type rate_limiter struct {
+	sync.Mutex
+	rate    int64 // bytes per second, 0 means unlimited
+	tokens  float64
+	last    time.Time
+	waiting int32 // priority transfers waiting
+}
+
+func (l *rate_limiter) set_rate(rate int64) {
+	l.Lock()
+	defer l.Unlock()
+	if rate < 0 {
+		rate = 0
+	}
+	l.rate = rate
+	l.tokens = float64(rate)
+	l.last = time.Now()
+}
+
+func (l *rate_limiter) get_rate() int64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.rate
+}
+
+// reserves n bytes, returns how long caller must wait before retrying, 0 means go ahead
+// transfers may go into debt, so large frames are never starved
+func (l *rate_limiter) reserve(n int, priority bool, now time.Time) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if l.tokens > float64(l.rate) { // burst is limited to 1 sec
+		l.tokens = float64(l.rate)
+	}
+	l.last = now
+
+	if n < BW_SMALL_FRAME { // accounted but never delayed
+		l.tokens -= float64(n)
+		return 0
+	}
+	if !priority && atomic.LoadInt32(&l.waiting) > 0 { // yield to priority traffic
+		return 10 * time.Millisecond
+	}
+	if l.tokens > 0 {
+		l.tokens -= float64(n)
+		return 0
+	}
+	return time.Duration(-l.tokens/float64(l.rate)*float64(time.Second)) + time.Millisecond
+}
+
+// waits till n bytes can be transferred
+func (l *rate_limiter) wait(n int, priority bool) {
+	if priority {
+		atomic.AddInt32(&l.waiting, 1)
+		defer atomic.AddInt32(&l.waiting, -1)
+	}
+	for {
+		d := l.reserve(n, priority, time.Now())
+		if d == 0 {
+			return
+		}
+		time.Sleep(d)
+	}
+}
+
+var bw_upload, bw_download rate_limiter    // global limits
+var bw_peer_upload, bw_peer_download int64 // per peer limits in bytes per second
+
+// per peer bandwidth accounting
+type peer_bandwidth struct {
+	BytesIn    uint64
+	BytesOut   uint64
+	FramesIn   [4]uint64 // histogram of frame sizes, see bw_bucket_names
+	FramesOut  [4]uint64
+	ClassOut   [3]uint64 // bytes sent per traffic class
+	ip         string
+	upload     rate_limiter
+	download   rate_limiter
+	registered bool // only the first connection from an ip exports per peer metrics
+}
+
+// per peer metrics are labelled with ip only, same as connections are keyed, so series of a peer can be joined
+func peer_metric(name, ip string) string {
+	return fmt.Sprintf(`%s{peer=%q}`, name, ip)
+}
+
+var bw_peers = map[string]*peer_bandwidth{} // only used to export per peer metrics
+var bw_peers_mutex sync.Mutex
+
+func new_peer_bandwidth(ip string) *peer_bandwidth {
+	bw := &peer_bandwidth{ip: ip}
+	bw.upload.set_rate(atomic.LoadInt64(&bw_peer_upload))
+	bw.download.set_rate(atomic.LoadInt64(&bw_peer_download))
+	if ip != "" {
+		bw_peers_mutex.Lock()
+		if _, ok := bw_peers[ip]; !ok { // first connection from this ip exports metrics
+			bw_peers[ip] = bw
+			bw.registered = true
+			metrics.Set.GetOrCreateCounter(peer_metric("p2p_peer_bytes_in_total", ip))
+			metrics.Set.GetOrCreateCounter(peer_metric("p2p_peer_bytes_out_total", ip))
+		}
+		bw_peers_mutex.Unlock()
+	}
+	return bw
+}
+
+// called when connection is closed
+func (bw *peer_bandwidth) close() {
+	if !bw.registered {
+		return
+	}
+	bw_peers_mutex.Lock()
+	defer bw_peers_mutex.Unlock()
+	if bw_peers[bw.ip] == bw {
+		delete(bw_peers, bw.ip)
+		metrics.Set.UnregisterMetric(peer_metric("p2p_peer_bytes_in_total", bw.ip))
+		metrics.Set.UnregisterMetric(peer_metric("p2p_peer_bytes_out_total", bw.ip))
+	}
+}
+
+// waits for upload capacity and accounts the frames
+func (bw *peer_bandwidth) before_write(method string, n int) {
+	class := bw_class(method)
+	priority := class != BW_CLASS_BOOTSTRAP
+	bw.upload.wait(n, priority)
+	bw_upload.wait(n, priority)
+
+	atomic.AddUint64(&bw.BytesOut, uint64(n))
+	if bw.registered {
+		metrics.Set.GetOrCreateCounter(peer_metric("p2p_peer_bytes_out_total", bw.ip)).Add(n)
+	}
+	atomic.AddUint64(&bw.FramesOut[bw_bucket(n)], 1)
+	atomic.AddUint64(&bw.ClassOut[class], uint64(n))
+	metrics.Set.GetOrCreateCounter(fmt.Sprintf(`p2p_bytes_out_total{class=%q}`, bw_class_names[class])).Add(n)
+	metrics.Set.GetOrCreateHistogram("p2p_frame_out_size_bytes").Update(float64(n))
+}
+
+// waits for download capacity before a frame body is read, so the sender is slowed down by tcp flow control
+// frames queued behind are not held up by a sleep after the data has already arrived
+func (bw *peer_bandwidth) before_read(n int) {
+	bw.download.wait(n, true)
+	bw_download.wait(n, true)
+}
+
+// accounts a frame which has been read
+func (bw *peer_bandwidth) after_read(n int) {
+	atomic.AddUint64(&bw.BytesIn, uint64(n))
+	if bw.registered {
+		metrics.Set.GetOrCreateCounter(peer_metric("p2p_peer_bytes_in_total", bw.ip)).Add(n)
+	}
+	atomic.AddUint64(&bw.FramesIn[bw_bucket(n)], 1)
+	metrics.Set.GetOrCreateCounter("p2p_bytes_in_total").Add(n)
+	metrics.Set.GetOrCreateHistogram("p2p_frame_in_size_bytes").Update(float64(n))
+}
+
+// sets limits in KB/s, 0 means unlimited, per peer limits apply to new connections
+func SetBandwidthLimits(upload, download, peer_upload, peer_download int64) {
+	bw_upload.set_rate(upload * 1024)
+	bw_download.set_rate(download * 1024)
+	atomic.StoreInt64(&bw_peer_upload, peer_upload*1024)
+	atomic.StoreInt64(&bw_peer_download, peer_download*1024)
+}
+
+// parse bandwidth limits from command line
+func parse_bandwidth_limits() error {
+	var limits [4]int64
+	for i, option := range []string{"--p2p-upload-limit", "--p2p-download-limit", "--p2p-peer-upload-limit", "--p2p-peer-download-limit"} {
+		if s, ok := globals.Arguments[option].(string); ok {
+			if _, err := fmt.Sscan(s, &limits[i]); err != nil || limits[i] < 0 {
+				return fmt.Errorf("%s is invalid, it must be KB/s", option)
+			}
+		}
+	}
+	SetBandwidthLimits(limits[0], limits[1], limits[2], limits[3])
+	return nil
+}
+
+// prints per peer bandwidth, part of syncinfo
+func bandwidth_print(clist []*Connection) {
+	fmt.Printf("\nBandwidth limits upload %s/s download %s/s per peer upload %s/s download %s/s (0 is unlimited)\n", humanize.Bytes(uint64(bw_upload.get_rate())),
+		humanize.Bytes(uint64(bw_download.get_rate())), humanize.Bytes(uint64(atomic.LoadInt64(&bw_peer_upload))), humanize.Bytes(uint64(atomic.LoadInt64(&bw_peer_download))))
+	fmt.Printf("%-22s %9s %9s %11s %9s   %-24s %-24s\n", "Remote Addr", "In", "Out", "Propagation", "Bootstrap", "Frames In", "Frames Out")
+	for _, c := range clist {
+		bw := c.bandwidth
+		if bw == nil {
+			continue
+		}
+		fmt.Printf("%-22s %9s %9s %11s %9s   %-24s %-24s\n", Address(c), humanize.Bytes(atomic.LoadUint64(&bw.BytesIn)), humanize.Bytes(atomic.LoadUint64(&bw.BytesOut)),
+			humanize.Bytes(atomic.LoadUint64(&bw.ClassOut[BW_CLASS_PROPAGATION])), humanize.Bytes(atomic.LoadUint64(&bw.ClassOut[BW_CLASS_BOOTSTRAP])),
+			format_histogram(&bw.FramesIn), format_histogram(&bw.FramesOut))
+	}
+	fmt.Printf("Frame sizes are %s\n", strings.Join(bw_bucket_names, "/"))
+}
+
+func format_histogram(h *[4]uint64) string {
+	return fmt.Sprintf("%d/%d/%d/%d", atomic.LoadUint64(&h[0]), atomic.LoadUint64(&h[1]), atomic.LoadUint64(&h[2]), atomic.LoadUint64(&h[3]))
+}
+
+func (c *Connection) bytes_in() uint64 {
+	if c.bandwidth == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.bandwidth.BytesIn)
+}
+
+func (c *Connection) bytes_out() uint64 {
+	if c.bandwidth == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.bandwidth.BytesOut)
+}
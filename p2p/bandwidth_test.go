@@ -0,0 +1,104 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "testing"
+import "net"
+import "time"
+import "sync/atomic"
+
+func Test_Bandwidth_Class(t *testing.T) {
+	if bw_class("Peer.NotifyMiniBlock") != BW_CLASS_PROPAGATION || bw_class("Peer.NotifyINV") != BW_CLASS_PROPAGATION {
+		t.Fatalf("block propagation must be prioritized")
+	}
+	if bw_class("Peer.TreeSection") != BW_CLASS_BOOTSTRAP || bw_class("Peer.Chain") != BW_CLASS_BOOTSTRAP {
+		t.Fatalf("bootstrap traffic must not be prioritized")
+	}
+	if bw_class("Peer.Ping") != BW_CLASS_CONTROL {
+		t.Fatalf("ping is control traffic")
+	}
+
+	if bw_bucket(0) != 0 || bw_bucket(1023) != 0 || bw_bucket(1024) != 1 || bw_bucket(1<<30) != len(bw_buckets) {
+		t.Fatalf("histogram buckets incorrect")
+	}
+}
+
+func Test_Rate_Limiter(t *testing.T) {
+	var l rate_limiter
+	now := time.Now()
+	if l.reserve(1<<20, false, now) != 0 {
+		t.Fatalf("unlimited limiter must never delay")
+	}
+
+	l.set_rate(100 * 1024)
+	l.last = now
+
+	if l.reserve(100, false, now) != 0 {
+		t.Fatalf("small frames must never be delayed")
+	}
+	if l.reserve(200*1024, true, now) != 0 { // goes into debt
+		t.Fatalf("first large frame must go through")
+	}
+	d := l.reserve(10*1024, true, now)
+	if d < 900*time.Millisecond || d > 1200*time.Millisecond {
+		t.Fatalf("expected about 1 sec wait got %s", d)
+	}
+	if l.reserve(100, false, now) != 0 {
+		t.Fatalf("small frames must never be delayed even in debt")
+	}
+
+	l.set_rate(100 * 1024)
+	l.last = now
+	l.waiting = 1
+	if l.reserve(10*1024, false, now) == 0 {
+		t.Fatalf("bulk traffic must yield to waiting priority traffic")
+	}
+	if l.reserve(10*1024, true, now) != 0 {
+		t.Fatalf("priority traffic must go through")
+	}
+}
+
+// download limit is applied before the frame body is read, so the sender is held back instead of the read loop
+func Test_Download_Limit_Before_Read(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	var written int32
+	go func() {
+		Write_Data_Frame(client, RequestResponse{Method: "Peer.Ping", Seq: 7})
+		atomic.StoreInt32(&written, 1)
+	}()
+
+	limited := 0
+	limit := func(n int) {
+		time.Sleep(50 * time.Millisecond)
+		if atomic.LoadInt32(&written) != 0 {
+			t.Errorf("frame body was read before waiting for download capacity")
+		}
+		limited = n
+	}
+
+	var header RequestResponse
+	n, err := read_data_frame(server, &header, limit)
+	if err != nil {
+		t.Fatalf("cannot read frame err %s", err)
+	}
+	if limited != n || header.Seq != 7 {
+		t.Fatalf("limit must be applied to the whole frame, limited %d read %d header %+v", limited, n, header)
+	}
+}
@@ -115,6 +115,7 @@ func ban_clean_up_goroutine() {
 		case <-delay.C:
 		}
 		ban_clean_up()
+		score_clean_up()
 	}
 
 }
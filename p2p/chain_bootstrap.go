@@ -44,6 +44,7 @@ import "github.com/deroproject/derohe/cryptography/crypto"
 // resume may be implemented in future
 func (connection *Connection) bootstrap_chain() {
 	defer handle_connection_panic(connection)
+	chain := connection.node.chain
 	var request ChangeList
 	var response Changes
 	// var err error
@@ -70,7 +71,7 @@ func (connection *Connection) bootstrap_chain() {
 		request.TopoHeights = append(request.TopoHeights, topos[i])
 	}
 
-	fill_common(&request.Common) // fill common info
+	connection.node.fill_common(&request.Common) // fill common info
 	if err := connection.Client.Call("Peer.ChangeSet", request, &response); err != nil {
 		connection.logger.V(1).Error(err, "Call failed ChangeSet")
 		return
@@ -104,7 +105,7 @@ func (connection *Connection) bootstrap_chain() {
 			binary.BigEndian.PutUint64(section[:], bits.Reverse64(uint64(i))) // place reverse path
 			ts_request := Request_Tree_Section_Struct{Topo: request.TopoHeights[0], TreeName: []byte(config.BALANCE_TREE), Section: section[:], SectionLength: uint64(path_length)}
 			var ts_response Response_Tree_Section_Struct
-			fill_common(&ts_request.Common)
+			connection.node.fill_common(&ts_request.Common)
 			if err := connection.Client.Call("Peer.TreeSection", ts_request, &ts_response); err != nil {
 				connection.logger.V(1).Error(err, "Call failed TreeSection")
 				return
@@ -119,6 +120,7 @@ func (connection *Connection) bootstrap_chain() {
 
 				if len(ts_response.Keys) != len(ts_response.Values) {
 					//rlog.Warnf("Incoming Key count %d value count %d \"%s\" ", len(ts_response.Keys), len(ts_response.Values), globals.CTXString(connection.logger))
+					connection.score(SCORE_BAD_TREESECTION, "tree section key/value mismatch")
 					connection.exit()
 					return
 				}
@@ -168,7 +170,7 @@ func (connection *Connection) bootstrap_chain() {
 			binary.BigEndian.PutUint64(section[:], bits.Reverse64(uint64(i))) // place reverse path
 			ts_request := Request_Tree_Section_Struct{Topo: request.TopoHeights[0], TreeName: []byte(config.SC_META), Section: section[:], SectionLength: uint64(path_length)}
 			var ts_response Response_Tree_Section_Struct
-			fill_common(&ts_request.Common)
+			connection.node.fill_common(&ts_request.Common)
 			if err := connection.Client.Call("Peer.TreeSection", ts_request, &ts_response); err != nil {
 				connection.logger.V(1).Error(err, "Call failed TreeSection")
 				return
@@ -186,6 +188,7 @@ func (connection *Connection) bootstrap_chain() {
 
 				if len(ts_response.Keys) != len(ts_response.Values) {
 					//rlog.Warnf("Incoming Key count %d value count %d \"%s\" ", len(ts_response.Keys), len(ts_response.Values), globals.CTXString(connection.logger))
+					connection.score(SCORE_BAD_TREESECTION, "tree section key/value mismatch")
 					connection.exit()
 					return
 				}
@@ -198,7 +201,7 @@ func (connection *Connection) bootstrap_chain() {
 
 					sc_request := Request_Tree_Section_Struct{Topo: request.TopoHeights[0], TreeName: ts_response.Keys[j], Section: section[:], SectionLength: uint64(0)}
 					var sc_response Response_Tree_Section_Struct
-					fill_common(&sc_request.Common)
+					connection.node.fill_common(&sc_request.Common)
 					if err := connection.Client.Call("Peer.TreeSection", sc_request, &sc_response); err != nil {
 						connection.logger.V(1).Error(err, "Call failed TreeSection")
 						return
@@ -230,7 +233,7 @@ func (connection *Connection) bootstrap_chain() {
 								binary.BigEndian.PutUint64(sc_section[:], bits.Reverse64(uint64(k))) // place reverse path
 								sc_ts_request := Request_Tree_Section_Struct{Topo: request.TopoHeights[0], TreeName: ts_response.Keys[j], Section: sc_section[:], SectionLength: uint64(sc_path_length)}
 								var sc_ts_response Response_Tree_Section_Struct
-								fill_common(&sc_ts_request.Common)
+								connection.node.fill_common(&sc_ts_request.Common)
 								if err := connection.Client.Call("Peer.TreeSection", sc_ts_request, &sc_ts_response); err != nil {
 									connection.logger.V(1).Error(err, "Call failed TreeSection")
 									return
@@ -238,6 +241,7 @@ func (connection *Connection) bootstrap_chain() {
 
 									if len(sc_ts_response.Keys) != len(sc_ts_response.Values) {
 										connection.logger.V(1).Error(nil, "Wrong key/values", "Keycount", len(sc_ts_response.Keys), "valuecount", len(sc_ts_response.Values))
+										connection.score(SCORE_BAD_TREESECTION, "tree section key/value mismatch")
 										connection.exit()
 										return
 									}
@@ -285,6 +289,7 @@ func (connection *Connection) bootstrap_chain() {
 		err := bl.Deserialize(response.CBlocks[i].Block)
 		if err != nil { // we have a block which could not be deserialized ban peer
 			connection.logger.Error(err, "Error Incoming block could not be deserialised.")
+			connection.score(SCORE_INVALID_BLOCK, "undecodable block")
 			connection.exit()
 			return
 		}
@@ -303,11 +308,13 @@ func (connection *Connection) bootstrap_chain() {
 			err = tx.Deserialize(response.CBlocks[i].Txs[j])
 			if err != nil { // we have a tx which could not be deserialized ban peer
 				connection.logger.Error(err, "Error Incoming TX could not be deserialized")
+				connection.score(SCORE_INVALID_BLOCK, "undecodable tx")
 				connection.exit()
 				return
 			}
 			if bl.Tx_hashes[j] != tx.GetHash() {
 				connection.logger.Error(err, "Error Incoming TX has mismatch.")
+				connection.score(SCORE_INVALID_BLOCK, "tx mismatch")
 				connection.exit()
 				return
 			}
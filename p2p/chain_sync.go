@@ -17,6 +17,7 @@
 package p2p
 
 import "fmt"
+import "errors"
 import "time"
 import "math/big"
 import "sync/atomic"
@@ -111,6 +112,7 @@ func ConvertCBlock_To_CompleteBlock(cblock Complete_Block) (cbl block.Complete_B
 func (connection *Connection) sync_chain() {
 
 	defer handle_connection_panic(connection)
+	chain := connection.node.chain
 	atomic.AddInt32(&connection.Syncing, 1)
 	defer atomic.AddInt32(&connection.Syncing, -1)
 
@@ -152,7 +154,7 @@ try_again:
 	// add genesis block at the end
 	request.Block_list = append(request.Block_list, globals.Config.Genesis_Block_Hash)
 	request.TopoHeights = append(request.TopoHeights, 0)
-	fill_common(&request.Common) // fill common info
+	connection.node.fill_common(&request.Common) // fill common info
 
 	if err := connection.Client.Call("Peer.Chain", request, &response); err != nil {
 		connection.logger.V(2).Error(err, "Call failed Chain")
@@ -220,7 +222,7 @@ try_again:
 
 				//fmt.Printf("inserting blocks %d %x\n", (int64(i) + response.Start_topoheight), response.Block_list[i][:])
 				orequest.Block_list = append(orequest.Block_list, response.Block_list[i])
-				fill_common(&orequest.Common)
+				connection.node.fill_common(&orequest.Common)
 				if err := connection.Client.Call("Peer.GetObject", orequest, &oresponse); err != nil {
 					connection.logger.V(2).Error(err, "Call failed GetObject")
 					return
@@ -268,7 +270,7 @@ try_again:
 				var oresponse Objects
 
 				orequest.Block_list = append(orequest.Block_list, response.Block_list[i])
-				fill_common(&orequest.Common)
+				connection.node.fill_common(&orequest.Common)
 				if err := connection.Client.Call("Peer.GetObject", orequest, &oresponse); err != nil {
 					connection.logger.V(2).Error(err, "Call failed GetObject")
 					return
@@ -300,6 +302,7 @@ try_again:
 }
 
 func (connection *Connection) process_object_response(response Objects, sent int64, syncing bool) error {
+	chain := connection.node.chain
 	var err error
 	defer globals.Recover(2)
 
@@ -315,6 +318,7 @@ func (connection *Connection) process_object_response(response Objects, sent int
 		err := bl.Deserialize(response.CBlocks[i].Block)
 		if err != nil { // we have a block which could not be deserialized ban peer
 			connection.logger.V(2).Error(err, "Incoming block could not be deserilised")
+			connection.score(SCORE_INVALID_BLOCK, "undecodable block")
 			connection.exit()
 			if syncing {
 				return nil
@@ -338,6 +342,7 @@ func (connection *Connection) process_object_response(response Objects, sent int
 			err = tx.Deserialize(response.CBlocks[i].Txs[j])
 			if err != nil { // we have a tx which could not be deserialized ban peer
 				connection.logger.V(2).Error(err, "Incoming TX could not be deserilised")
+				connection.score(SCORE_INVALID_BLOCK, "undecodable tx")
 				connection.exit()
 
 				if syncing {
@@ -353,6 +358,7 @@ func (connection *Connection) process_object_response(response Objects, sent int
 		err, ok := chain.Add_Complete_Block(&cbl)
 		if !ok && err == errormsg.ErrInvalidPoW {
 			connection.logger.V(2).Error(err, "This peer should be banned")
+			connection.score(SCORE_INVALID_BLOCK, "invalid pow")
 			connection.exit()
 			if syncing {
 				return nil
@@ -384,6 +390,7 @@ func (connection *Connection) process_object_response(response Objects, sent int
 		err = tx.Deserialize(response.Txs[i])
 		if err != nil { // we have a tx which could not be deserialized ban peer
 			connection.logger.V(2).Error(err, "Incoming TX could not be deserilised")
+			connection.score(SCORE_INVALID_BLOCK, "undecodable tx")
 			connection.exit()
 
 			return nil
@@ -397,13 +404,19 @@ func (connection *Connection) process_object_response(response Objects, sent int
 
 		if !chain.Mempool.Mempool_TX_Exist(tx.GetHash()) { // we still donot have it, so try to process it
 			if chain.Add_TX_To_Pool(&tx) == nil { // currently we are ignoring error
-				broadcast_Tx(&tx, 0, sent)
+				connection.node.broadcast_Tx(&tx, 0, sent)
 			}
 		}
 	}
 
 	for i := range response.Chunks { // process incoming chunks
-		connection.feed_chunk(&response.Chunks[i], sent)
+		if err := connection.feed_chunk(&response.Chunks[i], sent); err == errormsg.ErrInvalidPoW {
+			connection.score(SCORE_INVALID_BLOCK, "chunk with invalid pow")
+		} else if errors.Is(err, errCorruptedChunk) { // only penalize what is provable
+			connection.score(SCORE_INVALID_CHUNK, err.Error())
+		} else if err != nil {
+			connection.logger.V(2).Info("chunk ignored", "err", err)
+		}
 	}
 
 	return nil
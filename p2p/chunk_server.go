@@ -2,6 +2,7 @@ package p2p
 
 // this file implements incoming chunk processor
 import "fmt"
+import "errors"
 
 import "time"
 import "sync"
@@ -18,13 +19,12 @@ import "github.com/deroproject/derohe/config"
 import "github.com/deroproject/derohe/metrics"
 import "github.com/deroproject/derohe/cryptography/crypto"
 
-var chunk_map sync.Map // key is blid, value is pointer to  Chunks_Per_Block_Data
-
-var chunk_lock sync.Mutex
-var single_construction sync.Mutex // used to single threaded processing while reconstructing blocks
-
 const MAX_CHUNKS uint8 = 255
 
+// chunk is inconsistent with its own hashes, this can only be sent by a misbehaving peer
+// all other errors are parameter/validation errors, which honest peers may trigger
+var errCorruptedChunk = errors.New("Corrupted Chunk")
+
 type Chunks_Per_Block_Data struct {
 	ChunkCollection [MAX_CHUNKS]*Block_Chunk // nil means we donot have the chunk
 	Created         time.Time                // when was this structure allocated
@@ -36,19 +36,19 @@ type Chunks_Per_Block_Data struct {
 }
 
 // cleans up chunks every minute
-func chunks_clean_up() {
-	chunk_map.Range(func(key, value interface{}) bool {
+func (n *Node) chunks_clean_up() {
+	n.chunk_map.Range(func(key, value interface{}) bool {
 		chunks_per_block := value.(*Chunks_Per_Block_Data)
 		if time.Now().Sub(chunks_per_block.Created) > time.Second*180 {
-			chunk_map.Delete(key)
+			n.chunk_map.Delete(key)
 		}
 		return true
 	})
 }
 
 // return whether chunk exist
-func is_chunk_exist(hhash [32]byte, cid uint8) *Block_Chunk {
-	chunksi, ok := chunk_map.Load(fmt.Sprintf("%x", hhash))
+func (n *Node) is_chunk_exist(hhash [32]byte, cid uint8) *Block_Chunk {
+	chunksi, ok := n.chunk_map.Load(fmt.Sprintf("%x", hhash))
 	if !ok {
 		//debug.PrintStack()
 		return nil
@@ -59,21 +59,22 @@ func is_chunk_exist(hhash [32]byte, cid uint8) *Block_Chunk {
 
 // feed a chunk until we are able to fully decode a chunk
 func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
+	n := connection.node
 
-	chunk_lock.Lock()
-	defer chunk_lock.Unlock()
+	n.chunk_lock.Lock()
+	defer n.chunk_lock.Unlock()
 
 	if chunk.HHash != chunk.HeaderHash() {
 		connection.logger.V(2).Info("This peer should be banned, since he supplied wrong chunk")
 		connection.exit()
-		return fmt.Errorf("Corrupted Chunk")
+		return errCorruptedChunk
 	}
 
 	if chunk.CHUNK_COUNT > uint(MAX_CHUNKS) || chunk.CHUNK_NEED > chunk.CHUNK_COUNT {
 		return fmt.Errorf("Invalid Chunk Count")
 	}
 	if chunk.CHUNK_COUNT != uint(len(chunk.CHUNK_HASH)) {
-		return fmt.Errorf("Corrupted Chunk")
+		return errCorruptedChunk
 	}
 
 	if chunk.CHUNK_ID >= chunk.CHUNK_COUNT {
@@ -93,15 +94,15 @@ func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
 	}
 
 	if chunk.CHUNK_HASH[chunk.CHUNK_ID] != crypto.Keccak256_64(chunk.CHUNK_DATA) { // chunk data corrupt
-		return fmt.Errorf("Corrupted Chunk")
+		return errCorruptedChunk
 	}
 
-	if nil != is_chunk_exist(chunk.HHash, uint8(chunk.CHUNK_ID)) { // chunk already exists return
+	if nil != n.is_chunk_exist(chunk.HHash, uint8(chunk.CHUNK_ID)) { // chunk already exists return
 		return nil
 	}
 
 	chunks_per_block := new(Chunks_Per_Block_Data)
-	if chunksi, ok := chunk_map.LoadOrStore(fmt.Sprintf("%x", chunk.HHash), chunks_per_block); ok {
+	if chunksi, ok := n.chunk_map.LoadOrStore(fmt.Sprintf("%x", chunk.HHash), chunks_per_block); ok {
 		chunks_per_block = chunksi.(*Chunks_Per_Block_Data)
 
 		// make sure we are matching what is stored already
@@ -132,11 +133,11 @@ func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
 			return nil
 		}
 		if bl.GetHash() != chunk.BLID {
-			return fmt.Errorf("Corrupted Chunk. bad block data")
+			return fmt.Errorf("%w. bad block data", errCorruptedChunk)
 		}
 
 		// we must check the Pow now
-		if int64(bl.Height) >= chain.Get_Height()-3 && int64(bl.Height) <= chain.Get_Height()+3 {
+		if int64(bl.Height) >= n.chain.Get_Height()-3 && int64(bl.Height) <= n.chain.Get_Height()+3 {
 
 		} else {
 			return nil // we need not broadcast
@@ -147,7 +148,7 @@ func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
 		}
 
 		for _, mbl := range bl.MiniBlocks {
-			if !chain.VerifyMiniblockPoW(&bl, mbl) {
+			if !n.chain.VerifyMiniblockPoW(&bl, mbl) {
 				return errormsg.ErrInvalidPoW
 			}
 		}
@@ -164,7 +165,7 @@ func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
 
 	if chunks_per_block.ChunkCollection[chunk.CHUNK_ID] == nil {
 		chunks_per_block.ChunkCollection[chunk.CHUNK_ID] = chunk
-		broadcast_Chunk(chunk, 0, sent) // broadcast chunk INV
+		n.broadcast_Chunk(chunk, 0, sent) // broadcast chunk INV
 	}
 
 	chunk_count := 0
@@ -176,41 +177,14 @@ func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
 
 	logger.V(3).Info("Have  chunks", "have", chunk_count, "total", chunk.CHUNK_COUNT, "tx_count", len(chunks_per_block.bl.Tx_hashes))
 
-	var cbl Complete_Block
-	cbl.Block = chunk.BLOCK
-	if len(chunks_per_block.bl.Tx_hashes) >= 1 { // if txs are present, then we need to join chunks, else we are already done
-
-		if uint(chunk_count) < chunk.CHUNK_NEED { // we do not have enough chunks
-			return nil
-		}
-
-		var shards [][]byte
-		for i := 0; i < int(chunk.CHUNK_COUNT); i++ {
-			if chunks_per_block.ChunkCollection[i] == nil {
-				shards = append(shards, nil)
-			} else {
-				shards = append(shards, chunks_per_block.ChunkCollection[i].CHUNK_DATA)
-			}
-		}
-
-		enc, _ := reedsolomon.New(int(chunk.CHUNK_NEED), int(chunk.CHUNK_COUNT-chunk.CHUNK_NEED))
-
-		if err := enc.Reconstruct(shards); err != nil {
-			logger.V(3).Error(err, "error reconstructing data ")
-			return nil
-		}
-
-		var writer bytes.Buffer
-
-		if err := enc.Join(&writer, shards, int(chunk.DSIZE)); err != nil {
-			logger.V(1).Error(err, "error joining data")
-			return nil
-		}
+	if len(chunks_per_block.bl.Tx_hashes) >= 1 && uint(chunk_count) < chunk.CHUNK_NEED { // if txs are present, then we need to join chunks, else we are already done
+		return nil // we do not have enough chunks
+	}
 
-		if err := cbor.Unmarshal(writer.Bytes(), &cbl); err != nil {
-			logger.V(1).Error(err, "error deserializing txset")
-			return nil
-		}
+	cbl, err := Join_Chunks(chunks_per_block.ChunkCollection[:chunk.CHUNK_COUNT])
+	if err != nil {
+		logger.V(1).Error(err, "error reconstructing block from chunks")
+		return nil
 	}
 
 	chunks_per_block.Processed = true // we have successfully reconstructed data,so we give it a try
@@ -233,8 +207,8 @@ func (connection *Connection) feed_chunk(chunk *Block_Chunk, sent int64) error {
 }
 
 // cehck whether we have already chunked this
-func is_already_chunked_by_us(blid crypto.Hash, data_shard_count, parity_shard_count int) (hash [32]byte, chunk_count int) {
-	chunk_map.Range(func(key, value interface{}) bool {
+func (n *Node) is_already_chunked_by_us(blid crypto.Hash, data_shard_count, parity_shard_count int) (hash [32]byte, chunk_count int) {
+	n.chunk_map.Range(func(key, value interface{}) bool {
 
 		chunks_per_block := value.(*Chunks_Per_Block_Data)
 		for _, c := range chunks_per_block.ChunkCollection {
@@ -296,28 +270,14 @@ func Convert_P2PCBL_TO_CBL(input []byte) *block.Complete_Block {
 
 // note we do not send complete block,
 // since other nodes already have most of the mempool, let the mempool be used as much as possible
-func convert_block_to_chunks(cbl *block.Complete_Block, data_shard_count, parity_shard_count int) ([32]byte, int) {
-	var cbor_cbl TXSET
-	bl_serialized := cbl.Bl.Serialize()
+func (n *Node) convert_block_to_chunks(cbl *block.Complete_Block, data_shard_count, parity_shard_count int) ([32]byte, int) {
 	blid := [32]byte(cbl.Bl.GetHash())
 
-	for _, tx := range cbl.Txs {
-		cbor_cbl.Txs = append(cbor_cbl.Txs, tx.Serialize())
-	}
-	if len(cbor_cbl.Txs) != len(cbl.Bl.Tx_hashes) {
-		panic("invalid complete block")
-	}
-
-	cbl_serialized, err := cbor.Marshal(cbor_cbl)
-	if err != nil {
-		panic(err)
-	}
-
 	//if hhash, count := is_already_chunked_by_us(blid, data_shard_count, parity_shard_count); count > 0 {
 	//	return hhash, count
 	//}
 	// loop through all the data chunks and overide from there
-	chunk_map.Range(func(key, value interface{}) bool {
+	n.chunk_map.Range(func(key, value interface{}) bool {
 		chunk := value.(*Chunks_Per_Block_Data)
 		for _, c := range chunk.ChunkCollection {
 			if c != nil && c.BLID == blid {
@@ -332,6 +292,39 @@ func convert_block_to_chunks(cbl *block.Complete_Block, data_shard_count, parity
 		return true
 	})
 
+	chunk := Split_Block_Into_Chunks(cbl, data_shard_count, parity_shard_count)
+
+	chunks := new(Chunks_Per_Block_Data)
+	for i := range chunk {
+		chunks.ChunkCollection[i] = &chunk[i]
+	}
+	chunks.Created = time.Now()
+	chunks.Processed = true
+	chunks.Complete = true
+
+	n.chunk_map.Store(fmt.Sprintf("%x", chunk[0].HHash), chunks)
+	return chunk[0].HeaderHash(), data_shard_count + parity_shard_count
+}
+
+// splits a complete block into erasure coded chunks
+// block header is part of every chunk, txs are spread over the chunks
+func Split_Block_Into_Chunks(cbl *block.Complete_Block, data_shard_count, parity_shard_count int) []Block_Chunk {
+	var cbor_cbl TXSET
+	bl_serialized := cbl.Bl.Serialize()
+	blid := [32]byte(cbl.Bl.GetHash())
+
+	for _, tx := range cbl.Txs {
+		cbor_cbl.Txs = append(cbor_cbl.Txs, tx.Serialize())
+	}
+	if len(cbor_cbl.Txs) != len(cbl.Bl.Tx_hashes) {
+		panic("invalid complete block")
+	}
+
+	cbl_serialized, err := cbor.Marshal(cbor_cbl)
+	if err != nil {
+		panic(err)
+	}
+
 	// we will use a 16 datablocks,32 parity blocks RS code,
 	// if the peer receives any of 16 blocks in any order, they can reconstruct entire block
 
@@ -379,14 +372,59 @@ func convert_block_to_chunks(cbl *block.Complete_Block, data_shard_count, parity
 		}
 
 	}
-	chunks := new(Chunks_Per_Block_Data)
-	for i := 0; i < data_shard_count+parity_shard_count; i++ {
-		chunks.ChunkCollection[i] = &chunk[i]
+	return chunk
+}
+
+// reconstructs a block from its chunks, chunks are indexed by chunk id and missing chunks are nil
+// chunks must already have been verified
+func Join_Chunks(chunks []*Block_Chunk) (cbl Complete_Block, err error) {
+	var first *Block_Chunk
+	chunk_count := 0
+	for _, c := range chunks {
+		if c != nil {
+			if first == nil {
+				first = c
+			}
+			chunk_count++
+		}
+	}
+	if first == nil {
+		return cbl, fmt.Errorf("no chunks")
 	}
-	chunks.Created = time.Now()
-	chunks.Processed = true
-	chunks.Complete = true
 
-	chunk_map.Store(fmt.Sprintf("%x", chunk[0].HHash), chunks)
-	return chunk[0].HeaderHash(), data_shard_count + parity_shard_count
+	var bl block.Block
+	if err = bl.Deserialize(first.BLOCK); err != nil {
+		return
+	}
+	cbl.Block = first.BLOCK
+	if len(bl.Tx_hashes) == 0 { // block has no txs, so header is all we need
+		return
+	}
+
+	if uint(chunk_count) < first.CHUNK_NEED {
+		return cbl, fmt.Errorf("insufficient chunks, have %d need %d", chunk_count, first.CHUNK_NEED)
+	}
+
+	shards := make([][]byte, first.CHUNK_COUNT)
+	for i := range shards {
+		if i < len(chunks) && chunks[i] != nil {
+			shards[i] = chunks[i].CHUNK_DATA
+		}
+	}
+
+	enc, err := reedsolomon.New(int(first.CHUNK_NEED), int(first.CHUNK_COUNT-first.CHUNK_NEED))
+	if err != nil {
+		return
+	}
+	if err = enc.Reconstruct(shards); err != nil {
+		return
+	}
+
+	var writer bytes.Buffer
+	if err = enc.Join(&writer, shards, int(first.DSIZE)); err != nil {
+		return
+	}
+
+	err = cbor.Unmarshal(writer.Bytes(), &cbl)
+	return
 }
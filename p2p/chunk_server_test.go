@@ -0,0 +1,84 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "bytes"
+import "errors"
+import "testing"
+import "encoding/hex"
+
+import "github.com/go-logr/logr"
+
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/transaction"
+
+func Test_Chunks_Split_Join(t *testing.T) {
+	var tx transaction.Transaction
+	tx_bytes, _ := hex.DecodeString(config.Testnet.Genesis_Tx)
+	if err := tx.Deserialize(tx_bytes); err != nil {
+		t.Fatalf("cannot deserialize tx err %s", err)
+	}
+
+	bl := blockchain.Generate_Genesis_Block()
+	bl.Tx_hashes = append(bl.Tx_hashes, tx.GetHash())
+	cbl := &block.Complete_Block{Bl: &bl, Txs: []*transaction.Transaction{&tx}}
+
+	chunks := Split_Block_Into_Chunks(cbl, 16, 32)
+	if len(chunks) != 48 {
+		t.Fatalf("expected 48 chunks, actual %d", len(chunks))
+	}
+
+	var have [48]*Block_Chunk
+	for i := 32; i < 48; i++ { // only parity chunks
+		have[i] = &chunks[i]
+	}
+	joined, err := Join_Chunks(have[:])
+	if err != nil {
+		t.Fatalf("cannot join chunks err %s", err)
+	}
+	if !bytes.Equal(joined.Block, bl.Serialize()) || len(joined.Txs) != 1 || !bytes.Equal(joined.Txs[0], tx.Serialize()) {
+		t.Fatalf("joined block differs from original")
+	}
+
+	have[32] = nil
+	if _, err := Join_Chunks(have[:]); err == nil {
+		t.Fatalf("15 chunks must not be sufficient")
+	}
+}
+
+// only chunks inconsistent with their own hashes are reported as corrupted, other errors must not be penalized
+func Test_Feed_Chunk_Errors(t *testing.T) {
+	bl := blockchain.Generate_Genesis_Block()
+	chunks := Split_Block_Into_Chunks(&block.Complete_Block{Bl: &bl}, 16, 32)
+	connection := &Connection{logger: logr.Discard(), node: &Node{}}
+
+	corrupted := chunks[0]
+	corrupted.CHUNK_DATA = append([]byte{}, corrupted.CHUNK_DATA...)
+	corrupted.CHUNK_DATA[0] ^= 1
+	if err := connection.feed_chunk(&corrupted, 0); !errors.Is(err, errCorruptedChunk) {
+		t.Fatalf("corrupted chunk data must be reported as corrupted, err %v", err)
+	}
+
+	oversized := chunks[1]
+	oversized.DSIZE = uint(config.STARGATE_HE_MAX_BLOCK_SIZE + 1)
+	oversized.HHash = oversized.HeaderHash()
+	if err := connection.feed_chunk(&oversized, 0); err == nil || errors.Is(err, errCorruptedChunk) {
+		t.Fatalf("invalid chunk size is not provable misbehaviour, err %v", err)
+	}
+}
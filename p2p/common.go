@@ -7,8 +7,9 @@ import "github.com/deroproject/derohe/globals"
 import "github.com/deroproject/derohe/cryptography/crypto"
 
 // fill the common part from our chain
-func fill_common(common *Common_Struct) {
+func (n *Node) fill_common(common *Common_Struct) {
 	var err error
+	chain := n.chain
 	common.Height = chain.Get_Height()
 	//common.StableHeight = chain.Get_Stable_Height()
 	common.TopoHeight = chain.Load_TOPO_HEIGHT()
@@ -39,8 +40,8 @@ func fill_common_T0T1T2(request, response *Common_Struct) {
 
 // used while sendint TX ASAP
 // this also skips statehash
-func fill_common_skip_topoheight(common *Common_Struct) {
-	fill_common(common)
+func (n *Node) fill_common_skip_topoheight(common *Common_Struct) {
+	n.fill_common(common)
 	return
 
 }
@@ -65,8 +65,6 @@ type Connection struct {
 	Pruned                int64  // till where chain has been pruned on this node
 	LastObjectRequestTime int64  // when was the last item placed in object list
 	Latency               int64  // time.Duration            // latency to this node when sending timed sync
-	BytesIn               uint64 // total bytes in
-	BytesOut              uint64 // total bytes out
 	Top_Version           uint64 // current hard fork version supported by peer
 	Peer_ID               uint64 // Remote peer id
 	ping_count            int64
@@ -92,7 +90,9 @@ type Connection struct {
 	DaemonVersion   string
 	Top_ID          crypto.Hash // top block id of the connection
 
-	logger logr.Logger // connection specific logger
+	logger    logr.Logger     // connection specific logger
+	node      *Node           // node this connection belongs to
+	bandwidth *peer_bandwidth // bytes accounting and limits, shared with codec
 
 	Requested_Objects [][32]byte // currently unused as we sync up with a single peer at a time
 
@@ -105,6 +105,9 @@ type Connection struct {
 	delays        [MAX_CLOCK_DATA_SET]time.Duration
 	onceexit      sync.Once
 
+	seen       map[[32]byte]struct{} // objects received recently, used to detect floods
+	seen_mutex sync.Mutex
+
 	Mutex sync.Mutex // used only by connection go routine
 }
 
@@ -127,10 +130,11 @@ func (c *Connection) exit() {
 
 // add connection to  map
 func Connection_Delete(c *Connection) {
-	connection_map.Range(func(k, value interface{}) bool {
+	c.node.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
 		if c.Addr.String() == v.Addr.String() {
-			connection_map.Delete(Address(v))
+			c.node.connection_map.Delete(Address(v))
+			metrics.Set.UnregisterMetric(peer_metric("p2p_peer_height_lag", Address(v)))
 			return false
 		}
 		return true
@@ -138,7 +142,7 @@ func Connection_Delete(c *Connection) {
 }
 
 func Connection_Pending_Clear() {
-	connection_map.Range(func(k, value interface{}) bool {
+	default_node.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
 		if atomic.LoadUint32(&v.State) == HANDSHAKE_PENDING && time.Now().Sub(v.Created) > 10*time.Second { //and skip ourselves
 			v.exit()
@@ -160,11 +164,9 @@ func Connection_Pending_Clear() {
 	})
 }
 
-var connection_map sync.Map // map[string]*Connection{}
-
 // check whether an IP is in the map already
 func IsAddressConnected(address string) bool {
-	if _, ok := connection_map.Load(strings.TrimSpace(address)); ok {
+	if _, ok := default_node.connection_map.Load(strings.TrimSpace(address)); ok {
 		return true
 	}
 	return false
@@ -175,9 +177,12 @@ func IsAddressConnected(address string) bool {
 // same ip max 8 ip ( considering NAT)
 // same Peer ID   4
 func Connection_Add(c *Connection) bool {
-	if dup, ok := connection_map.LoadOrStore(Address(c), c); !ok {
+	if dup, ok := c.node.connection_map.LoadOrStore(Address(c), c); !ok {
 		c.Created = time.Now()
 		c.logger.V(3).Info("IP address being added", "ip", c.Addr.String())
+		metrics.Set.GetOrCreateGauge(peer_metric("p2p_peer_height_lag", Address(c)), func() float64 { // positive means peer is ahead
+			return float64(atomic.LoadInt64(&c.Height) - c.node.chain.Get_Height())
+		})
 		return true
 	} else {
 		c.logger.V(3).Info("IP address already has one connection, exiting this connection", "ip", c.Addr.String(), "pre", dup.(*Connection).Addr.String())
@@ -190,11 +195,11 @@ func Connection_Add(c *Connection) bool {
 // since 2 nodes may be connected in both directions, we need to deliver new blocks/tx to only one
 // thereby saving NW/computing costs
 // we find duplicates using peer id
-func UniqueConnections() map[uint64]*Connection {
+func (n *Node) UniqueConnections() map[uint64]*Connection {
 	unique_map := map[uint64]*Connection{}
-	connection_map.Range(func(k, value interface{}) bool {
+	n.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
-		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && GetPeerID() != v.Peer_ID { //and skip ourselves
+		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && n.GetPeerID() != v.Peer_ID { //and skip ourselves
 			unique_map[v.Peer_ID] = v // map will automatically deduplicate/overwrite previous
 		}
 		return true
@@ -204,7 +209,7 @@ func UniqueConnections() map[uint64]*Connection {
 
 // this function has infinite loop to keep ping every few sec
 func ping_loop() {
-	connection_map.Range(func(k, value interface{}) bool {
+	default_node.connection_map.Range(func(k, value interface{}) bool {
 		c := value.(*Connection)
 		if atomic.LoadUint32(&c.State) != HANDSHAKE_PENDING && GetPeerID() != c.Peer_ID /*&& atomic.LoadInt32(&c.ping_in_progress) == 0*/ {
 			if atomic.LoadInt32(&c.Syncing) >= 1 {
@@ -216,7 +221,7 @@ func ping_loop() {
 				defer atomic.AddInt32(&c.ping_in_progress, -1)
 
 				var request, response Dummy
-				fill_common(&request.Common) // fill common info
+				c.node.fill_common(&request.Common) // fill common info
 
 				c.ping_count++
 				if c.ping_count%10 == 1 {
@@ -228,6 +233,9 @@ func ping_loop() {
 
 				if err := c.Client.CallWithContext(ctx, "Peer.Ping", request, &response); err != nil {
 					c.logger.V(2).Error(err, "ping failed")
+					if ctx.Err() != nil {
+						c.timeout("ping timeout")
+					}
 					c.exit()
 					return
 				}
@@ -242,12 +250,13 @@ func ping_loop() {
 func Connection_Print() {
 	var clist []*Connection
 
-	connection_map.Range(func(k, value interface{}) bool {
+	default_node.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
 		clist = append(clist, v)
 		return true
 	})
 
+	chain := default_node.chain
 	version, err := chain.ReadBlockSnapshotVersion(chain.Get_Top_ID())
 	if err != nil {
 		panic(err)
@@ -308,10 +317,11 @@ func Connection_Print() {
 		ctime := time.Now().Sub(clist[i].Created).Round(time.Second)
 
 		hstring := fmt.Sprintf("%d/%d/%d", clist[i].StableHeight, clist[i].Height, clist[i].TopoHeight)
-		fmt.Printf("%-30s %16x %5d %7s %7s %7s %23s %s %5d %7s %7s     %16s %s %x\n", Address(clist[i])+" ("+ctime.String()+")", clist[i].Peer_ID, clist[i].Port, state, time.Duration(atomic.LoadInt64(&clist[i].Latency)).Round(time.Millisecond).String(), time.Duration(atomic.LoadInt64(&clist[i].clock_offset)).Round(time.Millisecond).String(), hstring, dir, 0, humanize.Bytes(atomic.LoadUint64(&clist[i].BytesIn)), humanize.Bytes(atomic.LoadUint64(&clist[i].BytesOut)), version, tag, clist[i].StateHash[:])
+		fmt.Printf("%-30s %16x %5d %7s %7s %7s %23s %s %5d %7s %7s     %16s %s %x\n", Address(clist[i])+" ("+ctime.String()+")", clist[i].Peer_ID, clist[i].Port, state, time.Duration(atomic.LoadInt64(&clist[i].Latency)).Round(time.Millisecond).String(), time.Duration(atomic.LoadInt64(&clist[i].clock_offset)).Round(time.Millisecond).String(), hstring, dir, 0, humanize.Bytes(clist[i].bytes_in()), humanize.Bytes(clist[i].bytes_out()), version, tag, clist[i].StateHash[:])
 
 		fmt.Print(color_normal)
 	}
+	bandwidth_print(clist)
 	logger.Info("Connection info for peers", "count", len(clist), "our Statehash", StateHash)
 
 }
@@ -323,7 +333,7 @@ func Best_Peer_Height() (best_height, best_topo_height int64) {
 	var heights []uint64
 	var topoheights []uint64
 
-	connection_map.Range(func(k, value interface{}) bool {
+	default_node.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
 		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING {
 			height := atomic.LoadInt64(&v.Height)
@@ -341,9 +351,14 @@ func Best_Peer_Height() (best_height, best_topo_height int64) {
 
 // this function return peer count which have successful handshake
 func Peer_Count() (Count uint64) {
-	connection_map.Range(func(k, value interface{}) bool {
+	return default_node.Peer_Count()
+}
+
+// peer count of this node, which have successful handshake
+func (n *Node) Peer_Count() (Count uint64) {
+	n.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
-		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && GetPeerID() != v.Peer_ID {
+		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && n.GetPeerID() != v.Peer_ID {
 			Count++
 		}
 		return true
@@ -353,7 +368,7 @@ func Peer_Count() (Count uint64) {
 
 // this returns count of peers in both directions
 func Peer_Direction_Count() (Incoming uint64, Outgoing uint64) {
-	connection_map.Range(func(k, value interface{}) bool {
+	default_node.connection_map.Range(func(k, value interface{}) bool {
 		v := value.(*Connection)
 		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && GetPeerID() != v.Peer_ID {
 			if v.Incoming {
@@ -368,7 +383,12 @@ func Peer_Direction_Count() (Incoming uint64, Outgoing uint64) {
 }
 
 func Broadcast_Block(cbl *block.Complete_Block, PeerID uint64) {
-	Broadcast_Block_Coded(cbl, PeerID)
+	default_node.Broadcast_Block(cbl, PeerID)
+}
+
+// broadcasts a block to peers of this node, see Broadcast_Block_Coded
+func (n *Node) Broadcast_Block(cbl *block.Complete_Block, PeerID uint64) {
+	n.broadcast_Block_Coded(cbl, PeerID, globals.Time().UTC().UnixMicro())
 }
 
 // broad cast a block to all connected peers in cut up in chunks with erasure coding
@@ -376,10 +396,10 @@ func Broadcast_Block(cbl *block.Complete_Block, PeerID uint64) {
 // this function is trigger from 2 points, one when we receive a unknown block which can be successfully added to chain
 // second from the blockchain which has to relay locally  mined blocks as soon as possible
 func Broadcast_Block_Coded(cbl *block.Complete_Block, PeerID uint64) { // if peerid is provided it is skipped
-	broadcast_Block_Coded(cbl, PeerID, globals.Time().UTC().UnixMicro())
+	default_node.broadcast_Block_Coded(cbl, PeerID, globals.Time().UTC().UnixMicro())
 }
 
-func broadcast_Block_Coded(cbl *block.Complete_Block, PeerID uint64, first_seen int64) {
+func (n *Node) broadcast_Block_Coded(cbl *block.Complete_Block, PeerID uint64, first_seen int64) {
 
 	defer globals.Recover(3)
 
@@ -391,13 +411,13 @@ func broadcast_Block_Coded(cbl *block.Complete_Block, PeerID uint64, first_seen
 
 	logger.V(1).Info("Will broadcast block", "blid", blid, "tx_count", len(cbl.Bl.Tx_hashes), "txs", len(cbl.Txs))
 
-	hhash, chunk_count := convert_block_to_chunks(cbl, 16, 32)
+	hhash, chunk_count := n.convert_block_to_chunks(cbl, 16, 32)
 
-	our_height := chain.Get_Height()
+	our_height := n.chain.Get_Height()
 	// build the request once and dispatch it to all possible peers
 	tries := 0
 	count := 0
-	unique_map := UniqueConnections()
+	unique_map := n.UniqueConnections()
 
 	var connections []*Connection
 	for _, v := range unique_map {
@@ -430,7 +450,7 @@ func broadcast_Block_Coded(cbl *block.Complete_Block, PeerID uint64, first_seen
 				return
 			}
 			tries++
-			if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != GetPeerID() { // skip pre-handshake connections
+			if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != n.GetPeerID() { // skip pre-handshake connections
 
 				// if the other end is > 2 blocks behind, do not broadcast block to him
 				// this is an optimisation, since if the other end is syncing
@@ -458,7 +478,7 @@ func broadcast_Block_Coded(cbl *block.Complete_Block, PeerID uint64, first_seen
 					peer_specific_list.Chunk_list = append(peer_specific_list.Chunk_list, chunkid)
 					connection.logger.V(3).Info("Sending erasure coded chunk to peer ", "cid", cid)
 					var dummy Dummy
-					fill_common(&peer_specific_list.Common) // fill common info
+					n.fill_common(&peer_specific_list.Common) // fill common info
 					if err := connection.Client.Call("Peer.NotifyINV", peer_specific_list, &dummy); err != nil {
 						return
 					}
@@ -480,13 +500,13 @@ done:
 // we can only broadcast a block which is in our db
 // this function is triggerred from 2 points, one when we receive a unknown block which can be successfully added to chain
 // second from the blockchain which has to relay locally  mined blocks as soon as possible
-func broadcast_Chunk(chunk *Block_Chunk, PeerID uint64, first_seen int64) { // if peerid is provided it is skipped
+func (n *Node) broadcast_Chunk(chunk *Block_Chunk, PeerID uint64, first_seen int64) { // if peerid is provided it is skipped
 	defer globals.Recover(3)
 
-	our_height := chain.Get_Height()
+	our_height := n.chain.Get_Height()
 
 	count := 0
-	unique_map := UniqueConnections()
+	unique_map := n.UniqueConnections()
 
 	hhash := chunk.HHash
 
@@ -496,7 +516,7 @@ func broadcast_Chunk(chunk *Block_Chunk, PeerID uint64, first_seen int64) { // i
 			return
 		default:
 		}
-		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != GetPeerID() { // skip pre-handshake connections
+		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != n.GetPeerID() { // skip pre-handshake connections
 
 			// if the other end is > 50 blocks behind, do not broadcast block to hime
 			// this is an optimisation, since if the other end is syncing
@@ -520,9 +540,9 @@ func broadcast_Chunk(chunk *Block_Chunk, PeerID uint64, first_seen int64) { // i
 				peer_specific_list.Sent = first_seen
 
 				peer_specific_list.Chunk_list = append(peer_specific_list.Chunk_list, chunkid)
-				connection.logger.V(3).Info("Sending erasure coded chunk INV to peer ", "raw", fmt.Sprintf("%x", chunkid), "blid", fmt.Sprintf("%x", chunk.BLID), "cid", chunk.CHUNK_ID, "hhash", fmt.Sprintf("%x", hhash), "exists", nil != is_chunk_exist(hhash, uint8(chunk.CHUNK_ID)))
+				connection.logger.V(3).Info("Sending erasure coded chunk INV to peer ", "raw", fmt.Sprintf("%x", chunkid), "blid", fmt.Sprintf("%x", chunk.BLID), "cid", chunk.CHUNK_ID, "hhash", fmt.Sprintf("%x", hhash), "exists", nil != n.is_chunk_exist(hhash, uint8(chunk.CHUNK_ID)))
 				var dummy Dummy
-				fill_common(&peer_specific_list.Common) // fill common info
+				n.fill_common(&peer_specific_list.Common) // fill common info
 				if err := connection.Client.Call("Peer.NotifyINV", peer_specific_list, &dummy); err != nil {
 					return
 				}
@@ -538,21 +558,26 @@ func broadcast_Chunk(chunk *Block_Chunk, PeerID uint64, first_seen int64) { // i
 // this function is trigger from 2 points, one when we receive a unknown block which can be successfully added to chain
 // second from the blockchain which has to relay locally  mined blocks as soon as possible
 func Broadcast_MiniBlock(mbl block.MiniBlock, PeerID uint64) { // if peerid is provided it is skipped
-	broadcast_MiniBlock(mbl, PeerID, globals.Time().UTC().UnixMicro())
+	default_node.Broadcast_MiniBlock(mbl, PeerID)
 }
-func broadcast_MiniBlock(mbl block.MiniBlock, PeerID uint64, first_seen int64) { // if peerid is provided it is skipped
+
+// broadcasts a miniblock to peers of this node
+func (n *Node) Broadcast_MiniBlock(mbl block.MiniBlock, PeerID uint64) {
+	n.broadcast_MiniBlock(mbl, PeerID, globals.Time().UTC().UnixMicro())
+}
+func (n *Node) broadcast_MiniBlock(mbl block.MiniBlock, PeerID uint64, first_seen int64) { // if peerid is provided it is skipped
 
 	defer globals.Recover(3)
 
 	var peer_specific_block Objects
 	peer_specific_block.MiniBlocks = append(peer_specific_block.MiniBlocks, mbl.Serialize())
-	fill_common(&peer_specific_block.Common) // fill common info
+	n.fill_common(&peer_specific_block.Common) // fill common info
 	peer_specific_block.Sent = first_seen
 
-	our_height := chain.Get_Height()
+	our_height := n.chain.Get_Height()
 	// build the request once and dispatch it to all possible peers
 	count := 0
-	unique_map := UniqueConnections()
+	unique_map := n.UniqueConnections()
 
 	//connection.logger.V(4).Info("Sending mini block to peer ")
 
@@ -562,7 +587,7 @@ func broadcast_MiniBlock(mbl block.MiniBlock, PeerID uint64, first_seen int64) {
 			return
 		default:
 		}
-		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != GetPeerID() { // skip pre-handshake connections
+		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != n.GetPeerID() { // skip pre-handshake connections
 
 			// if the other end is > 50 blocks behind, do not broadcast block to hime
 			// this is an optimisation, since if the other end is syncing
@@ -594,22 +619,22 @@ func broadcast_MiniBlock(mbl block.MiniBlock, PeerID uint64, first_seen int64) {
 // second from the mempool which may want to relay local ot soon going to expire transactions
 
 func Broadcast_Tx(tx *transaction.Transaction, PeerID uint64) (relayed_count int32) {
-	return broadcast_Tx(tx, PeerID, globals.Time().UTC().UnixMicro())
+	return default_node.broadcast_Tx(tx, PeerID, globals.Time().UTC().UnixMicro())
 
 }
-func broadcast_Tx(tx *transaction.Transaction, PeerID uint64, sent int64) (relayed_count int32) {
+func (n *Node) broadcast_Tx(tx *transaction.Transaction, PeerID uint64, sent int64) (relayed_count int32) {
 	defer globals.Recover(3)
 
 	var request ObjectList
-	fill_common_skip_topoheight(&request.Common) // fill common info, but skip topo height
+	n.fill_common_skip_topoheight(&request.Common) // fill common info, but skip topo height
 	txhash := tx.GetHash()
 
 	request.Tx_list = append(request.Tx_list, txhash)
 	request.Sent = sent
 
-	our_height := chain.Get_Height()
+	our_height := n.chain.Get_Height()
 
-	unique_map := UniqueConnections()
+	unique_map := n.UniqueConnections()
 
 	for _, v := range unique_map {
 		select {
@@ -617,7 +642,7 @@ func broadcast_Tx(tx *transaction.Transaction, PeerID uint64, sent int64) (relay
 			return
 		default:
 		}
-		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != GetPeerID() { // skip pre-handshake connections
+		if atomic.LoadUint32(&v.State) != HANDSHAKE_PENDING && PeerID != v.Peer_ID && v.Peer_ID != n.GetPeerID() { // skip pre-handshake connections
 
 			// if the other end is > 50 blocks behind, do not broadcast block to hime
 			// this is an optimisation, since if the other end is syncing
@@ -637,7 +662,7 @@ func broadcast_Tx(tx *transaction.Transaction, PeerID uint64, sent int64) (relay
 				}()
 
 				var dummy Dummy
-				fill_common(&dummy.Common) // fill common info
+				n.fill_common(&dummy.Common) // fill common info
 				if err := connection.Client.Call("Peer.NotifyINV", request, &dummy); err != nil {
 					return
 				}
@@ -655,10 +680,11 @@ func broadcast_Tx(tx *transaction.Transaction, PeerID uint64, sent int64) (relay
 }
 
 // trigger a sync with a random peer
-func trigger_sync() {
+func (n *Node) trigger_sync() {
 	defer globals.Recover(3)
 
-	unique_map := UniqueConnections()
+	chain := n.chain
+	unique_map := n.UniqueConnections()
 
 	var clist []*Connection
 
@@ -736,23 +762,21 @@ func Abs(n int64) int64 {
 // detect whether we are behind any of the connected peers and trigger sync ASAP
 // randomly with one of the peers
 
-var single_sync int32
-
 func syncroniser() {
 
-	defer atomic.AddInt32(&single_sync, -1)
+	defer atomic.AddInt32(&default_node.single_sync, -1)
 
-	if atomic.AddInt32(&single_sync, 1) != 1 {
+	if atomic.AddInt32(&default_node.single_sync, 1) != 1 {
 		return
 	}
-	calculate_network_time() // calculate time every sec
-	trigger_sync()           // check whether we are out of sync
+	calculate_network_time()    // calculate time every sec
+	default_node.trigger_sync() // check whether we are out of sync
 }
 
 // update P2P time
 func calculate_network_time() {
 	var total, count, mean int64
-	unique_map := UniqueConnections()
+	unique_map := default_node.UniqueConnections()
 
 	for _, v := range unique_map {
 		if Abs(atomic.LoadInt64(&v.clock_offset)) < 100*1000000000 { //  this is 100 sec
@@ -0,0 +1,273 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+// this file implements filtering of inbound connections, done before any handshake takes place
+// allow/deny lists contain ips or subnets in CIDR form, deny always wins
+// subnet limits cap inbound connections from a subnet, max_per_16 caps connections from
+// any single /16 ( /32 for ipv6 ), so a single provider cannot take all our inbound slots
+// filter is loaded from json file given by --p2p-inbound-filter and reloaded when file changes
+// sample file
+// {
+//	"allow": ["10.0.0.0/8", "192.168.1.20"],
+//	"deny": ["10.1.0.0/16"],
+//	"subnet_limits": {"10.2.0.0/16": 4},
+//	"max_per_16": 8
+// }
+
+import "os"
+import "fmt"
+import "net"
+import "sync"
+import "time"
+import "encoding/json"
+
+import "github.com/deroproject/derohe/globals"
+
+type inbound_filter_file struct {
+	Allow        []string       `json:"allow"`         // if not empty, only these can connect
+	Deny         []string       `json:"deny"`          // these can never connect
+	SubnetLimits map[string]int `json:"subnet_limits"` // subnet => max inbound connections
+	MaxPer16     int            `json:"max_per_16"`    // max inbound connections from a single /16, 0 is unlimited
+}
+
+type subnet_limit struct {
+	ipnet *net.IPNet
+	max   int
+}
+
+type inbound_filter struct {
+	allow      []*net.IPNet
+	deny       []*net.IPNet
+	limits     []subnet_limit
+	max_per_16 int
+}
+
+var inbound_mutex sync.Mutex
+var inbound_rules inbound_filter
+var inbound_active = map[string]int{} // ip => live inbound connections, including ones in handshake
+var inbound_modtime time.Time         // modification time of loaded filter file
+
+// parse ip or subnet into subnet form, single ips become /32 or /128
+func parse_subnet(address string) (*net.IPNet, error) {
+	ipnet, result, err := ParseAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address '%s'", address)
+	}
+	if ipnet != nil {
+		return ipnet, nil
+	}
+	ip := net.ParseIP(result)
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func parse_inbound_filter(data []byte) (f inbound_filter, err error) {
+	var file inbound_filter_file
+	if err = json.Unmarshal(data, &file); err != nil {
+		return
+	}
+	for _, a := range file.Allow {
+		ipnet, err := parse_subnet(a)
+		if err != nil {
+			return f, err
+		}
+		f.allow = append(f.allow, ipnet)
+	}
+	for _, a := range file.Deny {
+		ipnet, err := parse_subnet(a)
+		if err != nil {
+			return f, err
+		}
+		f.deny = append(f.deny, ipnet)
+	}
+	for a, max := range file.SubnetLimits {
+		ipnet, err := parse_subnet(a)
+		if err != nil {
+			return f, err
+		}
+		if max < 0 {
+			return f, fmt.Errorf("invalid limit %d for subnet '%s'", max, a)
+		}
+		f.limits = append(f.limits, subnet_limit{ipnet: ipnet, max: max})
+	}
+	if file.MaxPer16 < 0 {
+		return f, fmt.Errorf("invalid max_per_16 %d", file.MaxPer16)
+	}
+	f.max_per_16 = file.MaxPer16
+	return
+}
+
+// key of the /16 ( /32 for ipv6 ) an ip belongs to
+func subnet16(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String()
+}
+
+func contains_ip(list []*net.IPNet, ip net.IP) bool {
+	for _, ipnet := range list {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checks whether an inbound ip can connect, if yes it is counted till inbound_release is called
+func inbound_admit(address string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("unparseable ip '%s'", address)
+	}
+
+	inbound_mutex.Lock()
+	defer inbound_mutex.Unlock()
+
+	f := inbound_rules
+	if contains_ip(f.deny, ip) {
+		return fmt.Errorf("ip is in deny list")
+	}
+	if len(f.allow) > 0 && !contains_ip(f.allow, ip) {
+		return fmt.Errorf("ip is not in allow list")
+	}
+
+	for _, limit := range f.limits {
+		if !limit.ipnet.Contains(ip) {
+			continue
+		}
+		count := 0
+		for k, v := range inbound_active {
+			if limit.ipnet.Contains(net.ParseIP(k)) {
+				count += v
+			}
+		}
+		if count >= limit.max {
+			return fmt.Errorf("subnet %s has reached limit %d", limit.ipnet, limit.max)
+		}
+	}
+
+	if f.max_per_16 > 0 {
+		key := subnet16(ip)
+		count := 0
+		for k, v := range inbound_active {
+			if subnet16(net.ParseIP(k)) == key {
+				count += v
+			}
+		}
+		if count >= f.max_per_16 {
+			return fmt.Errorf("subnet %s/16 has reached limit %d", key, f.max_per_16)
+		}
+	}
+
+	inbound_active[ip.String()]++
+	return nil
+}
+
+// inbound connection has been closed
+func inbound_release(address string) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return
+	}
+	inbound_mutex.Lock()
+	defer inbound_mutex.Unlock()
+	if inbound_active[ip.String()]--; inbound_active[ip.String()] <= 0 {
+		delete(inbound_active, ip.String())
+	}
+}
+
+// loads filter from file, if file has not changed since last load, nothing is done unless forced
+func load_inbound_filter(force bool) error {
+	if globals.Arguments["--p2p-inbound-filter"] == nil {
+		return nil
+	}
+	filename := globals.Arguments["--p2p-inbound-filter"].(string)
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	inbound_mutex.Lock()
+	unchanged := fi.ModTime().Equal(inbound_modtime)
+	inbound_mutex.Unlock()
+	if unchanged && !force {
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	f, err := parse_inbound_filter(data)
+	if err != nil { // keep existing rules
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	inbound_mutex.Lock()
+	inbound_rules = f
+	inbound_modtime = fi.ModTime()
+	inbound_mutex.Unlock()
+
+	logger.Info("Loaded inbound filter", "file", filename, "allow", len(f.allow), "deny", len(f.deny), "subnet_limits", len(f.limits), "max_per_16", f.max_per_16)
+	return nil
+}
+
+// called periodically to pick up changes in filter file
+func reload_inbound_filter() {
+	if err := load_inbound_filter(false); err != nil {
+		logger.Error(err, "Could not reload inbound filter")
+	}
+}
+
+// reload inbound filter from file now
+func Inbound_Filter_Reload() error {
+	return load_inbound_filter(true)
+}
+
+// prints inbound filter and current inbound connection counts
+func Inbound_Filter_Print() {
+	inbound_mutex.Lock()
+	defer inbound_mutex.Unlock()
+
+	f := inbound_rules
+	fmt.Printf("Inbound filter allow %d deny %d subnet limits %d max per /16 %d\n", len(f.allow), len(f.deny), len(f.limits), f.max_per_16)
+	for _, ipnet := range f.allow {
+		fmt.Printf("%-8s %s\n", "allow", ipnet)
+	}
+	for _, ipnet := range f.deny {
+		fmt.Printf("%-8s %s\n", "deny", ipnet)
+	}
+	for _, limit := range f.limits {
+		fmt.Printf("%-8s %s %d\n", "limit", limit.ipnet, limit.max)
+	}
+
+	total := 0
+	per16 := map[string]int{}
+	for k, v := range inbound_active {
+		per16[subnet16(net.ParseIP(k))] += v
+		total += v
+	}
+	fmt.Printf("Inbound connections %d from %d IPs in %d subnets\n", total, len(inbound_active), len(per16))
+	for k, v := range per16 {
+		fmt.Printf("%-22s %d\n", k+"/16", v)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "os"
+import "time"
+import "testing"
+import "path/filepath"
+
+import "github.com/go-logr/logr"
+
+import "github.com/deroproject/derohe/globals"
+
+func reset_inbound(f inbound_filter) {
+	inbound_mutex.Lock()
+	inbound_rules = f
+	inbound_active = map[string]int{}
+	inbound_mutex.Unlock()
+}
+
+func Test_Inbound_Filter(t *testing.T) {
+	logger = logr.Discard()
+	defer reset_inbound(inbound_filter{})
+
+	if _, err := parse_inbound_filter([]byte(`{"allow":["10.0.0.0/33"]}`)); err == nil {
+		t.Fatalf("invalid subnet accepted")
+	}
+	if _, err := parse_inbound_filter([]byte(`{"max_per_16":-1}`)); err == nil {
+		t.Fatalf("negative limit accepted")
+	}
+
+	f, err := parse_inbound_filter([]byte(`{"allow":["10.0.0.0/8","192.168.1.20"],"deny":["10.1.0.0/16"],"subnet_limits":{"10.2.0.0/24":2},"max_per_16":3}`))
+	if err != nil {
+		t.Fatalf("cannot parse filter err %s", err)
+	}
+	reset_inbound(f)
+
+	tests := []struct {
+		ip string
+		ok bool
+	}{
+		{"192.168.1.20", true},  // single allowed ip
+		{"192.168.1.21", false}, // not in allow list
+		{"10.1.5.5", false},     // deny wins over allow
+		{"10.2.0.1", true},
+		{"10.2.0.2", true},
+		{"10.2.0.3", false}, // subnet limit of 2
+		{"10.2.1.1", true},  // /16 now has 3
+		{"10.2.1.2", false}, // /16 limit of 3
+		{"10.3.0.1", true},
+		{"not an ip", false},
+	}
+	for _, test := range tests {
+		if err := inbound_admit(test.ip); (err == nil) != test.ok {
+			t.Fatalf("ip %s expected %v err %v", test.ip, test.ok, err)
+		}
+	}
+
+	inbound_release("10.2.0.1")
+	if err := inbound_admit("10.2.0.3"); err != nil {
+		t.Fatalf("released slot not reused err %s", err)
+	}
+
+	// without rules everything is accepted
+	reset_inbound(inbound_filter{})
+	for i := 0; i < 100; i++ {
+		if err := inbound_admit("203.0.113.9"); err != nil {
+			t.Fatalf("empty filter rejected err %s", err)
+		}
+	}
+}
+
+func Test_Inbound_Filter_Reload(t *testing.T) {
+	logger = logr.Discard()
+	defer reset_inbound(inbound_filter{})
+
+	file := filepath.Join(t.TempDir(), "filter.json")
+	if err := os.WriteFile(file, []byte(`{"deny":["198.51.100.0/24"]}`), 0600); err != nil {
+		t.Fatalf("cannot write filter err %s", err)
+	}
+
+	if globals.Arguments == nil {
+		globals.Arguments = map[string]interface{}{}
+	}
+	globals.Arguments["--p2p-inbound-filter"] = file
+	defer delete(globals.Arguments, "--p2p-inbound-filter")
+
+	if err := load_inbound_filter(true); err != nil {
+		t.Fatalf("cannot load filter err %s", err)
+	}
+	if err := inbound_admit("198.51.100.1"); err == nil {
+		t.Fatalf("denied ip accepted")
+	}
+
+	// broken file must keep existing rules
+	os.WriteFile(file, []byte(`{"deny":[`), 0600)
+	os.Chtimes(file, time.Now(), time.Now().Add(time.Minute))
+	if err := load_inbound_filter(false); err == nil {
+		t.Fatalf("broken filter accepted")
+	}
+	if err := inbound_admit("198.51.100.1"); err == nil {
+		t.Fatalf("rules lost after failed reload")
+	}
+
+	// changed file is picked up without force
+	os.WriteFile(file, []byte(`{"deny":["192.0.2.0/24"]}`), 0600)
+	os.Chtimes(file, time.Now(), time.Now().Add(2*time.Minute))
+	reload_inbound_filter()
+	if err := inbound_admit("198.51.100.1"); err != nil {
+		t.Fatalf("reloaded rules not applied err %s", err)
+	}
+	if err := inbound_admit("192.0.2.1"); err == nil {
+		t.Fatalf("reloaded deny not applied")
+	}
+}
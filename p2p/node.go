@@ -0,0 +1,74 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "net"
+import "sync"
+import "sync/atomic"
+
+import "github.com/go-logr/logr"
+
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/blockchain"
+
+// a node serves a single chain over p2p, it has its own peer id, connections and chunks
+// daemon runs the default node, tests may run several nodes within a process, each with its own chain
+// peer list, bans, scores, bandwidth limits and inbound filter are process wide and shared by all nodes
+type Node struct {
+	chain  *blockchain.Blockchain
+	peerid uint64
+	logger logr.Logger // connection loggers are children of this
+
+	connection_map sync.Map // map[string]*Connection{}
+	single_sync    int32    // only a single sync runs at a time
+
+	chunk_map  sync.Map // key is blid, value is pointer to  Chunks_Per_Block_Data
+	chunk_lock sync.Mutex
+}
+
+var default_node = &Node{logger: logr.Discard()} // node of the daemon, chain is attached by P2P_Init
+
+// creates a node serving the chain, it has no connections
+// chain must be wired to Broadcast_Block and Broadcast_MiniBlock so its blocks are relayed
+func NewNode(chain *blockchain.Blockchain) *Node {
+	n := &Node{chain: chain, logger: globals.Logger.WithName("P2P")}
+	n.GetPeerID()
+	return n
+}
+
+// runs p2p protocol over an already established connection, blocks till the connection dies
+// no tls is layered, so conn must be private already, eg in memory pipes
+func (n *Node) Attach(conn net.Conn, incoming bool) {
+	n.process_outgoing_connection(conn, conn, conn.RemoteAddr(), incoming, false)
+}
+
+// syncs with a peer which is ahead of us, if any, daemon does this every 4 secs
+func (n *Node) Sync() {
+	defer atomic.AddInt32(&n.single_sync, -1)
+
+	if atomic.AddInt32(&n.single_sync, 1) != 1 {
+		return
+	}
+	n.trigger_sync()
+}
+
+// forgets a dead connection, so the peer can connect again without waiting for Connection_Pending_Clear
+func (n *Node) connection_closed(c *Connection) {
+	if v, ok := n.connection_map.Load(Address(c)); ok && v.(*Connection) == c {
+		Connection_Delete(c)
+	}
+}
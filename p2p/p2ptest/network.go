@@ -0,0 +1,501 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package p2ptest runs several chains within a single process, wired together through in memory pipes.
+// Every chain is served by its own p2p.Node, so blocks, miniblocks, chunks and chain sync go through the
+// real p2p handshake and handlers ( chain_sync.go, rpc_notifications.go, chunk_server.go) over net.Pipe.
+// Links can be delayed, cut or made lossy and every node may run with its own clock skew, so
+// reorgs, chunked block propagation and sync from scratch can be tested deterministically.
+//
+// Dialing, listeners, tls, peer list, bans and scores are not covered, these are process wide in p2p.
+// Nodes sync every 100 ms instead of every 4 secs and pings are not sent.
+package p2ptest
+
+import "fmt"
+import "io"
+import "net"
+import "sync"
+import "time"
+import "path/filepath"
+import "encoding/hex"
+import "encoding/binary"
+
+import "github.com/fxamacker/cbor/v2"
+
+import "github.com/deroproject/derohe/p2p"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/transaction"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// a set of nodes and the links between them
+type Network struct {
+	Nodes []*Node
+
+	dir      string
+	links    map[[2]int]*Link // key is sorted node ids
+	detached map[[2]int]*Link // links cut by a partition, restored by Heal
+	quit     chan struct{}    // stops sync loops
+	wg       sync.WaitGroup   // running sync loops
+	sync.Mutex
+}
+
+var setup_once sync.Once
+
+// every chain runs in simulator mode on testnet, difficulty is 1 and pow is not checked
+func setup_globals() {
+	setup_once.Do(func() {
+		if globals.Arguments == nil {
+			globals.Arguments = map[string]interface{}{}
+		}
+		globals.Arguments["--testnet"] = true
+		globals.Arguments["--simulator"] = true
+		globals.InitNetwork()
+	})
+}
+
+// creates a network with count nodes, chains are stored under dir
+// nodes are not connected, use Connect or ConnectAll
+func NewNetwork(dir string, count int) (*Network, error) {
+	setup_globals()
+
+	n := &Network{dir: dir, links: map[[2]int]*Link{}, detached: map[[2]int]*Link{}, quit: make(chan struct{})}
+	for i := 0; i < count; i++ {
+		if _, err := n.AddNode(); err != nil {
+			n.Close()
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// adds a node with a fresh chain, which only has the genesis block
+func (n *Network) AddNode() (*Node, error) {
+	n.Lock()
+	id := len(n.Nodes)
+	n.Unlock()
+
+	params := map[string]interface{}{}
+	params["--simulator"] = true
+	params["data_directory"] = filepath.Join(n.dir, fmt.Sprintf("node%d", id))
+
+	chain, err := blockchain.Blockchain_Start(params)
+	if err != nil {
+		return nil, err
+	}
+
+	miner, err := genesis_miner()
+	if err != nil {
+		chain.Shutdown()
+		return nil, err
+	}
+
+	node := &Node{ID: id, Chain: chain, P2P: p2p.NewNode(chain), Miner: miner, network: n}
+	chain.P2P_Block_Relayer = node.P2P.Broadcast_Block
+	chain.P2P_MiniBlock_Relayer = node.P2P.Broadcast_MiniBlock
+
+	n.Lock()
+	n.Nodes = append(n.Nodes, node)
+	n.Unlock()
+
+	n.wg.Add(1)
+	go node.sync_loop(n.quit)
+	return node, nil
+}
+
+// address which received the genesis reward, it is registered from the start so it can mine
+func genesis_miner() (addr rpc.Address, err error) {
+	var tx transaction.Transaction
+	var tx_bytes []byte
+	if tx_bytes, err = hex.DecodeString(globals.Config.Genesis_Tx); err != nil {
+		return
+	}
+	if err = tx.Deserialize(tx_bytes); err != nil {
+		return
+	}
+	a, err := rpc.NewAddressFromCompressedKeys(tx.MinerAddress[:])
+	if err != nil {
+		return
+	}
+	return *a, nil
+}
+
+func link_key(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// connects 2 nodes and waits till both ends have completed the handshake
+func (n *Network) Connect(a, b int) (*Link, error) {
+	l, err := n.connect(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return l, n.wait_handshakes()
+}
+
+func (n *Network) connect(a, b int) (*Link, error) {
+	if a == b || a < 0 || b < 0 || a >= len(n.Nodes) || b >= len(n.Nodes) {
+		return nil, fmt.Errorf("invalid nodes %d %d", a, b)
+	}
+
+	n.Lock()
+	if l, ok := n.links[link_key(a, b)]; ok {
+		n.Unlock()
+		return l, nil
+	}
+	l := &Link{A: a, B: b}
+	n.links[link_key(a, b)] = l
+	n.Unlock()
+
+	l.connect(n.Nodes[a], n.Nodes[b])
+	return l, nil
+}
+
+// connects every node to every other node
+func (n *Network) ConnectAll() error {
+	for a := range n.Nodes {
+		for b := a + 1; b < len(n.Nodes); b++ {
+			if _, err := n.connect(a, b); err != nil {
+				return err
+			}
+		}
+	}
+	return n.wait_handshakes()
+}
+
+// returns link between 2 nodes, nil if they are not connected
+func (n *Network) Link(a, b int) *Link {
+	n.Lock()
+	defer n.Unlock()
+	return n.links[link_key(a, b)]
+}
+
+// cuts the link between 2 nodes, returns once both nodes have dropped the connection
+func (n *Network) Disconnect(a, b int) error {
+	n.Lock()
+	l, ok := n.links[link_key(a, b)]
+	delete(n.links, link_key(a, b))
+	n.Unlock()
+	if ok {
+		l.close()
+	}
+	return n.wait_handshakes()
+}
+
+// splits the network into groups, links crossing groups are cut until Heal is called
+// nodes not present in any group form a group of their own
+func (n *Network) Partition(groups ...[]int) error {
+	group := map[int]int{}
+	for i := range n.Nodes {
+		group[i] = -1
+	}
+	for g := range groups {
+		for _, id := range groups[g] {
+			group[id] = g
+		}
+	}
+
+	n.Lock()
+	var cut []*Link
+	for k, l := range n.links {
+		if group[k[0]] != group[k[1]] {
+			delete(n.links, k)
+			n.detached[k] = l
+			cut = append(cut, l)
+		}
+	}
+	n.Unlock()
+
+	for _, l := range cut {
+		l.close()
+	}
+	return n.wait_handshakes()
+}
+
+// restores all links cut by Partition
+func (n *Network) Heal() error {
+	n.Lock()
+	var restore []*Link
+	for k, l := range n.detached {
+		delete(n.detached, k)
+		n.links[k] = l
+		restore = append(restore, l)
+	}
+	n.Unlock()
+
+	for _, l := range restore {
+		l.connect(n.Nodes[l.A], n.Nodes[l.B])
+	}
+	return n.wait_handshakes()
+}
+
+// waits till every node has exactly one handshaked connection per link, p2p handshakes 2 secs after connecting
+func (n *Network) wait_handshakes() error {
+	return n.wait(10*time.Second, func() error {
+		n.Lock()
+		defer n.Unlock()
+		for _, node := range n.Nodes {
+			expected := 0
+			for k := range n.links {
+				if k[0] == node.ID || k[1] == node.ID {
+					expected++
+				}
+			}
+			if count := node.P2P.Peer_Count(); count != uint64(expected) {
+				return fmt.Errorf("node %d has %d peers, expected %d", node.ID, count, expected)
+			}
+		}
+		return nil
+	})
+}
+
+// waits till every node has reached the height
+func (n *Network) WaitForHeight(height int64, timeout time.Duration) error {
+	return n.wait(timeout, func() error {
+		for _, node := range n.Nodes {
+			if node.Chain.Get_Height() < height {
+				return fmt.Errorf("node %d is at height %d, expected %d", node.ID, node.Chain.Get_Height(), height)
+			}
+		}
+		return nil
+	})
+}
+
+// waits till every node has the same top block
+func (n *Network) WaitForConvergence(timeout time.Duration) error {
+	return n.wait(timeout, func() error {
+		top := n.Nodes[0].Chain.Get_Top_ID()
+		for _, node := range n.Nodes[1:] {
+			if ntop := node.Chain.Get_Top_ID(); ntop != top {
+				return fmt.Errorf("node %d has top %s, node 0 has top %s", node.ID, ntop, top)
+			}
+		}
+		return nil
+	})
+}
+
+func (n *Network) wait(timeout time.Duration, check func() error) (err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err = check(); err == nil || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// disconnects all nodes and stops all chains
+func (n *Network) Close() {
+	n.Lock()
+	var links []*Link
+	for k, l := range n.links {
+		delete(n.links, k)
+		links = append(links, l)
+	}
+	nodes := n.Nodes
+	n.Unlock()
+
+	close(n.quit)
+	for _, l := range links {
+		l.close()
+	}
+	n.wg.Wait()
+	for _, node := range nodes {
+		node.Chain.Shutdown()
+	}
+}
+
+// returns the hash at given topo height for every node, useful while debugging forks
+func (n *Network) Topo(topo int64) (hashes []crypto.Hash) {
+	for _, node := range n.Nodes {
+		hash, _ := node.Chain.Load_Block_Topological_order_at_index(topo)
+		hashes = append(hashes, hash)
+	}
+	return
+}
+
+// address used by node to identify itself on the pipes
+func node_addr(id int) net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(10, byte(id>>16), byte(id>>8), byte(id)), Port: config.Testnet.P2P_Default_Port}
+}
+
+// a link between 2 nodes
+type Link struct {
+	A, B int
+
+	latency     time.Duration // every frame is delayed by this much
+	drop_chunks int           // these many chunks of every block are not announced over the link
+	conns       []net.Conn
+	sync.Mutex
+}
+
+// delays every frame on this link
+func (l *Link) SetLatency(latency time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.latency = latency
+}
+
+// chunks with id below count are never announced over this link, so receiver must rebuild blocks from other chunks
+func (l *Link) SetDropChunks(count int) {
+	l.Lock()
+	defer l.Unlock()
+	l.drop_chunks = count
+}
+
+func (l *Link) get_latency() time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	return l.latency
+}
+
+func (l *Link) get_drop_chunks() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.drop_chunks
+}
+
+// a is the dialing end, b accepts
+func (l *Link) connect(a, b *Node) {
+	pa, pb := net.Pipe()
+	ca := new_link_conn(pa, l, node_addr(b.ID))
+	cb := new_link_conn(pb, l, node_addr(a.ID))
+
+	l.Lock()
+	l.conns = []net.Conn{ca, cb}
+	l.Unlock()
+
+	go a.P2P.Attach(ca, false)
+	go b.P2P.Attach(cb, true)
+}
+
+func (l *Link) close() {
+	l.Lock()
+	conns := l.conns
+	l.conns = nil
+	l.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// pipe end which delays frames and drops chunk announcements as configured in the link
+// p2p writes length prefixed cbor frames, a request header frame is followed by the request frame
+// frames are delivered in order by a separate goroutine, so latency delays every frame but does not limit throughput
+type link_conn struct {
+	net.Conn
+	link    *Link
+	remote  net.Addr
+	pending []byte // incomplete frame
+	inv     bool   // last frame was the header of a NotifyINV request
+	queue   chan delayed_frame
+	quit    chan struct{}
+	closer  sync.Once
+	sync.Mutex
+}
+
+type delayed_frame struct {
+	due  time.Time
+	data []byte // length prefixed frame
+}
+
+func new_link_conn(conn net.Conn, l *Link, remote net.Addr) *link_conn {
+	c := &link_conn{Conn: conn, link: l, remote: remote, queue: make(chan delayed_frame, 1024), quit: make(chan struct{})}
+	go c.deliver()
+	return c
+}
+
+func (c *link_conn) Write(b []byte) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.pending = append(c.pending, b...)
+	for len(c.pending) >= 4 {
+		length := 4 + int(binary.LittleEndian.Uint32(c.pending))
+		if len(c.pending) < length {
+			break
+		}
+		frame := c.filter(c.pending[4:length])
+		c.pending = c.pending[length:]
+
+		data := make([]byte, 4, 4+len(frame))
+		binary.LittleEndian.PutUint32(data, uint32(len(frame)))
+		select {
+		case c.queue <- delayed_frame{due: time.Now().Add(c.link.get_latency()), data: append(data, frame...)}:
+		case <-c.quit:
+			return 0, io.ErrClosedPipe
+		}
+	}
+	return len(b), nil
+}
+
+func (c *link_conn) deliver() {
+	for {
+		select {
+		case <-c.quit:
+			return
+		case f := <-c.queue:
+			if wait := time.Until(f.due); wait > 0 {
+				time.Sleep(wait)
+			}
+			if _, err := c.Conn.Write(f.data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *link_conn) Close() error {
+	c.closer.Do(func() { close(c.quit) })
+	return c.Conn.Close()
+}
+
+// removes dropped chunks from inventory announcements, everything else passes as is
+func (c *link_conn) filter(frame []byte) []byte {
+	if !c.inv {
+		var header p2p.RequestResponse
+		c.inv = cbor.Unmarshal(frame, &header) == nil && header.Method == "Peer.NotifyINV"
+		return frame
+	}
+	c.inv = false
+
+	drop := c.link.get_drop_chunks()
+	var request p2p.ObjectList
+	if drop == 0 || cbor.Unmarshal(frame, &request) != nil || len(request.Chunk_list) == 0 {
+		return frame
+	}
+	var kept [][32 + 1 + 32]byte
+	for _, chunk := range request.Chunk_list {
+		if int(chunk[32]) >= drop {
+			kept = append(kept, chunk)
+		}
+	}
+	request.Chunk_list = kept
+	if data, err := cbor.Marshal(request); err == nil {
+		return data
+	}
+	return frame
+}
+
+func (c *link_conn) RemoteAddr() net.Addr {
+	return c.remote
+}
@@ -0,0 +1,157 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2ptest
+
+import "testing"
+import "time"
+
+func new_network(t *testing.T, count int) *Network {
+	n, err := NewNetwork(t.TempDir(), count)
+	if err != nil {
+		t.Fatalf("cannot create network err %s", err)
+	}
+	t.Cleanup(n.Close)
+	return n
+}
+
+func Test_Block_Propagation(t *testing.T) {
+	n := new_network(t, 3)
+
+	// line topology, so node 1 must relay
+	if _, err := n.Connect(0, 1); err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+	l, err := n.Connect(1, 2)
+	if err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+	l.SetLatency(20 * time.Millisecond)
+
+	if err := n.Nodes[0].MineBlocks(3); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if err := n.WaitForHeight(3, 10*time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := n.WaitForConvergence(10 * time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func Test_Chunk_Loss(t *testing.T) {
+	n := new_network(t, 2)
+	l, err := n.Connect(0, 1)
+	if err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+	l.SetDropChunks(DATA_SHARDS + PARITY_SHARDS - 1) // only a single chunk reaches the peer
+
+	if err := n.Nodes[0].MineBlocks(2); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if err := n.WaitForConvergence(10 * time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if n.Nodes[1].Chain.Get_Height() != 2 {
+		t.Fatalf("expected height 2, actual %d", n.Nodes[1].Chain.Get_Height())
+	}
+}
+
+func Test_Reorg(t *testing.T) {
+	n := new_network(t, 4)
+	if err := n.ConnectAll(); err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+	if err := n.Nodes[0].MineBlocks(2); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if err := n.WaitForConvergence(10 * time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if err := n.Partition([]int{0, 1}, []int{2, 3}); err != nil {
+		t.Fatalf("partition failed err %s", err)
+	}
+	if err := n.Nodes[0].MineBlocks(2); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if err := n.Nodes[2].MineBlocks(4); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	heavier := n.Nodes[2].Chain.Get_Top_ID()
+
+	time.Sleep(200 * time.Millisecond)
+	if n.Nodes[1].Chain.Get_Height() != 4 || n.Nodes[3].Chain.Get_Height() != 6 {
+		t.Fatalf("partition leaked, heights %d %d", n.Nodes[1].Chain.Get_Height(), n.Nodes[3].Chain.Get_Height())
+	}
+
+	if err := n.Heal(); err != nil {
+		t.Fatalf("heal failed err %s", err)
+	}
+	if err := n.WaitForConvergence(10 * time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if top := n.Nodes[0].Chain.Get_Top_ID(); top != heavier {
+		t.Fatalf("expected heavier chain %s to win, actual top %s", heavier, top)
+	}
+}
+
+func Test_Sync_From_Scratch(t *testing.T) {
+	n := new_network(t, 1)
+	if err := n.Nodes[0].MineBlocks(12); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+
+	if _, err := n.AddNode(); err != nil {
+		t.Fatalf("cannot add node err %s", err)
+	}
+	if n.Nodes[1].Chain.Get_Height() != 0 {
+		t.Fatalf("new node must start from genesis")
+	}
+	if _, err := n.Connect(0, 1); err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+	if err := n.WaitForConvergence(20 * time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func Test_Clock_Skew(t *testing.T) {
+	n := new_network(t, 2)
+	if _, err := n.Connect(0, 1); err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+
+	n.Nodes[1].SetClockSkew(time.Minute) // node 1 mines blocks from the future
+	if err := n.Nodes[1].MineBlocks(1); err != nil {
+		t.Fatalf("mining failed err %s", err)
+	}
+	if err := n.WaitForHeight(1, time.Second); err == nil {
+		t.Fatalf("block from the future must be rejected")
+	}
+
+	n.Nodes[0].SetClockSkew(2 * time.Minute) // now node 0 lives even further in future, so it accepts the block
+	if err := n.Disconnect(0, 1); err != nil {
+		t.Fatalf("disconnect failed err %s", err)
+	}
+	if _, err := n.Connect(0, 1); err != nil {
+		t.Fatalf("connect failed err %s", err)
+	}
+	if err := n.WaitForConvergence(10 * time.Second); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2ptest
+
+import "fmt"
+import "time"
+
+import "github.com/deroproject/derohe/p2p"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// same shard counts as p2p uses while broadcasting blocks
+const DATA_SHARDS = 16
+const PARITY_SHARDS = 32
+
+// a single chain together with the p2p node serving it
+type Node struct {
+	ID    int
+	Chain *blockchain.Blockchain
+	P2P   *p2p.Node   // runs the p2p handlers for this chain
+	Miner rpc.Address // mined blocks are credited here, default is the genesis miner
+
+	network *Network
+}
+
+// shifts the clock of the node, blocks mined by it get shifted timestamps and
+// blocks received by it are judged against the shifted clock
+func (n *Node) SetClockSkew(skew time.Duration) {
+	n.Chain.Set_Clock_Skew(skew)
+}
+
+// mines a single block through Accept_new_block, similar to a getwork miner
+// miniblocks are submitted until the template turns final
+func (n *Node) Mine() (blid crypto.Hash, err error) {
+	var template_time uint64
+	submitted := 0
+	deadline := time.Now().Add(time.Minute)
+
+	for time.Now().Before(deadline) {
+		var bl block.Block
+		var mbl block.MiniBlock
+		if bl, mbl, _, _, err = n.Chain.Create_new_block_template_mining(n.Miner); err != nil {
+			return
+		}
+		if bl.Timestamp != template_time { // template has been refreshed
+			template_time = bl.Timestamp
+			submitted = 0
+		}
+		if !mbl.Final && submitted >= int(config.BLOCK_TIME-config.MINIBLOCK_HIGHDIFF) { // wait for template to pick up our miniblocks
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		submitted++
+
+		// simulator chains skip pow, however peers verify it, so find a nonce like a miner would
+		for !n.Chain.VerifyMiniblockPoW(&bl, mbl) {
+			mbl.Nonce[0]++
+		}
+
+		if _, blid, _, err = n.Chain.Accept_new_block(bl.Timestamp, mbl.Serialize()); err != nil {
+			return
+		} else if !blid.IsZero() {
+			return
+		}
+	}
+	return blid, fmt.Errorf("node %d could not mine a block", n.ID)
+}
+
+// mines count blocks one after another
+func (n *Node) MineBlocks(count int) error {
+	for i := 0; i < count; i++ {
+		if _, err := n.Mine(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncs with peers which are ahead, daemon does this every 4 secs, we do it more often to keep tests quick
+func (n *Node) sync_loop(quit chan struct{}) {
+	defer n.network.wg.Done()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		n.P2P.Sync()
+	}
+}
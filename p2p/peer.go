@@ -35,17 +35,21 @@ import "encoding/binary"
 
 // This file defines  what all needs to be responded to become a server ( handling incoming requests)
 
-var peerid uint64
 var node_tag string
 
 // get peer id
 // we make a peer id randomly at every program start
 // first call to this will give you a unique peer id
 func GetPeerID() uint64 {
-	if peerid == 0 {
+	return default_node.GetPeerID()
+}
+
+// peer id of the node, every node within a process has its own
+func (n *Node) GetPeerID() uint64 {
+	if n.peerid == 0 {
 		var buf [8]byte
 		rand.Read(buf[:])
-		peerid = binary.LittleEndian.Uint64(buf[:]) & 0x7FFFFFFFFFFFFFFF
+		n.peerid = binary.LittleEndian.Uint64(buf[:]) & 0x7FFFFFFFFFFFFFFF
 	}
-	return peerid
+	return n.peerid
 }
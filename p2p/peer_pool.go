@@ -169,6 +169,10 @@ func Peer_Add(p *Peer) {
 
 	}
 
+	if !valid_endpoint(p.Address) { // discard garbage, peers may be ip, dns names or onion addresses
+		return
+	}
+
 	if v, ok := peer_map[ParseIPNoError(p.Address)]; ok {
 		v.Lock()
 		// logger.Infof("Peer already in list adding good count")
@@ -245,7 +249,7 @@ func PeerList_Print() {
 	peer_mutex.Lock()
 	defer peer_mutex.Unlock()
 	fmt.Printf("Peer List\n")
-	fmt.Printf("%-22s %-6s %-4s   %-5s %-7s %9s %3s\n", "Remote Addr", "Active", "Good", "Fail", " State", "Height", "DIR")
+	fmt.Printf("%-22s %-6s %-4s   %-5s %8s\n", "Remote Addr", "Active", "Good", "Fail", "Score")
 
 	var list []*Peer
 	greycount := 0
@@ -265,7 +269,7 @@ func PeerList_Print() {
 		if IsAddressConnected(ParseIPNoError(list[i].Address)) {
 			connected = "ACTIVE"
 		}
-		fmt.Printf("%-22s %-6s %4d %5d \n", list[i].Address, connected, list[i].GoodCount, list[i].FailCount)
+		fmt.Printf("%-22s %-6s %4d %5d %8.2f\n", list[i].Address, connected, list[i].GoodCount, list[i].FailCount, Peer_Score(list[i].Address))
 	}
 
 	fmt.Printf("\nWhitelist size %d\n", len(peer_map)-greycount)
@@ -289,22 +293,24 @@ func find_peer_to_connect(version int) *Peer {
 	peer_mutex.Lock()
 	defer peer_mutex.Unlock()
 
-	// first search the whitelisted ones
-	for _, v := range peer_map {
-		if uint64(time.Now().Unix()) > v.BlacklistBefore && //  if ip is blacklisted skip it
-			uint64(time.Now().Unix()) > v.ConnectAfter &&
-			!IsAddressConnected(ParseIPNoError(v.Address)) && v.Whitelist && !IsAddressInBanList(ParseIPNoError(v.Address)) {
-			v.ConnectAfter = uint64(time.Now().UTC().Unix()) + 10 // minimum 10 secs gap
-			return v
+	// first search the whitelisted ones, if we donot have any white listed, choose from the greylist
+	// within a list, peer with highest score is preferred
+	for _, whitelist := range []bool{true, false} {
+		var best *Peer
+		best_score := 0.0
+		for _, v := range peer_map {
+			if uint64(time.Now().Unix()) > v.BlacklistBefore && //  if ip is blacklisted skip it
+				uint64(time.Now().Unix()) > v.ConnectAfter &&
+				!IsAddressConnected(ParseIPNoError(v.Address)) && v.Whitelist == whitelist && !IsAddressInBanList(ParseIPNoError(v.Address)) &&
+				endpoint_reachable(v.Address) {
+				if score := Peer_Score(v.Address); best == nil || score > best_score {
+					best, best_score = v, score
+				}
+			}
 		}
-	}
-	// if we donot have any white listed, choose from the greylist
-	for _, v := range peer_map {
-		if uint64(time.Now().Unix()) > v.BlacklistBefore && //  if ip is blacklisted skip it
-			uint64(time.Now().Unix()) > v.ConnectAfter &&
-			!IsAddressConnected(ParseIPNoError(v.Address)) && !v.Whitelist && !IsAddressInBanList(ParseIPNoError(v.Address)) {
-			v.ConnectAfter = uint64(time.Now().UTC().Unix()) + 10 // minimum 10 secs gap
-			return v
+		if best != nil {
+			best.ConnectAfter = uint64(time.Now().UTC().Unix()) + 10 // minimum 10 secs gap
+			return best
 		}
 	}
 
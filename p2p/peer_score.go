@@ -0,0 +1,196 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+/* this file implements peer reputation, every ip carries a score which goes up on useful behaviour
+ * and down on misbehaviour, scores decay towards zero over time
+ * if score drops below threshold, ip is banned temporarily, repeat offenders get longer bans
+ */
+import "math"
+import "sync"
+import "time"
+
+const SCORE_HALF_LIFE = 10 * time.Minute // score halves every these many minutes
+const SCORE_MAX = 100.0                  // good behaviour cannot be accumulated beyond this
+const SCORE_BAN_THRESHOLD = -100.0       // ip is banned when score drops below this
+const SCORE_BAN_MIN = uint64(10 * 60)    // first ban is 10 minutes, each next ban doubles
+const SCORE_BAN_MAX = uint64(24 * 3600)  // bans never exceed a day
+const SCORE_TIMEOUT_FLOOR = -50.0        // timeouts alone never push score below this, slow links are not misbehaviour
+
+// score changes for various events
+const (
+	SCORE_GOOD_OBJECT     = 1.0    // valid block, chunk or miniblock received
+	SCORE_HANDSHAKE_OK    = 2.0    // successful handshake
+	SCORE_DUPLICATE       = -2.0   // same object sent again by same peer
+	SCORE_TIMEOUT         = -5.0   // request or handshake timed out, see SCORE_TIMEOUT_FLOOR
+	SCORE_HANDSHAKE_ERROR = -20.0  // handshake failed or network mismatch
+	SCORE_PROTOCOL_ERROR  = -25.0  // malformed request or response
+	SCORE_INVALID_CHUNK   = -50.0  // chunk which fails verification
+	SCORE_BAD_TREESECTION = -50.0  // bootstrap tree section which is inconsistent
+	SCORE_INVALID_BLOCK   = -100.0 // block/tx which cannot be decoded or has invalid PoW
+)
+
+type peer_score struct {
+	Score   float64
+	Updated time.Time
+	Bans    int    // automatic bans so far
+	Reason  string // reason of last penalty
+}
+
+var score_map = map[string]*peer_score{} // key is ip
+var score_mutex sync.Mutex
+
+// brings score upto date by applying decay, must be called with lock held
+func (s *peer_score) decay(now time.Time) {
+	if elapsed := now.Sub(s.Updated); elapsed > 0 {
+		s.Score *= math.Pow(0.5, float64(elapsed)/float64(SCORE_HALF_LIFE))
+		s.Updated = now
+	}
+}
+
+// ban duration doubles on every automatic ban
+func score_ban_duration(bans int) uint64 {
+	if bans >= 8 {
+		return SCORE_BAN_MAX
+	}
+	if d := SCORE_BAN_MIN << uint(bans); d < SCORE_BAN_MAX {
+		return d
+	}
+	return SCORE_BAN_MAX
+}
+
+// ips which are seed nodes or provided on command line are never banned automatically
+func is_nonbannable(ip string) bool {
+	for i := range nonbanlist {
+		if ip == ParseIPNoError(nonbanlist[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns current score of an ip or endpoint
+func Peer_Score(address string) float64 {
+	score_mutex.Lock()
+	defer score_mutex.Unlock()
+	if s, ok := score_map[ParseIPNoError(address)]; ok {
+		s.decay(time.Now())
+		return s.Score
+	}
+	return 0
+}
+
+// updates score of an ip, if score drops below threshold, ip is banned and ban duration is returned
+func Peer_Score_Update(address string, delta float64, reason string) (ban_seconds uint64) {
+	return score_update(ParseIPNoError(address), delta, reason, time.Now())
+}
+
+func score_update(ip string, delta float64, reason string, now time.Time) (ban_seconds uint64) {
+	if ip == "" {
+		return
+	}
+	score_mutex.Lock()
+	defer score_mutex.Unlock()
+
+	s := score_get(ip, now)
+	s.Score = math.Min(s.Score+delta, SCORE_MAX)
+	if delta < 0 {
+		s.Reason = reason
+	}
+
+	if s.Score < SCORE_BAN_THRESHOLD && !is_nonbannable(ip) {
+		ban_seconds = score_ban_duration(s.Bans)
+		s.Bans++
+		s.Score = 0 // peer starts afresh after serving the ban
+		if err := Ban_Address(ip, ban_seconds); err != nil {
+			return 0
+		}
+	}
+	return
+}
+
+// penalizes an ip for a timeout, timeouts lower the score only till SCORE_TIMEOUT_FLOOR, so they never ban on their own
+func score_timeout(ip string, reason string, now time.Time) {
+	if ip == "" {
+		return
+	}
+	score_mutex.Lock()
+	defer score_mutex.Unlock()
+
+	s := score_get(ip, now)
+	if s.Score > SCORE_TIMEOUT_FLOOR {
+		s.Score = math.Max(s.Score+SCORE_TIMEOUT, SCORE_TIMEOUT_FLOOR)
+	}
+	s.Reason = reason
+}
+
+// returns decayed score of an ip, creating it if required, must be called with lock held
+func score_get(ip string, now time.Time) *peer_score {
+	s, ok := score_map[ip]
+	if !ok {
+		s = &peer_score{Updated: now}
+		score_map[ip] = s
+	}
+	s.decay(now)
+	return s
+}
+
+// change score of the connection, connection is terminated if it gets banned
+func (c *Connection) score(delta float64, reason string) {
+	if delta < 0 && c.SyncNode { // user explicitly asked for this peer
+		return
+	}
+	if ban_seconds := Peer_Score_Update(Address(c), delta, reason); ban_seconds > 0 {
+		c.logger.Info("peer banned due to misbehaviour", "reason", reason, "seconds", ban_seconds)
+		c.exit()
+	}
+}
+
+// penalizes the connection for a timeout, this never bans the peer
+func (c *Connection) timeout(reason string) {
+	if c.SyncNode { // user explicitly asked for this peer
+		return
+	}
+	score_timeout(ParseIPNoError(Address(c)), reason, time.Now())
+}
+
+// tracks objects received from this connection, so as floods of same object can be detected
+func (c *Connection) is_duplicate(hash [32]byte) bool {
+	c.seen_mutex.Lock()
+	defer c.seen_mutex.Unlock()
+	if c.seen == nil || len(c.seen) > 4096 {
+		c.seen = map[[32]byte]struct{}{}
+	}
+	if _, ok := c.seen[hash]; ok {
+		return true
+	}
+	c.seen[hash] = struct{}{}
+	return false
+}
+
+// discards scores which have decayed to nothing
+func score_clean_up() {
+	score_mutex.Lock()
+	defer score_mutex.Unlock()
+	now := time.Now()
+	for k, s := range score_map {
+		s.decay(now)
+		if math.Abs(s.Score) < 1 && (s.Bans == 0 || now.Sub(s.Updated) > 24*time.Hour) {
+			delete(score_map, k)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "time"
+import "math"
+import "testing"
+
+import "github.com/go-logr/logr"
+
+func Test_Peer_Score(t *testing.T) {
+	logger = logr.Discard()
+	now := time.Now()
+	ip := "192.0.2.10"
+	defer UnBan_Address(ip)
+
+	score_update(ip, -60, "test", now)
+	score_mutex.Lock()
+	s := score_map[ip]
+	s.decay(now.Add(SCORE_HALF_LIFE))
+	decayed := s.Score
+	score_mutex.Unlock()
+	if math.Abs(decayed+30) > 0.001 {
+		t.Fatalf("score must halve after half life, actual %f", decayed)
+	}
+
+	if ban := score_update(ip, SCORE_INVALID_BLOCK, "invalid block", now.Add(SCORE_HALF_LIFE)); ban != SCORE_BAN_MIN {
+		t.Fatalf("peer must be banned for %d secs, actual %d", SCORE_BAN_MIN, ban)
+	}
+	if !IsAddressInBanList(ip) {
+		t.Fatalf("peer must be in ban list")
+	}
+	if ban := score_update(ip, 2*SCORE_INVALID_BLOCK, "invalid block", now.Add(SCORE_HALF_LIFE)); ban != 2*SCORE_BAN_MIN {
+		t.Fatalf("repeat offenders must get longer bans, actual %d", ban)
+	}
+
+	if score_update(ip, 1000, "", now.Add(SCORE_HALF_LIFE)); Peer_Score(ip) > SCORE_MAX {
+		t.Fatalf("score cannot exceed %f", SCORE_MAX)
+	}
+
+	if score_ban_duration(100) != SCORE_BAN_MAX {
+		t.Fatalf("ban duration must be capped")
+	}
+}
+
+func Test_Peer_Score_Nonbannable(t *testing.T) {
+	ip := "192.0.2.11"
+	nonbanlist = append(nonbanlist, ip+":10101")
+	defer func() { nonbanlist = nonbanlist[:len(nonbanlist)-1] }()
+
+	if ban := score_update(ip, 10*SCORE_INVALID_BLOCK, "invalid block", time.Now()); ban != 0 {
+		t.Fatalf("seed and priority nodes must never be banned")
+	}
+}
+
+// timeouts alone never ban, however they still count when combined with misbehaviour
+func Test_Peer_Score_Timeout(t *testing.T) {
+	logger = logr.Discard()
+	now := time.Now()
+	ip := "192.0.2.12"
+	defer UnBan_Address(ip)
+
+	for i := 0; i < 100; i++ {
+		score_timeout(ip, "handshake timeout", now)
+	}
+	if IsAddressInBanList(ip) {
+		t.Fatalf("timeouts alone must not ban")
+	}
+	if score := Peer_Score(ip); score < SCORE_TIMEOUT_FLOOR || score > SCORE_TIMEOUT_FLOOR+1 {
+		t.Fatalf("timeouts must stop at floor, actual %f", score)
+	}
+
+	if ban := score_update(ip, 2*SCORE_PROTOCOL_ERROR-1, "protocol error", now); ban == 0 {
+		t.Fatalf("misbehaviour on top of timeouts must ban")
+	}
+}
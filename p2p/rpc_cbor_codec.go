@@ -31,26 +31,38 @@ var bufPool = &sync.Pool{
 
 // reads our data, length prefix blocks
 func Read_Data_Frame(r net.Conn, obj interface{}) error {
+	_, err := read_data_frame(r, obj, nil)
+	return err
+}
+
+// reads a frame and returns number of bytes read
+// if limit is not nil, it is called with the frame size before the body is read, so it can wait for download capacity
+func read_data_frame(r net.Conn, obj interface{}, limit func(int)) (int, error) {
 	var frame_length_buf [4]byte
 
 	//connection.set_timeout()
 	r.SetReadDeadline(time.Now().Add(READ_TIMEOUT))
 	nbyte, err := io.ReadFull(r, frame_length_buf[:])
 	if err != nil {
-		return err
+		return nbyte, err
 	}
 	if nbyte != 4 {
-		return fmt.Errorf("needed 4 bytes, but got %d bytes", nbyte)
+		return nbyte, fmt.Errorf("needed 4 bytes, but got %d bytes", nbyte)
 	}
 
 	//  time to ban
 	frame_length := binary.LittleEndian.Uint32(frame_length_buf[:])
 	if frame_length == 0 {
-		return nil
+		return 4, nil
 	}
 	// most probably memory DDOS attack, kill the connection
 	if uint64(frame_length) > (5 * config.STARGATE_HE_MAX_BLOCK_SIZE) {
-		return fmt.Errorf("Frame length is too big Expected %d Actual %d", 5*config.STARGATE_HE_MAX_BLOCK_SIZE, frame_length)
+		return 4, fmt.Errorf("Frame length is too big Expected %d Actual %d", 5*config.STARGATE_HE_MAX_BLOCK_SIZE, frame_length)
+	}
+
+	if limit != nil {
+		limit(4 + int(frame_length))
+		r.SetReadDeadline(time.Now().Add(READ_TIMEOUT)) // time spent waiting must not count against the peer
 	}
 
 	buf := bufPool.Get().(*bytes.Buffer)
@@ -62,22 +74,27 @@ func Read_Data_Frame(r net.Conn, obj interface{}) error {
 	data_buf = data_buf[:frame_length]
 	data_size, err := io.ReadFull(r, data_buf)
 	if err != nil || data_size <= 0 || uint32(data_size) != frame_length {
-		return fmt.Errorf("Could not read data size  read %d, frame length %d err %s", data_size, frame_length, err)
+		return 4 + data_size, fmt.Errorf("Could not read data size  read %d, frame length %d err %s", data_size, frame_length, err)
 	}
 	data_buf = data_buf[:frame_length]
 	err = cbor.Unmarshal(data_buf, obj)
 
 	//fmt.Printf("Read object %+v raw %s\n",obj, data_buf)
-	return err
+	return 4 + data_size, err
 }
 
 // reads our data, length prefix blocks
 func Write_Data_Frame(w net.Conn, obj interface{}) error {
-	var frame_length_buf [4]byte
 	data_bytes, err := cbor.Marshal(obj)
 	if err != nil {
 		return err
 	}
+	return write_data_frame(w, data_bytes)
+}
+
+// writes an already serialized frame
+func write_data_frame(w net.Conn, data_bytes []byte) (err error) {
+	var frame_length_buf [4]byte
 	binary.LittleEndian.PutUint32(frame_length_buf[:], uint32(len(data_bytes)))
 
 	w.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
@@ -91,7 +108,10 @@ func Write_Data_Frame(w net.Conn, obj interface{}) error {
 
 // ClientCodec implements the rpc.ClientCodec interface for generic golang objects.
 type ClientCodec struct {
-	r net.Conn
+	r         net.Conn
+	bandwidth *peer_bandwidth
+	pending   map[uint64]string // requests being served, seq -> method, used to classify responses
+	pmutex    sync.Mutex
 	sync.Mutex
 }
 
@@ -99,7 +119,45 @@ type ClientCodec struct {
 // on the other end of the conn.
 // to support deadlines we use net.conn
 func NewCBORCodec(conn net.Conn) *ClientCodec {
-	return &ClientCodec{r: conn}
+	ip := ""
+	if conn.RemoteAddr() != nil {
+		ip = ParseIPNoError(conn.RemoteAddr().String())
+	}
+	return &ClientCodec{r: conn, bandwidth: new_peer_bandwidth(ip), pending: map[uint64]string{}}
+}
+
+// reads a frame and accounts it
+func (c *ClientCodec) read(obj interface{}) error {
+	n, err := read_data_frame(c.r, obj, c.bandwidth.before_read)
+	if n > 0 {
+		c.bandwidth.after_read(n)
+	}
+	return err
+}
+
+// serializes all frames, waits for bandwidth and then writes them together
+func (c *ClientCodec) write(method string, objs ...interface{}) error {
+	var frames [][]byte
+	size := 0
+	for _, obj := range objs {
+		data_bytes, err := cbor.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		frames = append(frames, data_bytes)
+		size += 4 + len(data_bytes)
+	}
+
+	c.bandwidth.before_write(method, size) // must not hold lock, so others are not stuck behind us
+
+	c.Lock()
+	defer c.Unlock()
+	for _, data_bytes := range frames {
+		if err := write_data_frame(c.r, data_bytes); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ReadResponseHeader reads a 4 byte length from the connection and decodes that many
@@ -107,7 +165,7 @@ func NewCBORCodec(conn net.Conn) *ClientCodec {
 // in the given request.
 func (c *ClientCodec) ReadResponseHeader(resp *rpc2.Response) error {
 	var header RequestResponse
-	if err := Read_Data_Frame(c.r, &header); err != nil {
+	if err := c.read(&header); err != nil {
 		return err
 	}
 	//if header.Method == "" {
@@ -122,6 +180,7 @@ func (c *ClientCodec) ReadResponseHeader(resp *rpc2.Response) error {
 
 // Close closes the underlying connection.
 func (c *ClientCodec) Close() error {
+	c.bandwidth.close()
 	return c.r.Close()
 }
 
@@ -130,13 +189,16 @@ func (c *ClientCodec) Close() error {
 // in the given request.
 func (s *ClientCodec) ReadHeader(req *rpc2.Request, resp *rpc2.Response) error {
 	var header RequestResponse
-	if err := Read_Data_Frame(s.r, &header); err != nil {
+	if err := s.read(&header); err != nil {
 		return err
 	}
 
 	if header.Method != "" {
 		req.Seq = header.Seq
 		req.Method = header.Method
+		s.pmutex.Lock()
+		s.pending[header.Seq] = header.Method
+		s.pmutex.Unlock()
 	} else {
 		resp.Seq = header.Seq
 		resp.Error = header.Error
@@ -150,7 +212,7 @@ func (s *ClientCodec) ReadRequestBody(obj interface{}) error {
 	if obj == nil {
 		return nil
 	}
-	return Read_Data_Frame(s.r, obj)
+	return s.read(obj)
 }
 
 // ReadResponseBody reads a 4 byte length from the connection and decodes that many
@@ -160,36 +222,28 @@ func (c *ClientCodec) ReadResponseBody(obj interface{}) error {
 	if obj == nil {
 		return nil
 	}
-	return Read_Data_Frame(c.r, obj)
+	return c.read(obj)
 }
 
 // WriteRequest writes the 4 byte length from the connection and encodes that many
 // subsequent bytes into the given object.
 func (c *ClientCodec) WriteRequest(req *rpc2.Request, obj interface{}) error {
-	c.Lock()
-	defer c.Unlock()
-
 	header := RequestResponse{Method: req.Method, Seq: req.Seq}
-	if err := Write_Data_Frame(c.r, header); err != nil {
-		return err
-	}
-	return Write_Data_Frame(c.r, obj)
+	return c.write(req.Method, header, obj)
 }
 
 // WriteResponse writes the appropriate header. If
 // the response was invalid, the size of the body of the resp is reported as
 // having size zero and is not sent.
 func (c *ClientCodec) WriteResponse(resp *rpc2.Response, obj interface{}) error {
-	c.Lock()
-	defer c.Unlock()
-	header := RequestResponse{Seq: resp.Seq, Error: resp.Error}
-	if err := Write_Data_Frame(c.r, header); err != nil {
-		return err
-	}
+	c.pmutex.Lock()
+	method := c.pending[resp.Seq]
+	delete(c.pending, resp.Seq)
+	c.pmutex.Unlock()
 
+	header := RequestResponse{Seq: resp.Seq, Error: resp.Error}
 	if resp.Error == "" { // only write response object if error is nil
-		return Write_Data_Frame(c.r, obj)
+		return c.write(method, header, obj)
 	}
-
-	return nil
+	return c.write(method, header)
 }
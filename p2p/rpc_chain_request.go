@@ -30,6 +30,7 @@ import "github.com/deroproject/derohe/globals"
 
 func (c *Connection) Chain(request Chain_Request_Struct, response *Chain_Response_Struct) error {
 	defer handle_connection_panic(c)
+	chain := c.node.chain
 	if len(request.Block_list) < 1 { // malformed request ban peer
 		c.logger.V(3).Info("malformed chain request  received, banning peer", "request", request)
 		c.exit()
@@ -86,8 +87,8 @@ func (c *Connection) Chain(request Chain_Request_Struct, response *Chain_Respons
 
 	response.Start_height = start_height
 	response.Start_topoheight = start_topoheight
-	fill_common(&response.Common) // fill common info
-	c.update(&request.Common)     // update common information
+	c.node.fill_common(&response.Common) // fill common info
+	c.update(&request.Common)            // update common information
 
 	return nil
 }
@@ -28,6 +28,7 @@ const max_request_topoheights = 50
 // notifies inventory
 func (c *Connection) ChangeSet(request ChangeList, response *Changes) (err error) {
 	defer handle_connection_panic(c)
+	chain := c.node.chain
 	if len(request.TopoHeights) < 1 || len(request.TopoHeights) > max_request_topoheights { // we are expecting 1 block or 1 tx
 		c.logger.V(1).Info("malformed object request received, banning peer", "request", request)
 		c.exit()
@@ -128,7 +129,7 @@ func (c *Connection) ChangeSet(request ChangeList, response *Changes) (err error
 	}
 
 	// if everything is OK, we must respond with object response
-	fill_common(&response.Common) // fill common info
+	c.node.fill_common(&response.Common) // fill common info
 
 	return nil
 }
@@ -45,16 +45,16 @@ func Verify_Handshake(handshake *Handshake_Struct) bool {
 	return bytes.Equal(handshake.Network_ID[:], globals.Config.Network_ID[:])
 }
 
-func (handshake *Handshake_Struct) Fill() {
-	fill_common(&handshake.Common) // fill common info
+func (handshake *Handshake_Struct) Fill(n *Node) {
+	n.fill_common(&handshake.Common) // fill common info
 
 	handshake.ProtocolVersion = "1.0.0"
 	handshake.DaemonVersion = config.Version.String()
 	handshake.Tag = node_tag
 	handshake.UTC_Time = int64(time.Now().UTC().Unix()) // send our UTC time
 	handshake.Local_Port = uint32(P2P_Port)             // export requested or default port
-	handshake.Peer_ID = GetPeerID()                     // give our randomly generated peer id
-	handshake.Pruned = chain.LocatePruneTopo()
+	handshake.Peer_ID = n.GetPeerID()                   // give our randomly generated peer id
+	handshake.Pruned = n.chain.LocatePruneTopo()
 
 	//	handshake.Flags = // add any flags necessary
 
@@ -66,7 +66,7 @@ func (handshake *Handshake_Struct) Fill() {
 func (connection *Connection) dispatch_test_handshake() {
 	defer handle_connection_panic(connection)
 	var request, response Handshake_Struct
-	request.Fill()
+	request.Fill(connection.node)
 
 	//scan our peer list and send peers which have been recently communicated
 	request.PeerList = get_peer_list_specific(Address(connection))
@@ -75,12 +75,18 @@ func (connection *Connection) dispatch_test_handshake() {
 	defer cancel()
 	if err := connection.Client.CallWithContext(ctx, "Peer.Handshake", request, &response); err != nil {
 		connection.logger.V(4).Error(err, "cannot handshake")
+		if ctx.Err() != nil { // slow or congested links are not penalized like failed handshakes
+			connection.timeout("handshake timeout")
+		} else {
+			connection.score(SCORE_HANDSHAKE_ERROR, "handshake failed")
+		}
 		connection.exit()
 		return
 	}
 
 	if !Verify_Handshake(&response) { // if not same network boot off
 		connection.logger.V(3).Info("terminating connection network id mismatch ", "networkid", response.Network_ID)
+		connection.score(SCORE_HANDSHAKE_ERROR, "network id mismatch")
 		connection.exit()
 		return
 	}
@@ -131,6 +137,7 @@ func (connection *Connection) dispatch_test_handshake() {
 		}
 	}
 
+	connection.score(SCORE_HANDSHAKE_OK, "")
 	atomic.StoreUint32(&connection.State, ACTIVE)
 }
 
@@ -139,7 +146,7 @@ func (c *Connection) Ping(request Dummy, response *Dummy) error {
 	defer handle_connection_panic(c)
 	fill_common_T1(&request.Common)
 	c.update(&request.Common)                             // update common information
-	fill_common(&response.Common)                         // fill common info
+	c.node.fill_common(&response.Common)                  // fill common info
 	fill_common_T0T1T2(&request.Common, &response.Common) // fill time related information
 	return nil
 }
@@ -147,7 +154,7 @@ func (c *Connection) Ping(request Dummy, response *Dummy) error {
 // serves handhake requests
 func (c *Connection) Handshake(request Handshake_Struct, response *Handshake_Struct) error {
 	defer handle_connection_panic(c)
-	if request.Peer_ID == GetPeerID() { // check if self connection exit
+	if request.Peer_ID == c.node.GetPeerID() { // check if self connection exit
 		//rlog.Tracef(1, "Same peer ID, probably self connection, disconnecting from this client")
 		c.exit()
 		return fmt.Errorf("Same peer ID")
@@ -155,11 +162,12 @@ func (c *Connection) Handshake(request Handshake_Struct, response *Handshake_Str
 
 	if !Verify_Handshake(&request) { // if not same network boot off
 		logger.V(2).Info("kill connection network id mismatch peer network id.", "Network_ID", request.Network_ID)
+		c.score(SCORE_HANDSHAKE_ERROR, "network id mismatch")
 		c.exit()
 		return fmt.Errorf("NID mismatch")
 	}
 
-	response.Fill()
+	response.Fill(c.node)
 
 	c.update(&request.Common) // update common information
 	if c.State == ACTIVE {
@@ -31,6 +31,7 @@ import "github.com/deroproject/derohe/globals"
 // handles notifications of inventory
 func (c *Connection) NotifyINV(request ObjectList, response *Dummy) (err error) {
 	defer handle_connection_panic(c)
+	chain := c.node.chain
 	var need ObjectList
 	var dirty = false
 
@@ -79,7 +80,7 @@ func (c *Connection) NotifyINV(request ObjectList, response *Dummy) (err error)
 			}
 
 			if !chain.Block_Exists(blid) { // check whether the block can be loaded from disk
-				if nil == is_chunk_exist(hhash, cid) { // if chunk does not exist
+				if nil == c.node.is_chunk_exist(hhash, cid) { // if chunk does not exist
 					c.logger.V(3).Info("requesting INV chunk", "blid", fmt.Sprintf("%x", blid), "cid", cid, "hhash", fmt.Sprintf("%x", hhash), "raw", fmt.Sprintf("%x", request.Chunk_list[i]))
 					need.Chunk_list = append(need.Chunk_list, request.Chunk_list[i])
 					dirty = true
@@ -90,7 +91,7 @@ func (c *Connection) NotifyINV(request ObjectList, response *Dummy) (err error)
 
 	if dirty { //  request inventory only if we want it
 		var oresponse Objects
-		fill_common(&need.Common) // fill common info
+		c.node.fill_common(&need.Common) // fill common info
 		if err = c.Client.Call("Peer.GetObject", need, &oresponse); err != nil {
 			c.logger.V(2).Error(err, "Call failed GetObject", "need_objects", need)
 			c.exit()
@@ -102,8 +103,8 @@ func (c *Connection) NotifyINV(request ObjectList, response *Dummy) (err error)
 		}
 	}
 
-	c.update(&request.Common)     // update common information
-	fill_common(&response.Common) // fill common info
+	c.update(&request.Common)            // update common information
+	c.node.fill_common(&response.Common) // fill common info
 
 	return nil
 
@@ -112,9 +113,11 @@ func (c *Connection) NotifyINV(request ObjectList, response *Dummy) (err error)
 // only miniblocks carry extra info, which leads to better time tracking
 func (c *Connection) NotifyMiniBlock(request Objects, response *Dummy) (err error) {
 	defer handle_connection_panic(c)
+	chain := c.node.chain
 	if len(request.MiniBlocks) >= 5 {
 		err = fmt.Errorf("Notify Block can notify max 5 miniblocks")
 		c.logger.V(3).Error(err, "Should be banned")
+		c.score(SCORE_PROTOCOL_ERROR, "too many miniblocks")
 		c.exit()
 		return err
 	}
@@ -152,6 +155,11 @@ func (c *Connection) NotifyMiniBlock(request Objects, response *Dummy) (err erro
 			metrics.Set.GetOrCreateHistogram("miniblock_propagation_duration_histogram_seconds").Update(time_to_receive)
 		}
 
+		if c.is_duplicate(mbl.GetHash()) { // same peer sending same miniblock again
+			c.score(SCORE_DUPLICATE, "duplicate miniblock")
+			continue
+		}
+
 		// first check whether it is already in the chain
 		if chain.MiniBlocks.IsCollision(mbl) {
 			continue // miniblock already in chain, so skip it
@@ -187,25 +195,28 @@ func (c *Connection) NotifyMiniBlock(request Objects, response *Dummy) (err erro
 
 		// lets get the difficulty at tips
 		if !chain.VerifyMiniblockPoW(&bl, mbl) {
+			c.score(SCORE_INVALID_BLOCK, "miniblock with invalid pow")
 			return errormsg.ErrInvalidPoW
 		}
 
 		if err, ok = chain.InsertMiniBlock(mbl); !ok {
 			return err
 		} else { // rebroadcast miniblock
+			c.score(SCORE_GOOD_OBJECT, "")
 			valid_found = true
 			if valid_found {
-				broadcast_MiniBlock(mbl, c.Peer_ID, request.Sent) // do not send back to the original peer
+				c.node.broadcast_MiniBlock(mbl, c.Peer_ID, request.Sent) // do not send back to the original peer
 			}
 		}
 	}
 
-	fill_common(&response.Common)                         // fill common info
+	c.node.fill_common(&response.Common)                  // fill common info
 	fill_common_T0T1T2(&request.Common, &response.Common) // fill time related information
 	return nil
 }
 
 func (c *Connection) processChunkedBlock(request Objects, data_shard_count, parity_shard_count int) error {
+	chain := c.node.chain
 	var err error
 
 	var cbl block.Complete_Block // parse incoming block and deserialize it
@@ -215,6 +226,7 @@ func (c *Connection) processChunkedBlock(request Objects, data_shard_count, pari
 	err = bl.Deserialize(request.CBlocks[0].Block)
 	if err != nil { // we have a block which could not be deserialized ban peer
 		c.logger.V(3).Error(err, "Block cannot be deserialized.Should be banned")
+		c.score(SCORE_INVALID_BLOCK, "undecodable block")
 		c.exit()
 		return err
 	}
@@ -234,6 +246,7 @@ func (c *Connection) processChunkedBlock(request Objects, data_shard_count, pari
 			err = tx.Deserialize(request.CBlocks[0].Txs[j])
 			if err != nil { // we have a tx which could not be deserialized ban peer
 				c.logger.V(3).Error(err, "tx cannot be deserialized.Should be banned")
+				c.score(SCORE_INVALID_BLOCK, "undecodable tx")
 				c.exit()
 				return err
 			}
@@ -249,10 +262,12 @@ func (c *Connection) processChunkedBlock(request Objects, data_shard_count, pari
 	// check if we can add ourselves to chain
 	if err, ok := chain.Add_Complete_Block(&cbl); ok { // if block addition was successfil
 		// notify all peers
-		Broadcast_Block(&cbl, c.Peer_ID) // do not send back to the original peer
+		c.score(SCORE_GOOD_OBJECT, "")
+		c.node.Broadcast_Block(&cbl, c.Peer_ID) // do not send back to the original peer
 	} else { // ban the peer for sometime
 		if err == errormsg.ErrInvalidPoW {
 			c.logger.Error(err, "This peer should be banned and terminated")
+			c.score(SCORE_INVALID_BLOCK, "invalid pow")
 			c.exit()
 			return err
 		}
@@ -23,15 +23,18 @@ import "fmt"
 // an object is either a block or a tx
 func (connection *Connection) GetObject(request ObjectList, response *Objects) error {
 	defer handle_connection_panic(connection)
+	chain := connection.node.chain
 	var err error
 	if len(request.Block_list) < 1 && len(request.Tx_list) < 1 && len(request.Chunk_list) < 1 { // we are expecting 1 block or 1 tx
 		connection.logger.V(2).Info("malformed object request  received, banning peer", "request", request)
+		connection.score(SCORE_PROTOCOL_ERROR, "malformed object request")
 		connection.exit()
 		return nil
 	}
 
 	if len(request.Block_list) > 4096 || len(request.Tx_list) > 4096 || len(request.Chunk_list) > 128 || len(request.Block_list)+len(request.Tx_list)+len(request.Chunk_list) > 4096 { // we are expecting max 4096 items
 		connection.logger.V(2).Info("malformed object request  received, banning peer", "request", request)
+		connection.score(SCORE_PROTOCOL_ERROR, "malformed object request")
 		connection.exit()
 		return nil
 	}
@@ -74,7 +77,7 @@ func (connection *Connection) GetObject(request ObjectList, response *Objects) e
 		cid := request.Chunk_list[i][32]
 		copy(hhash[:], request.Chunk_list[i][33:])
 
-		if chunk := is_chunk_exist(hhash, cid); chunk == nil {
+		if chunk := connection.node.is_chunk_exist(hhash, cid); chunk == nil {
 			return fmt.Errorf("no such chunk %x %x %x cid %d %2x", blid, cid, hhash, cid, cid)
 		} else { // we do have the chunk, pass it on
 			response.Chunks = append(response.Chunks, *chunk) // append the chunk
@@ -82,7 +85,7 @@ func (connection *Connection) GetObject(request ObjectList, response *Objects) e
 	}
 
 	// if everything is OK, we must respond with object response
-	fill_common(&response.Common) // fill common info
+	connection.node.fill_common(&response.Common) // fill common info
 	response.Sent = request.Sent
 
 	//rlog.Tracef(3, "OBJECT RESPONSE SENT  sent size %d %s", len(serialized), connection.logid)
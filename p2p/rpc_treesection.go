@@ -21,8 +21,10 @@ import "github.com/deroproject/graviton"
 // get parts of the specified balance tree chunk by chunk
 func (c *Connection) TreeSection(request Request_Tree_Section_Struct, response *Response_Tree_Section_Struct) (err error) {
 	defer handle_connection_panic(c)
+	chain := c.node.chain
 	if request.Topo < 2 || request.SectionLength > 256 || len(request.Section) < int(request.SectionLength/8) { // we are expecting 1 block or 1 tx
 		c.logger.V(1).Info("malformed object request  received, banning peer", "request", request)
+		c.score(SCORE_PROTOCOL_ERROR, "malformed tree section request")
 		c.exit()
 	}
 
@@ -62,7 +64,7 @@ func (c *Connection) TreeSection(request Request_Tree_Section_Struct, response *
 	}
 
 	// if everything is OK, we must respond with object response
-	fill_common(&response.Common) // fill common info
+	c.node.fill_common(&response.Common) // fill common info
 	return nil
 
 }
@@ -0,0 +1,247 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+// this file gathers seed nodes, sources are tried in following order
+// dns seeds (A/AAAA/TXT records), signed peer list file and lastly the hardcoded list from config
+import "fmt"
+import "net"
+import "sync"
+import "time"
+import "bytes"
+import "strconv"
+import "strings"
+import "io/ioutil"
+import "crypto/rand"
+import "math/big"
+
+import "github.com/miekg/dns"
+import "golang.org/x/crypto/openpgp"
+import "golang.org/x/crypto/openpgp/clearsign"
+
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/globals"
+
+var seed_mutex sync.Mutex
+var seed_dns []string    // resolved from dns seeds
+var seed_signed []string // from signed peer list file
+
+// returns seed nodes, dns seeds are preferred and the hardcoded list is the last fallback
+func seed_nodes() (list []string) {
+	seed_mutex.Lock()
+	list = append(list, seed_dns...)
+	list = append(list, seed_signed...)
+	seed_mutex.Unlock()
+
+	if len(list) == 0 {
+		if globals.IsMainnet() {
+			list = append(list, config.Mainnet_seed_nodes...)
+		} else {
+			list = append(list, config.Testnet_seed_nodes...)
+		}
+	}
+	return
+}
+
+// chooses a random seed node
+func random_seed_node() string {
+	list := seed_nodes()
+	if len(list) == 0 {
+		return ""
+	}
+	r, _ := rand.Int(rand.Reader, big.NewInt(int64(len(list))))
+	return list[r.Int64()]
+}
+
+// dns seeds to be resolved, user supplied seeds override config
+func dns_seeds() []string {
+	if globals.Arguments["--dns-seed"] != nil {
+		if list := globals.Arguments["--dns-seed"].([]string); len(list) > 0 {
+			return list
+		}
+	}
+	if globals.IsMainnet() {
+		return config.Mainnet_DNS_seeds
+	}
+	return config.Testnet_DNS_seeds
+}
+
+// refreshes seed nodes from dns seeds and signed peer list file
+func refresh_seed_nodes() {
+	var resolved []string
+	for _, seed := range dns_seeds() {
+		server := config.DNS_servers[random_index(len(config.DNS_servers))]
+		list, err := resolve_dns_seed(server, seed, globals.Config.P2P_Default_Port)
+		if err != nil {
+			logger.V(1).Error(err, "could not resolve dns seed", "seed", seed, "server", server)
+			continue
+		}
+		logger.V(1).Info("resolved dns seed", "seed", seed, "count", len(list))
+		resolved = append(resolved, list...)
+	}
+
+	var signed []string
+	if globals.Arguments["--peer-list-file"] != nil {
+		filename := globals.Arguments["--peer-list-file"].(string)
+		if data, err := ioutil.ReadFile(filename); err != nil {
+			logger.Error(err, "could not read peer list file", "file", filename)
+		} else if signed, err = verify_signed_peer_list(data, config.Peer_List_Signing_Key); err != nil {
+			logger.Error(err, "peer list file rejected", "file", filename)
+		} else {
+			logger.V(1).Info("loaded signed peer list", "file", filename, "count", len(signed))
+		}
+	}
+
+	seed_mutex.Lock()
+	seed_dns = dedup_endpoints(resolved)
+	seed_signed = dedup_endpoints(signed)
+	seed_mutex.Unlock()
+
+	for _, endpoint := range signed { // signed seeds are trusted like hardcoded ones
+		add_nonban(endpoint)
+	}
+}
+
+func random_index(n int) int {
+	r, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	return int(r.Int64())
+}
+
+func add_nonban(endpoint string) {
+	endpoint = strings.ToLower(endpoint)
+	ban_mutex.Lock()
+	defer ban_mutex.Unlock()
+	for _, e := range nonbanlist {
+		if e == endpoint {
+			return
+		}
+	}
+	nonbanlist = append(nonbanlist, endpoint)
+}
+
+func dedup_endpoints(list []string) (result []string) {
+	seen := map[string]bool{}
+	for _, e := range list {
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+	return
+}
+
+// resolves A, AAAA and TXT records of a dns seed using given dns server
+// queries are made over tcp, so they work through socks proxy too
+func resolve_dns_seed(server string, seed string, port int) (list []string, err error) {
+	seed = dns.Fqdn(seed)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeTXT} {
+		var answers []dns.RR
+		if answers, err = dns_query(server, seed, qtype); err != nil {
+			return nil, err
+		}
+		for _, rr := range answers {
+			switch r := rr.(type) {
+			case *dns.A:
+				list = append(list, net.JoinHostPort(r.A.String(), strconv.Itoa(port)))
+			case *dns.AAAA:
+				list = append(list, net.JoinHostPort(r.AAAA.String(), strconv.Itoa(port)))
+			case *dns.TXT: // entries separated by space or comma, invalid ones are skipped
+				for _, field := range strings.FieldsFunc(strings.Join(r.Txt, " "), func(c rune) bool { return c == ' ' || c == ',' }) {
+					if endpoint, err := parse_endpoint(field); err == nil {
+						list = append(list, endpoint)
+					}
+				}
+			}
+		}
+	}
+	return dedup_endpoints(list), nil
+}
+
+func dns_query(server string, name string, qtype uint16) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = true
+
+	conn, err := globals.Dialer.Dial("tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	co := &dns.Conn{Conn: conn}
+	if err = co.WriteMsg(m); err != nil {
+		return nil, err
+	}
+	r, err := co.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	if r.Id != m.Id {
+		return nil, fmt.Errorf("dns id mismatch")
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("dns query failed rcode %s", dns.RcodeToString[r.Rcode])
+	}
+	return r.Answer, nil
+}
+
+// checks ip:port form and normalizes it
+func parse_endpoint(s string) (string, error) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(s))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", fmt.Errorf("invalid ip %q", host)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
+// verifies a clearsigned peer list and returns the endpoints, one ip:port per line
+// empty lines and lines starting with # are ignored
+func verify_signed_peer_list(data []byte, armored_key string) (list []string, err error) {
+	block, _ := clearsign.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("peer list is not clearsigned")
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored_key))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(block.Plaintext), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		endpoint, err := parse_endpoint(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry %q in peer list: %s", line, err)
+		}
+		list = append(list, endpoint)
+	}
+	return dedup_endpoints(list), nil
+}
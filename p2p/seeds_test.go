@@ -0,0 +1,145 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "net"
+import "bytes"
+import "sort"
+import "strings"
+import "testing"
+
+import "github.com/miekg/dns"
+import "golang.org/x/crypto/openpgp"
+import "golang.org/x/crypto/openpgp/armor"
+import "golang.org/x/crypto/openpgp/clearsign"
+
+import "github.com/deroproject/derohe/config"
+
+// local dns stand-in, answers only for seed.test.
+func dns_standin(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen err %s", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if q.Name != "seed.test." {
+			m.Rcode = dns.RcodeNameError
+			w.WriteMsg(m)
+			return
+		}
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+		switch q.Qtype {
+		case dns.TypeA:
+			m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.ParseIP("192.0.2.1")}, &dns.A{Hdr: hdr, A: net.ParseIP("192.0.2.2")})
+		case dns.TypeAAAA:
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP("2001:db8::1")})
+		case dns.TypeTXT:
+			m.Answer = append(m.Answer, &dns.TXT{Hdr: hdr, Txt: []string{"198.51.100.7:11011,192.0.2.1:18089", " garbage 203.0.113.9:40401"}})
+		}
+		w.WriteMsg(m)
+	})
+
+	server := &dns.Server{Listener: l, Net: "tcp", Handler: handler}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return l.Addr().String()
+}
+
+func Test_DNS_Seeds(t *testing.T) {
+	server := dns_standin(t)
+
+	list, err := resolve_dns_seed(server, "seed.test", 18089)
+	if err != nil {
+		t.Fatalf("resolution failed err %s", err)
+	}
+	sort.Strings(list)
+	expected := []string{"192.0.2.1:18089", "192.0.2.2:18089", "198.51.100.7:11011", "203.0.113.9:40401", "[2001:db8::1]:18089"}
+	sort.Strings(expected)
+	if strings.Join(list, " ") != strings.Join(expected, " ") {
+		t.Fatalf("unexpected seeds %v", list)
+	}
+
+	if _, err := resolve_dns_seed(server, "unknown.test", 18089); err == nil {
+		t.Fatalf("unknown seed must fail")
+	}
+}
+
+func sign_peer_list(t *testing.T, e *openpgp.Entity, text string) []byte {
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, e.PrivateKey, nil)
+	if err != nil {
+		t.Fatalf("cannot sign err %s", err)
+	}
+	w.Write([]byte(text))
+	w.Close()
+	return buf.Bytes()
+}
+
+func armored_public_key(t *testing.T, e *openpgp.Entity) string {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("cannot armor err %s", err)
+	}
+	if err = e.Serialize(w); err != nil {
+		t.Fatalf("cannot serialize key err %s", err)
+	}
+	w.Close()
+	return buf.String()
+}
+
+func Test_Signed_Peer_List(t *testing.T) {
+	signer, err := openpgp.NewEntity("seeds", "", "seeds@example.org", nil)
+	if err != nil {
+		t.Fatalf("cannot create key err %s", err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.org", nil)
+	if err != nil {
+		t.Fatalf("cannot create key err %s", err)
+	}
+	key := armored_public_key(t, signer)
+
+	signed := sign_peer_list(t, signer, "# seed nodes\n192.0.2.1:18089\n\n[2001:db8::1]:11011\n")
+	list, err := verify_signed_peer_list(signed, key)
+	if err != nil {
+		t.Fatalf("valid list rejected err %s", err)
+	}
+	if len(list) != 2 || list[0] != "192.0.2.1:18089" {
+		t.Fatalf("unexpected list %v", list)
+	}
+
+	if _, err = verify_signed_peer_list(bytes.Replace(signed, []byte("192.0.2.1"), []byte("192.0.2.6"), 1), key); err == nil {
+		t.Fatalf("tampered list must be rejected")
+	}
+	if _, err = verify_signed_peer_list(sign_peer_list(t, other, "192.0.2.1:18089\n"), key); err == nil {
+		t.Fatalf("list signed by other key must be rejected")
+	}
+	if _, err = verify_signed_peer_list([]byte("192.0.2.1:18089\n"), key); err == nil {
+		t.Fatalf("unsigned list must be rejected")
+	}
+	if _, err = verify_signed_peer_list(sign_peer_list(t, signer, "seed.example.org:18089\n"), key); err == nil {
+		t.Fatalf("hostnames must be rejected")
+	}
+
+	if _, err = openpgp.ReadArmoredKeyRing(strings.NewReader(config.Peer_List_Signing_Key)); err != nil {
+		t.Fatalf("built-in signing key cannot be parsed err %s", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "net"
+import "time"
+import "strings"
+import "crypto/tls"
+
+import "golang.org/x/net/proxy"
+
+import "github.com/deroproject/derohe/globals"
+
+// this file implements tcp/tls transport, which is used to connect through socks5 proxies such as tor
+// kcp transport cannot be used since socks proxies in practice donot support UDP ASSOCIATE
+// nodes accept tcp connections only if --p2p-tcp-bind is given, usually on the same port as kcp
+
+const tcp_dial_timeout = 30 * time.Second // tor circuits may take a while to build
+
+// endpoint of a peer, host may be an ip address, a dns name or a .onion address
+// names are never resolved locally, so they donot leak through dns
+type endpoint_addr string
+
+func (e endpoint_addr) Network() string { return "tcp" }
+func (e endpoint_addr) String() string  { return string(e) }
+
+// whether socks proxy is being used for all outgoing connections
+func using_proxy() bool {
+	return globals.Arguments["--socks-proxy"] != nil
+}
+
+// whether endpoint is a tor hidden service
+func IsOnionAddress(endpoint string) bool {
+	host := ParseIPNoError(endpoint)
+	return strings.HasSuffix(strings.ToLower(host), ".onion")
+}
+
+// check whether endpoint is in host:port form, where host is an ip, a dns name or a .onion address
+func valid_endpoint(endpoint string) bool {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || host == "" || port == "" || len(host) > 255 {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, label := range strings.Split(host, ".") {
+		if len(label) < 1 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// whether we can connect to this endpoint, .onion addresses can only be reached through proxy
+func endpoint_reachable(endpoint string) bool {
+	return valid_endpoint(endpoint) && (using_proxy() || !IsOnionAddress(endpoint))
+}
+
+// dial endpoint using dialer and complete tls handshake, name resolution is left to dialer
+func dial_tcp_tls(dialer proxy.Dialer, endpoint string) (conn net.Conn, conntls *tls.Conn, err error) {
+	type dial_result struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan dial_result, 1)
+	go func() {
+		c, err := dialer.Dial("tcp", endpoint)
+		ch <- dial_result{c, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if conn, err = r.conn, r.err; err != nil {
+			return
+		}
+	case <-time.After(tcp_dial_timeout):
+		go func() { // close the connection if it completes later
+			if r := <-ch; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		err = &net.OpError{Op: "dial", Net: "tcp", Err: errTimeout}
+		return
+	}
+
+	conntls = tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	conn.SetDeadline(time.Now().Add(tcp_dial_timeout))
+	if err = conntls.Handshake(); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	return
+}
+
+// will try to connect with given endpoint using tcp/tls through the dialer
+// will block until the connection dies or is killed
+func connect_with_endpoint_tcp(dialer proxy.Dialer, endpoint string, sync_node bool) {
+	defer globals.Recover(2)
+
+	if !valid_endpoint(endpoint) {
+		logger.V(3).Info("Invalid endpoint", "endpoint", endpoint)
+		return
+	}
+	host := ParseIPNoError(endpoint)
+
+	if IsAddressInBanList(host) {
+		logger.V(2).Info("Connecting to banned address is prohibited", "address", host)
+		return
+	}
+
+	if IsAddressConnected(host) {
+		logger.V(4).Info("outgoing address is already connected", "address", endpoint)
+		return
+	}
+
+	if shouldwebackoff(host) {
+		logger.V(1).Info("backing off from this connection", "address", endpoint)
+		return
+	} else {
+		backoff_mutex.Lock()
+		backoff[host] = time.Now().Unix() + 10
+		backoff_mutex.Unlock()
+	}
+
+	conn, conntls, err := dial_tcp_tls(dialer, endpoint)
+	if err != nil {
+		logger.V(3).Error(err, "Dial failed", "endpoint", endpoint)
+		Peer_SetFail(endpoint) // update peer list as we see
+		return
+	}
+
+	default_node.process_outgoing_connection(conn, conntls, endpoint_addr(endpoint), false, sync_node)
+}
+
+type timeout_error struct{}
+
+func (timeout_error) Error() string   { return "i/o timeout" }
+func (timeout_error) Timeout() bool   { return true }
+func (timeout_error) Temporary() bool { return true }
+
+var errTimeout error = timeout_error{}
@@ -0,0 +1,178 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package p2p
+
+import "io"
+import "net"
+import "testing"
+import "strconv"
+import "crypto/tls"
+import "encoding/binary"
+
+import "github.com/go-logr/logr"
+import "golang.org/x/net/proxy"
+
+// minimal socks5 stand-in, supports only CONNECT without authentication
+// all requested hosts are recorded and connections are relayed to target irrespective of requested host
+func socks5_standin(t *testing.T, target string, requested chan<- string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen err %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 262)
+				if _, err := io.ReadFull(conn, buf[:2]); err != nil || buf[0] != 5 { // version, method count
+					return
+				}
+				if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+					return
+				}
+				conn.Write([]byte{5, 0}) // no authentication
+
+				if _, err := io.ReadFull(conn, buf[:4]); err != nil || buf[1] != 1 { // only connect is supported
+					return
+				}
+				var host string
+				switch buf[3] {
+				case 1:
+					io.ReadFull(conn, buf[:4])
+					host = net.IP(buf[:4]).String()
+				case 3:
+					io.ReadFull(conn, buf[:1])
+					n := int(buf[0])
+					io.ReadFull(conn, buf[:n])
+					host = string(buf[:n])
+				default:
+					return
+				}
+				io.ReadFull(conn, buf[:2])
+				requested <- net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+
+				remote, err := net.Dial("tcp", target)
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0}) // connection refused
+					return
+				}
+				defer remote.Close()
+				conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+
+				go io.Copy(remote, conn)
+				io.Copy(conn, remote)
+			}(conn)
+		}
+	}()
+	return l
+}
+
+// tls echo server, similar to p2p tcp listener
+func tls_echo_server(t *testing.T) net.Listener {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{generate_random_tls_cert()}})
+	if err != nil {
+		t.Fatalf("cannot listen err %s", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l
+}
+
+func Test_Dial_Through_Socks5(t *testing.T) {
+	logger = logr.Discard()
+
+	server := tls_echo_server(t)
+	defer server.Close()
+
+	requested := make(chan string, 4)
+	socks := socks5_standin(t, server.Addr().String(), requested)
+	defer socks.Close()
+
+	dialer, err := proxy.SOCKS5("tcp", socks.Addr().String(), nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("cannot create dialer err %s", err)
+	}
+
+	for _, endpoint := range []string{"dero4gn5sz2cnixbkzhfpzsjdgr7ekbcx26bn3mhgafnsrfiwk2xosid.onion:18089", "node.dero.example:18089", "10.1.2.3:18089"} {
+		conn, conntls, err := dial_tcp_tls(dialer, endpoint)
+		if err != nil {
+			t.Fatalf("dial %s failed err %s", endpoint, err)
+		}
+
+		if got := <-requested; got != endpoint { // names must reach proxy unresolved
+			t.Fatalf("proxy received %s, expected %s", got, endpoint)
+		}
+
+		msg := []byte("hello dero")
+		if _, err = conntls.Write(msg); err != nil {
+			t.Fatalf("write failed err %s", err)
+		}
+		reply := make([]byte, len(msg))
+		if _, err = io.ReadFull(conntls, reply); err != nil || string(reply) != string(msg) {
+			t.Fatalf("echo failed err %s reply %q", err, reply)
+		}
+		conn.Close()
+	}
+
+	// proxy refuses connection
+	server.Close()
+	if _, _, err := dial_tcp_tls(dialer, "unreachable.onion:18089"); err == nil {
+		t.Fatalf("dial must fail when proxy cannot connect")
+	}
+}
+
+func Test_Endpoint_Validation(t *testing.T) {
+	valid := []string{"1.2.3.4:18089", "[::1]:18089", "seed.dero.io:18089", "dero4gn5sz2cnixbkzhfpzsjdgr7ekbcx26bn3mhgafnsrfiwk2xosid.onion:18089"}
+	invalid := []string{"", "1.2.3.4", "seed.dero.io", ":18089", "bad host:18089", "-bad.io:18089", "a..b:18089", "evil\x00.onion:18089"}
+
+	for _, e := range valid {
+		if !valid_endpoint(e) {
+			t.Fatalf("endpoint %q must be valid", e)
+		}
+	}
+	for _, e := range invalid {
+		if valid_endpoint(e) {
+			t.Fatalf("endpoint %q must be invalid", e)
+		}
+	}
+
+	if !IsOnionAddress("abc.ONION:18089") || IsOnionAddress("onion.io:18089") {
+		t.Fatalf("onion address detection failed")
+	}
+
+	if endpoint_reachable("abc.onion:18089") { // no proxy in tests
+		t.Fatalf("onion address must not be reachable without proxy")
+	}
+	if !endpoint_reachable("seed.dero.io:18089") {
+		t.Fatalf("dns endpoint must be reachable")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package proof
+
+import "fmt"
+import "strings"
+
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/rpc"
+
+// a proof generated by wallet does not contain the txid, so it cannot be verified on its own
+// self-contained proofs carry the txid as an additional argument, older proofs remain valid
+const PROOF_TXID = "T"
+
+// embeds the txid within a wallet generated proof
+func NewTxProof(proof string, txid crypto.Hash) (string, error) {
+	addr, err := rpc.NewAddress(strings.TrimSpace(proof))
+	if err != nil {
+		return "", err
+	}
+	if !addr.Proof {
+		return "", fmt.Errorf("Invalid proof")
+	}
+
+	if i := addr.Arguments.Index(PROOF_TXID, rpc.DataHash); i >= 0 {
+		addr.Arguments[i].Value = txid
+	} else {
+		addr.Arguments = append(addr.Arguments, rpc.Argument{Name: PROOF_TXID, DataType: rpc.DataHash, Value: txid})
+	}
+	return addr.String(), nil
+}
+
+// extracts the txid from a self-contained proof
+func TXIDFromProof(proof string) (txid crypto.Hash, err error) {
+	addr, err := rpc.NewAddress(strings.TrimSpace(proof))
+	if err != nil {
+		return
+	}
+	if !addr.Proof {
+		err = fmt.Errorf("Invalid proof")
+		return
+	}
+	if !addr.Arguments.Has(PROOF_TXID, rpc.DataHash) {
+		err = fmt.Errorf("proof does not contain txid")
+		return
+	}
+	txid = addr.Arguments.Value(PROOF_TXID, rpc.DataHash).(crypto.Hash)
+	return
+}
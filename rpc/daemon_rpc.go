@@ -115,10 +115,11 @@ type (
 		Height             uint64 `json:"height"`
 		Prev_Hash          string `json:"prev_hash"`
 		EpochMilli         uint64 `json:"epochmilli"`
-		Blocks             uint64 `json:"blocks"`     // number of blocks found
-		MiniBlocks         uint64 `json:"miniblocks"` // number of miniblocks found
-		Rejected           uint64 `json:"rejected"`   // reject count
-		LastError          string `json:"lasterror"`  // last error
+		Blocks             uint64 `json:"blocks"`           // number of blocks found
+		MiniBlocks         uint64 `json:"miniblocks"`       // number of miniblocks found
+		Rejected           uint64 `json:"rejected"`         // reject count
+		Shares             uint64 `json:"shares,omitempty"` // number of valid shares, only in pool mode
+		LastError          string `json:"lasterror"`        // last error
 		Status             string `json:"status"`
 	}
 )
@@ -169,6 +170,23 @@ type (
 	}
 )
 
+// get encrypted balance along with a merkle proof against state root committed in a block
+type (
+	GetEncryptedBalanceProof_Params struct {
+		Address    string      `json:"address"`
+		SCID       crypto.Hash `json:"scid"`
+		TopoHeight int64       `json:"topoheight,omitempty"` // -1 means latest committed state
+	}
+	GetEncryptedBalanceProof_Result struct {
+		Balance         GetEncryptedBalance_Result `json:"balance"`         // status is unregistered if proof is non-membership
+		Proof           string                     `json:"proof"`           // graviton proof in hex
+		BalanceTreeHash string                     `json:"balancetreehash"` // root of balance tree
+		MetaTreeHash    string                     `json:"metatreehash"`    // root of sc meta tree
+		Block           string                     `json:"block"`           // block blob in hex, whose proof field commits to above roots
+		Status          string                     `json:"status"`
+	}
+)
+
 type (
 	GetTxPool_Params struct{} // no params
 	GetTxPool_Result struct {
@@ -316,9 +334,158 @@ type (
 	}
 )
 
+// verify a self-contained payment proof against the chain
+type (
+	CheckTxProof_Params struct {
+		Proof    string `json:"proof"`
+		Receiver string `json:"receiver,omitempty"` // if provided, proof must be for this receiver
+	}
+	CheckTxProof_Result struct {
+		TXID       string   `json:"txid"`
+		ValidBlock string   `json:"valid_block"` // tx is valid in this block
+		TopoHeight int64    `json:"topoheight"`  // topoheight at which the tx was mined
+		Receivers  []string `json:"receivers"`
+		Amounts    []uint64 `json:"amounts"`
+		Payloads   []string `json:"payloads"` // decoded payload if available, otherwise error while decoding
+		Status     string   `json:"status"`
+	}
+)
+
+// getwork server pool mode
+type (
+	Pool_Found struct {
+		Height uint64 `json:"height"`
+		Time   int64  `json:"time"`
+		Finder string `json:"finder"`
+		Block  bool   `json:"block"`  // miniblock completed a full block
+		Reward uint64 `json:"reward"` // estimated reward, split among window shares
+		Shares uint64 `json:"shares"` // shares in window at that time
+	}
+	Pool_Payout struct {
+		Address string `json:"address"`
+		Amount  uint64 `json:"amount"`
+		TXID    string `json:"txid,omitempty"`
+		Time    int64  `json:"time"`
+	}
+	Pool_Ledger_Entry struct {
+		Address   string `json:"address"`
+		Shares    uint64 `json:"shares"`
+		LastShare int64  `json:"lastshare"`
+		Credited  uint64 `json:"credited"`
+		Paid      uint64 `json:"paid"`
+		Balance   uint64 `json:"balance"`
+	}
+
+	GetPoolStats_Params struct{} // no params
+	GetPoolStats_Result struct {
+		Enabled         bool         `json:"enabled"`
+		ShareDifficulty uint64       `json:"share_difficulty"` // 0 means network difficulty / 100
+		Window          int          `json:"pplns_window"`
+		WindowShares    int          `json:"window_shares"`
+		Miners          int          `json:"miners"`   // distinct addresses in window
+		Hashrate        uint64       `json:"hashrate"` // estimated from shares of last 10 minutes
+		SharesValid     uint64       `json:"shares_valid"`
+		SharesInvalid   uint64       `json:"shares_invalid"`
+		SharesStale     uint64       `json:"shares_stale"`
+		SharesDuplicate uint64       `json:"shares_duplicate"`
+		MiniBlocks      uint64       `json:"miniblocks"`
+		Blocks          uint64       `json:"blocks"`
+		Found           []Pool_Found `json:"found"` // recently found
+		Status          string       `json:"status"`
+	}
+
+	GetPoolLedger_Params struct {
+		Address string `json:"address,omitempty"` // if empty, all accounts are returned
+	}
+	GetPoolLedger_Result struct {
+		Entries []Pool_Ledger_Entry `json:"entries"`
+		Payouts []Pool_Payout       `json:"payouts"`
+		Status  string              `json:"status"`
+	}
+)
+
 type GasEstimate_Params Transfer_Params // same structure as used by transfer call
 type GasEstimate_Result struct {
 	GasCompute uint64 `json:"gascompute"`
 	GasStorage uint64 `json:"gasstorage"`
 	Status     string `json:"status"`
 }
+
+type (
+	EstimateFee_Params struct {
+		Blocks []uint64 `json:"blocks,omitempty"` // confirmation targets in blocks, defaults to 1, 3 and 10
+	}
+	Fee_Estimate struct {
+		Blocks   uint64 `json:"blocks"`     // tx should be mined within these many blocks
+		FeePerKB uint64 `json:"fee_per_kb"` // suggested fee per KB in atomic units
+	}
+	EstimateFee_Result struct {
+		Estimates    []Fee_Estimate `json:"estimates"`
+		MinFeePerKB  uint64         `json:"min_fee_per_kb"`
+		MempoolTxs   uint64         `json:"mempool_txs"`
+		MempoolBytes uint64         `json:"mempool_bytes"`
+		BlockFill    float64        `json:"block_fill"` // average fill of recent blocks, 0 to 1
+		RecentBlocks int            `json:"recent_blocks"`
+		Status       string         `json:"status"`
+	}
+)
+
+// supply and emission, all amounts are cumulative till topoheight
+// only unpruned nodes can report supply
+type (
+	GetSupply_Params struct {
+		TopoHeight int64 `json:"topoheight,omitempty"` // 0 or -1 means latest
+	}
+	Supply_Info struct {
+		Premine      uint64 `json:"premine"`
+		Registration uint64 `json:"registration"` // amounts given to newly registered accounts
+		Mined        uint64 `json:"mined"`        // base block rewards, excluding fees
+		Fees         uint64 `json:"fees"`
+		Integrator   uint64 `json:"integrator"` // rewards given to block integrators, including fees
+		MiniBlocks   uint64 `json:"miniblocks"` // rewards given to miniblock miners, including fees
+		Burned       uint64 `json:"burned"`     // burned by BURN_TX and normal txs
+		SCDeposits   uint64 `json:"scdeposits"` // burned into SCs
+		SCHeld       uint64 `json:"scheld"`     // currently held by SCs
+		Emitted      uint64 `json:"emitted"`    // premine + registration + mined
+		Circulating  uint64 `json:"circulating"`
+	}
+	GetSupply_Result struct {
+		TopoHeight int64       `json:"topoheight"`
+		BLID       string      `json:"blid"`
+		Supply     Supply_Info `json:"supply"`
+		Status     string      `json:"status"`
+	}
+)
+
+// these are only available on simulator, under SIMULATOR service
+type (
+	SimulatorMine_Params struct {
+		Count int `json:"count"` // number of blocks to mine, defaults to 1
+	}
+	SimulatorMine_Result struct {
+		Height     int64  `json:"height"`
+		TopoHeight int64  `json:"topoheight"`
+		Status     string `json:"status"`
+	}
+
+	SimulatorSnapshot_Params struct {
+		Name string `json:"name"`
+	}
+	SimulatorSnapshot_Result struct {
+		Name       string `json:"name"`
+		TopoHeight int64  `json:"topoheight"`
+		Hash       string `json:"hash"`
+		Status     string `json:"status"`
+	}
+
+	SimulatorRestore_Params SimulatorSnapshot_Params
+	SimulatorRestore_Result SimulatorSnapshot_Result
+
+	SimulatorWarp_Params struct {
+		Seconds int64 `json:"seconds"` // moves block timestamps forward by this much
+	}
+	SimulatorWarp_Result struct {
+		ClockSkew int64  `json:"clockskew"` // total skew in seconds
+		Status    string `json:"status"`
+	}
+)
@@ -296,3 +296,33 @@ type (
 		Entry Entry       `json:"entry,omitempty"`
 	}
 )
+
+// GetTxProof
+type (
+	Get_Tx_Proof_Params struct {
+		TXID string `json:"txid"`
+	}
+	Get_Tx_Proof_Result struct {
+		Proofs []string `json:"proofs"` // one proof per transfer within the tx, each proof is self-contained
+	}
+)
+
+// GetOutbox
+type (
+	Outbox_Entry struct {
+		TXID       string    `json:"txid"`
+		Previous   []string  `json:"previous,omitempty"` // txids of earlier attempts, if tx was rebuilt
+		State      string    `json:"state"`              // pending, in-mempool, mined, stable or failed
+		TopoHeight int64     `json:"topoheight"`         // topoheight at which tx was mined
+		Rebuilds   int       `json:"rebuilds"`
+		Created    time.Time `json:"created"`
+		LastSent   time.Time `json:"lastsent"`
+		Error      string    `json:"error,omitempty"` // last error while rebroadcasting/rebuilding
+	}
+	Get_Outbox_Params struct {
+		State string `json:"state,omitempty"` // if empty, all entries are returned
+	}
+	Get_Outbox_Result struct {
+		Entries []Outbox_Entry `json:"entries"`
+	}
+)
@@ -0,0 +1,58 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package simulator
+
+import "testing"
+
+import "github.com/deroproject/derohe/walletapi"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// fail the test if balance of wallet w for scid differs from expected
+func (s *Simulator) AssertBalance(t testing.TB, w *walletapi.Wallet_Memory, scid crypto.Hash, expected uint64) {
+	t.Helper()
+	balance, err := s.Balance(w, scid)
+	if err != nil {
+		t.Fatalf("cannot obtain balance of %s err %s", w.GetAddress(), err)
+	}
+	if balance != expected {
+		t.Fatalf("balance of %s scid %s expected %d actual %d", w.GetAddress(), scid, expected, balance)
+	}
+}
+
+// fail the test if DERO balance held by SC differs from expected
+func (s *Simulator) AssertSCBalance(t testing.TB, scid crypto.Hash, expected uint64) {
+	t.Helper()
+	balance, err := s.SCBalance(scid)
+	if err != nil {
+		t.Fatalf("cannot obtain sc %s balance err %s", scid, err)
+	}
+	if balance != expected {
+		t.Fatalf("sc %s balance expected %d actual %d", scid, expected, balance)
+	}
+}
+
+// fail the test if SC variable differs from expected, expected must be uint64 or string
+func (s *Simulator) AssertSCVariable(t testing.TB, scid crypto.Hash, key interface{}, expected interface{}) {
+	t.Helper()
+	value, err := s.SCVariable(scid, key)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if value != expected {
+		t.Fatalf("sc %s variable %v expected %v(%T) actual %v(%T)", scid, key, expected, expected, value, value)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package simulator runs a complete simulator chain, daemon RPC server and a set of funded,
+// registered wallets inside the calling process, so that smart contracts and dApps can be
+// tested with plain go test suites.
+// since the daemon and walletapi keep global state, only one simulator may run per process at a time
+package simulator
+
+import "os"
+import "fmt"
+import "errors"
+import "net"
+import "time"
+import "strings"
+import "context"
+import "math/big"
+import "encoding/hex"
+
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/premine"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/transaction"
+import "github.com/deroproject/derohe/walletapi"
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/cryptography/bn256"
+import derodrpc "github.com/deroproject/derohe/cmd/derod/rpc"
+
+// default ringsize used for all simulator transactions
+const RINGSIZE = 2
+
+// returned by InvokeSC when the SC call failed or returned non-zero, the tx is still mined
+var ErrSCExecution = errors.New("sc execution failed")
+
+// a running in-process simulator
+type Simulator struct {
+	Chain      *blockchain.Blockchain
+	RPC        *derodrpc.RPCServer
+	RPCAddress string // ip:port of daemon rpc server
+
+	Genesis *walletapi.Wallet_Memory   // miner wallet, receives all block rewards
+	Wallets []*walletapi.Wallet_Memory // funded and registered at genesis
+
+	data_dir     string
+	remove_dir   bool
+	arguments    map[string]interface{}
+	premine_list string
+	testnet_tx   string
+	mainnet_tx   string
+	testnet_hash crypto.Hash
+	mainnet_hash crypto.Hash
+}
+
+// deterministic wallet key derived from seed and index, so test addresses stay stable across runs
+func Wallet_Seed(seed string, index int) *crypto.BNRed {
+	x := crypto.HashtoNumber([]byte(fmt.Sprintf("%s:%d", seed, index)))
+	return crypto.GetBNRed(new(big.Int).Mod(x, bn256.Order))
+}
+
+// start a simulator chain with count wallets each holding funds atomic units
+// if dir is empty, a temporary directory is used and removed on Stop
+// a caller supplied dir is never removed, it must be empty or not exist so that chain starts from genesis
+func Start(dir string, count int, funds uint64) (s *Simulator, err error) {
+	if count < 1 {
+		return nil, fmt.Errorf("simulator needs atleast 1 wallet")
+	}
+
+	s = &Simulator{data_dir: dir}
+	if s.data_dir == "" {
+		if s.data_dir, err = os.MkdirTemp("", "dero_simulator"); err != nil {
+			return nil, err
+		}
+		s.remove_dir = true
+	} else if entries, err := os.ReadDir(s.data_dir); err == nil && len(entries) != 0 {
+		return nil, fmt.Errorf("simulator refuses to start in non-empty directory %s", s.data_dir)
+	}
+
+	if s.RPCAddress, err = free_address(); err != nil {
+		return nil, err
+	}
+
+	s.arguments = globals.Arguments
+	globals.Arguments = map[string]interface{}{
+		"--testnet":        true,
+		"--simulator":      true,
+		"--debug":          false,
+		"--data-dir":       s.data_dir,
+		"--rpc-bind":       s.RPCAddress,
+		"--getwork-bind":   "127.0.0.1:0", // getwork is not required
+		"--daemon-address": s.RPCAddress,
+	}
+	if walletapi.Balance_lookup_table == nil {
+		walletapi.Initialize_LookupTable(1, 1<<17)
+	}
+
+	if s.Genesis, err = create_wallet(Wallet_Seed("genesis", 0)); err != nil {
+		globals.Arguments = s.arguments
+		return nil, err
+	}
+	var list strings.Builder
+	for i := 0; i < count; i++ {
+		var w *walletapi.Wallet_Memory
+		if w, err = create_wallet(Wallet_Seed("wallet", i)); err != nil {
+			globals.Arguments = s.arguments
+			return nil, err
+		}
+		s.Wallets = append(s.Wallets, w)
+		fmt.Fprintf(&list, "%d,%s\n", funds, hex.EncodeToString(w.GetRegistrationTX().Serialize()))
+	}
+
+	// genesis is rebuilt so that it pays our genesis wallet and premine registers our wallets
+	s.premine_list, s.testnet_tx, s.mainnet_tx = premine.List, config.Testnet.Genesis_Tx, config.Mainnet.Genesis_Tx
+	s.testnet_hash, s.mainnet_hash = config.Testnet.Genesis_Block_Hash, config.Mainnet.Genesis_Block_Hash
+	premine.List = list.String()
+
+	genesis_tx := transaction.Transaction{Transaction_Prefix: transaction.Transaction_Prefix{Version: 1, Value: funds}}
+	copy(genesis_tx.MinerAddress[:], s.Genesis.GetAddress().PublicKey.EncodeCompressed())
+	config.Testnet.Genesis_Tx = fmt.Sprintf("%x", genesis_tx.Serialize())
+	config.Mainnet.Genesis_Tx = config.Testnet.Genesis_Tx
+	genesis_block := blockchain.Generate_Genesis_Block()
+	config.Testnet.Genesis_Block_Hash = genesis_block.GetHash()
+	config.Mainnet.Genesis_Block_Hash = config.Testnet.Genesis_Block_Hash
+
+	globals.Initialize() // picks up the new genesis
+
+	params := map[string]interface{}{}
+	params["--simulator"] = true
+	if s.Chain, err = blockchain.Blockchain_Start(params); err != nil {
+		s.restore()
+		return nil, err
+	}
+	params["chain"] = s.Chain
+	if s.RPC, err = derodrpc.RPCServer_Start(params); err != nil {
+		s.Chain.Shutdown()
+		s.restore()
+		return nil, err
+	}
+
+	for i := 0; ; i++ {
+		if err = walletapi.Connect(s.RPCAddress); err == nil {
+			break
+		}
+		if i >= 50 {
+			s.Stop()
+			return nil, fmt.Errorf("cannot connect to simulator rpc server err %s", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for _, w := range s.all_wallets() {
+		w.SetDaemonAddress(s.RPCAddress)
+		w.SetOnlineMode()
+	}
+	if err = s.MineBlock(); err != nil { // first block, so wallets have something to sync against
+		s.Stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+// stop all subsystems and restore genesis/premine configuration and globals.Arguments
+func (s *Simulator) Stop() {
+	for _, w := range s.all_wallets() {
+		w.SetOfflineMode()
+	}
+	if s.RPC != nil {
+		s.RPC.RPCServer_Stop()
+	}
+	if s.Chain != nil {
+		s.Chain.Shutdown()
+	}
+	s.restore()
+	if s.remove_dir {
+		os.RemoveAll(s.data_dir)
+	}
+}
+
+func (s *Simulator) restore() {
+	globals.Arguments = s.arguments
+	premine.List, config.Testnet.Genesis_Tx, config.Mainnet.Genesis_Tx = s.premine_list, s.testnet_tx, s.mainnet_tx
+	config.Testnet.Genesis_Block_Hash, config.Mainnet.Genesis_Block_Hash = s.testnet_hash, s.mainnet_hash
+}
+
+func (s *Simulator) all_wallets() (list []*walletapi.Wallet_Memory) {
+	if s.Genesis != nil {
+		list = append(list, s.Genesis)
+	}
+	return append(list, s.Wallets...)
+}
+
+// mine a single block containing everything in mempool/regpool, then sync all wallets
+func (s *Simulator) MineBlock() error {
+	miner := s.Genesis.GetAddress()
+	for {
+		bl, mbl, _, _, err := s.Chain.Create_new_block_template_mining(miner)
+		if err != nil {
+			return err
+		}
+		_, blid, _, err := s.Chain.Accept_new_block(bl.Timestamp, mbl.Serialize())
+		if err != nil {
+			return err
+		}
+		if !blid.IsZero() {
+			break
+		}
+	}
+	return s.Sync()
+}
+
+// mine n blocks
+func (s *Simulator) MineBlocks(n int) error {
+	for i := 0; i < n; i++ {
+		if err := s.MineBlock(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sync all wallets with the daemon
+func (s *Simulator) Sync() error {
+	for _, w := range s.all_wallets() {
+		if err := w.Sync_Wallet_Memory_With_Daemon(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send tx to daemon and mine it
+func (s *Simulator) send(w *walletapi.Wallet_Memory, transfers []rpc.Transfer, args rpc.Arguments, fees uint64) (txid crypto.Hash, err error) {
+	tx, err := w.TransferPayload0(transfers, RINGSIZE, false, args, fees, false)
+	if err != nil {
+		return
+	}
+	if err = w.SendTransaction(tx); err != nil {
+		return
+	}
+	if err = s.MineBlock(); err != nil {
+		return
+	}
+	return tx.GetHash(), nil
+}
+
+// transfer amount to destination and mine it
+func (s *Simulator) Transfer(w *walletapi.Wallet_Memory, destination string, amount uint64) (txid crypto.Hash, err error) {
+	return s.send(w, []rpc.Transfer{{Destination: destination, Amount: amount}}, rpc.Arguments{}, 0)
+}
+
+// install an SC from wallet w and mine it, returns the SCID
+func (s *Simulator) InstallSC(w *walletapi.Wallet_Memory, code string) (scid crypto.Hash, err error) {
+	args := rpc.Arguments{
+		{Name: rpc.SCACTION, DataType: rpc.DataUint64, Value: uint64(rpc.SC_INSTALL)},
+		{Name: rpc.SCCODE, DataType: rpc.DataString, Value: code},
+	}
+	if scid, err = s.send(w, nil, args, 0); err != nil {
+		return
+	}
+
+	sc, err := s.GetSC(scid)
+	if err != nil {
+		return
+	}
+	if sc.Code == "" {
+		err = fmt.Errorf("sc %s was not installed", scid)
+	}
+	return
+}
+
+// invoke entrypoint of scid with the given arguments, depositing dero_deposit into the SC, and mine it
+// the call is dry run against current state first, if it fails or returns non-zero the tx is still mined
+// and an error wrapping ErrSCExecution is returned along with the txid
+func (s *Simulator) InvokeSC(w *walletapi.Wallet_Memory, scid crypto.Hash, entrypoint string, args rpc.Arguments, dero_deposit uint64) (txid crypto.Hash, err error) {
+	var transfers []rpc.Transfer
+	if dero_deposit >= 1 { // we must burn this much native currency
+		var zeroscid crypto.Hash
+		for _, k := range w.Random_ring_members(zeroscid) {
+			if k != w.GetAddress().String() { // make sure random member is not equal to ourself
+				transfers = append(transfers, rpc.Transfer{Destination: k, Amount: 0, Burn: dero_deposit})
+				break
+			}
+		}
+		if len(transfers) < 1 {
+			return txid, fmt.Errorf("could not obtain ring members")
+		}
+	}
+
+	scargs := append(rpc.Arguments{}, args...)
+	scargs = append(scargs, rpc.Argument{Name: "entrypoint", DataType: rpc.DataString, Value: entrypoint})
+	scargs = append(scargs, rpc.Argument{Name: rpc.SCACTION, DataType: rpc.DataUint64, Value: uint64(rpc.SC_CALL)})
+	scargs = append(scargs, rpc.Argument{Name: rpc.SCID, DataType: rpc.DataHash, Value: scid})
+
+	// the chain does not store execution results, so the call is run on the current state first
+	_, exec_err := derodrpc.GetGasEstimate(context.Background(), rpc.GasEstimate_Params{Transfers: transfers, SC_RPC: scargs, Signer: w.GetAddress().String()})
+
+	if txid, err = s.send(w, transfers, scargs, 0); err != nil {
+		return
+	}
+	if exec_err != nil {
+		err = fmt.Errorf("%w: %s entrypoint %s err %s", ErrSCExecution, scid, entrypoint, exec_err)
+	}
+	return
+}
+
+// state of SC at current topoheight including all variables
+func (s *Simulator) GetSC(scid crypto.Hash) (rpc.GetSC_Result, error) {
+	return derodrpc.GetSC(context.Background(), rpc.GetSC_Params{SCID: scid.String(), Code: true, Variables: true})
+}
+
+// balance of wallet w, scid is zero for DERO
+func (s *Simulator) Balance(w *walletapi.Wallet_Memory, scid crypto.Hash) (uint64, error) {
+	balance, _, err := w.GetDecryptedBalanceAtTopoHeight(scid, -1, w.GetAddress().String())
+	return balance, err
+}
+
+// DERO balance held by SC
+func (s *Simulator) SCBalance(scid crypto.Hash) (uint64, error) {
+	sc, err := s.GetSC(scid)
+	return sc.Balance, err
+}
+
+// value of SC variable, string values are returned decoded
+func (s *Simulator) SCVariable(scid crypto.Hash, key interface{}) (value interface{}, err error) {
+	sc, err := s.GetSC(scid)
+	if err != nil {
+		return
+	}
+
+	var ok bool
+	switch k := key.(type) {
+	case string:
+		value, ok = sc.VariableStringKeys[k]
+	case uint64:
+		value, ok = sc.VariableUint64Keys[k]
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if !ok {
+		return nil, fmt.Errorf("sc %s has no variable %v", scid, key)
+	}
+	if str, is_string := value.(string); is_string { // strings are hex encoded by the daemon
+		var raw []byte
+		if raw, err = hex.DecodeString(str); err != nil {
+			return
+		}
+		value = string(raw)
+	}
+	return
+}
+
+func create_wallet(seed *crypto.BNRed) (w *walletapi.Wallet_Memory, err error) {
+	if w, err = walletapi.Create_Encrypted_Wallet_Memory("", seed); err != nil {
+		return
+	}
+	w.SetNetwork(false)
+	return
+}
+
+// find a free local tcp port
+func free_address() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
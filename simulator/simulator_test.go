@@ -0,0 +1,104 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package simulator
+
+import "os"
+import "errors"
+import "path/filepath"
+import "testing"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/transaction"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+func Test_Simulator(t *testing.T) {
+	var zeroscid crypto.Hash
+
+	s := StartTest(t, 3)
+	for _, w := range s.Wallets {
+		s.AssertBalance(t, w, zeroscid, TEST_FUNDS)
+	}
+
+	s.MustTransfer(t, s.Wallets[0], s.Wallets[1].GetAddress().String(), 5000)
+	s.AssertBalance(t, s.Wallets[1], zeroscid, TEST_FUNDS+5000)
+
+	owner := s.Wallets[2]
+	scid := s.MustInstallSC(t, owner, Bank_SC)
+	s.AssertSCVariable(t, scid, "deposits", uint64(0))
+	s.AssertSCVariable(t, scid, "owner", string(owner.GetAddress().PublicKey.EncodeCompressed()))
+
+	s.MustInvokeSC(t, s.Wallets[1], scid, "Deposit", nil, 700)
+	s.AssertSCBalance(t, scid, 700)
+	s.AssertSCVariable(t, scid, "deposits", uint64(700))
+
+	if _, err := s.InvokeSC(s.Wallets[1], scid, "Withdraw", rpc.Arguments{{Name: "amount", DataType: rpc.DataUint64, Value: uint64(300)}}, 0); !errors.Is(err, ErrSCExecution) {
+		t.Fatalf("withdraw by non-owner must report sc execution failure, err %v", err)
+	}
+	s.AssertSCBalance(t, scid, 700)
+
+	before, _ := s.Balance(owner, zeroscid)
+	txid := s.MustInvokeSC(t, owner, scid, "Withdraw", rpc.Arguments{{Name: "amount", DataType: rpc.DataUint64, Value: uint64(300)}}, 0)
+	var tx transaction.Transaction
+	if tx_bytes, err := s.Chain.Store.Block_tx_store.ReadTX(txid); err != nil {
+		t.Fatalf("cannot load tx err %s", err)
+	} else if err = tx.Deserialize(tx_bytes); err != nil {
+		t.Fatalf("cannot deserialize tx err %s", err)
+	}
+	s.AssertSCBalance(t, scid, 400)
+	s.AssertBalance(t, owner, zeroscid, before+300-tx.Fees())
+
+	if _, err := s.SCVariable(scid, "missing"); err == nil {
+		t.Fatalf("missing variable must return error")
+	}
+}
+
+// a caller supplied directory is never wiped
+func Test_Start_Non_Empty_Dir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "keep")
+	if err := os.WriteFile(file, []byte("data"), 0600); err != nil {
+		t.Fatalf("cannot create file err %s", err)
+	}
+
+	if s, err := Start(dir, 1, TEST_FUNDS); err == nil {
+		s.Stop()
+		t.Fatalf("simulator must refuse non-empty directory")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("caller data was removed err %s", err)
+	}
+}
+
+// callers arguments are in effect again once the simulator stops
+func Test_Stop_Restores_Arguments(t *testing.T) {
+	saved := globals.Arguments
+	defer func() { globals.Arguments = saved }()
+	globals.Arguments = map[string]interface{}{"--testnet": false, "--rpc-bind": "caller"}
+
+	s, err := Start("", 1, TEST_FUNDS) // stopped explicitly, so StartTest is not used
+	if err != nil {
+		t.Fatalf("cannot start simulator err %s", err)
+	}
+	if globals.Arguments["--rpc-bind"] != s.RPCAddress {
+		t.Fatalf("simulator arguments not in effect")
+	}
+	s.Stop()
+	if globals.Arguments["--rpc-bind"] != "caller" || globals.Arguments["--testnet"] != false {
+		t.Fatalf("arguments not restored %+v", globals.Arguments)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package simulator
+
+import "testing"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/walletapi"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// every wallet started by StartTest is funded with this much at genesis
+const TEST_FUNDS = 1000000
+
+// SC holding deposits, anyone may deposit, only the installer may withdraw
+const Bank_SC = `
+	Function Initialize() Uint64
+	10 STORE("owner", SIGNER())
+	20 STORE("deposits", 0)
+	30 RETURN 0
+	End Function
+
+	Function Deposit() Uint64
+	10 STORE("deposits", LOAD("deposits") + DEROVALUE())
+	20 RETURN 0
+	End Function
+
+	Function Withdraw(amount Uint64) Uint64
+	10 IF LOAD("owner") == SIGNER() THEN GOTO 30
+	20 RETURN 1
+	30 SEND_DERO_TO_ADDRESS(SIGNER(), amount)
+	40 RETURN 0
+	End Function
+`
+
+// start a simulator with count wallets holding TEST_FUNDS each, it is stopped when the test finishes
+func StartTest(t testing.TB, count int) *Simulator {
+	t.Helper()
+	s, err := Start("", count, TEST_FUNDS)
+	if err != nil {
+		t.Fatalf("cannot start simulator err %s", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// same as Transfer, however fails the test on error
+func (s *Simulator) MustTransfer(t testing.TB, w *walletapi.Wallet_Memory, destination string, amount uint64) crypto.Hash {
+	t.Helper()
+	txid, err := s.Transfer(w, destination, amount)
+	if err != nil {
+		t.Fatalf("transfer failed err %s", err)
+	}
+	return txid
+}
+
+// same as InstallSC, however fails the test on error
+func (s *Simulator) MustInstallSC(t testing.TB, w *walletapi.Wallet_Memory, code string) crypto.Hash {
+	t.Helper()
+	scid, err := s.InstallSC(w, code)
+	if err != nil {
+		t.Fatalf("cannot install sc err %s", err)
+	}
+	return scid
+}
+
+// same as InvokeSC, however fails the test on error, including failed SC execution
+func (s *Simulator) MustInvokeSC(t testing.TB, w *walletapi.Wallet_Memory, scid crypto.Hash, entrypoint string, args rpc.Arguments, dero_deposit uint64) crypto.Hash {
+	t.Helper()
+	txid, err := s.InvokeSC(w, scid, entrypoint, args, dero_deposit)
+	if err != nil {
+		t.Fatalf("cannot invoke sc err %s", err)
+	}
+	return txid
+}
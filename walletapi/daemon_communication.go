@@ -211,6 +211,8 @@ func (w *Wallet_Memory) sync_loop() {
 			}
 		}
 
+		w.outbox_process() // track sent txs
+
 		time.Sleep(timeout) // wait 5 seconds
 	}
 }
@@ -310,6 +312,82 @@ func (w *Wallet_Memory) NameToAddress(name string) (addr string, err error) {
 	}
 }
 
+// estimate gas required to install/invoke an SC, signer defaults to our own address
+func (w *Wallet_Memory) GetGasEstimate(p rpc.GasEstimate_Params) (result rpc.GasEstimate_Result, err error) {
+	if !IsDaemonOnline() {
+		err = fmt.Errorf("offline or not connected. cannot estimate gas")
+		return
+	}
+
+	if p.Signer == "" {
+		p.Signer = w.GetAddress().String()
+	}
+
+	if err = rpc_client.Call("DERO.GetGasEstimate", p, &result); err != nil {
+		return
+	}
+	if result.Status != "OK" {
+		err = fmt.Errorf("Err %s", result.Status)
+	}
+	return
+}
+
+// obtain suggested fee per KB for confirmation targets, defaults to 1, 3 and 10 blocks
+func (w *Wallet_Memory) EstimateFee(blocks ...uint64) (result rpc.EstimateFee_Result, err error) {
+	if !IsDaemonOnline() {
+		err = fmt.Errorf("offline or not connected. cannot estimate fee")
+		return
+	}
+
+	if err = rpc_client.Call("DERO.EstimateFee", rpc.EstimateFee_Params{Blocks: blocks}, &result); err != nil {
+		return
+	}
+	if result.Status != "OK" {
+		err = fmt.Errorf("Err %s", result.Status)
+	}
+	return
+}
+
+// fee multiplier from next block estimate, cached for a minute
+func (w *Wallet_Memory) auto_fee_multiplier() (float32, error) {
+	w.dynamic_fees_mutex.Lock()
+	defer w.dynamic_fees_mutex.Unlock()
+
+	if w.dynamic_fees_per_kb == 0 || time.Since(w.dynamic_fees_time) > time.Minute {
+		result, err := w.EstimateFee(1)
+		if err != nil {
+			return 0, err
+		}
+		if len(result.Estimates) != 1 {
+			return 0, fmt.Errorf("daemon returned %d estimates", len(result.Estimates))
+		}
+		w.dynamic_fees_per_kb = result.Estimates[0].FeePerKB
+		w.dynamic_fees_time = time.Now()
+	}
+
+	multiplier := float32(w.dynamic_fees_per_kb) / float32(config.FEE_PER_KB)
+	if multiplier < 1.0 { // same minimum as manual priority
+		multiplier = 1.0
+	}
+	return multiplier, nil
+}
+
+// obtain SC balance, code and variables from daemon
+func (w *Wallet_Memory) GetSC(p rpc.GetSC_Params) (result rpc.GetSC_Result, err error) {
+	if !IsDaemonOnline() {
+		err = fmt.Errorf("offline or not connected. cannot obtain SC")
+		return
+	}
+
+	if err = rpc_client.Call("DERO.GetSC", p, &result); err != nil {
+		return
+	}
+	if result.Status != "OK" {
+		err = fmt.Errorf("Err %s", result.Status)
+	}
+	return
+}
+
 // this is as simple as it gets
 // single threaded communication to relay TX to daemon
 // if this is successful, then daemon is in control
@@ -323,19 +401,9 @@ func (w *Wallet_Memory) SendTransaction(tx *transaction.Transaction) (err error)
 		return fmt.Errorf("offline or not connected. cannot send transaction.")
 	}
 
-	params := rpc.SendRawTransaction_Params{Tx_as_hex: hex.EncodeToString(tx.Serialize())}
-	var result rpc.SendRawTransaction_Result
-
-	if err := rpc_client.Call("DERO.SendRawTransaction", params, &result); err != nil {
-		return err
-	}
-
-	if result.Status == "OK" {
-		return nil
-	} else {
-		err = fmt.Errorf("Err %s", result.Status)
+	if err = w.send_raw_transaction(hex.EncodeToString(tx.Serialize())); err == nil {
+		w.outbox_add(tx) // track tx till it becomes stable
 	}
-
 	return
 }
 
@@ -387,7 +455,12 @@ func (w *Wallet_Memory) GetEncryptedBalanceAtTopoHeight(scid crypto.Hash, topohe
 	var result rpc.GetEncryptedBalance_Result
 
 	// Issue a call with a response.
-	if err = rpc_client.Call("DERO.GetEncryptedBalance", rpc.GetEncryptedBalance_Params{SCID: scid, Address: accountaddr, TopoHeight: topoheight}, &result); err != nil {
+	if w.GetLightMode() {
+		if result, err = w.get_verified_balance(scid, topoheight, accountaddr); err != nil {
+			logger.Error(err, "balance verification failed", "address", accountaddr, "topoheight", topoheight)
+			return
+		}
+	} else if err = rpc_client.Call("DERO.GetEncryptedBalance", rpc.GetEncryptedBalance_Params{SCID: scid, Address: accountaddr, TopoHeight: topoheight}, &result); err != nil {
 		logger.Error(err, "DERO.GetEncryptedBalance Call failed:")
 
 		if strings.Contains(strings.ToLower(err.Error()), strings.ToLower(errormsg.ErrAccountUnregistered.Error())) && accountaddr == w.GetAddress().String() && scid.IsZero() {
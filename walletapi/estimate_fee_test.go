@@ -0,0 +1,35 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi_test
+
+import "testing"
+
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/simulator"
+
+// on an idle chain, daemon estimates minimum fee and auto priority stays at normal
+func Test_Estimate_Fee_Idle(t *testing.T) {
+	s := simulator.StartTest(t, 1)
+	w := s.Wallets[0]
+	if estimate, err := w.EstimateFee(); err != nil || len(estimate.Estimates) != 3 || estimate.Estimates[0].FeePerKB != config.FEE_PER_KB {
+		t.Fatalf("idle chain must estimate minimum fee err %v estimate %+v", err, estimate)
+	}
+	w.SetFeeMultiplierAuto(true)
+	if m := w.GetFeeMultiplier(); m != 1.0 {
+		t.Fatalf("auto priority on idle chain expected 1.0 actual %f", m)
+	}
+}
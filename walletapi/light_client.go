@@ -0,0 +1,390 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi
+
+// light mode, wallet does not trust the daemon for balances
+// every balance is accompanied by a graviton proof against the balance tree root
+// hash of balance and sc meta roots is committed in Proof field of a child block, whose header is chained back to
+// headers verified earlier and whose PoW is checked against locally computed difficulty
+// the first header seen is trusted ( trust on first use ), everything after it is verified
+
+import "fmt"
+import "sync"
+import "math"
+import "bytes"
+import "math/big"
+import "encoding/hex"
+
+import "github.com/deroproject/graviton"
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/errormsg"
+import "github.com/deroproject/derohe/cryptography/crypto"
+import "github.com/deroproject/derohe/rpc"
+
+import "golang.org/x/crypto/sha3"
+
+const light_header_window = 1000 // verified headers older than this are forgotten
+const light_max_link = 200       // max headers fetched to link a block to verified headers
+
+type light_header struct {
+	height           uint64
+	timestamp        uint64
+	parent_timestamp uint64
+	difficulty       *big.Int
+}
+
+// verified headers of a wallet, they are only valid for the daemon and network they were obtained from
+type light_client struct {
+	sync.Mutex
+	daemon  string
+	network string
+	headers map[crypto.Hash]*light_header
+	top     uint64
+}
+
+var light_one_lsh256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// difficulty of a block built on top of parent, this mirrors blockchain.Get_Difficulty_At_Tips
+func light_difficulty(parent *light_header) (*big.Int, error) {
+	if globals.IsSimulator() {
+		return new(big.Int).SetUint64(1), nil
+	}
+
+	minimum := new(big.Int).SetUint64(config.Settings.TESTNET_MINIMUM_DIFFICULTY)
+	genesis := new(big.Int).SetUint64(config.Settings.TESTNET_BOOTSTRAP_DIFFICULTY)
+	if globals.IsMainnet() {
+		minimum = new(big.Int).SetUint64(config.Settings.MAINNET_MINIMUM_DIFFICULTY)
+		genesis = new(big.Int).SetUint64(config.Settings.MAINNET_BOOTSTRAP_DIFFICULTY)
+	}
+
+	height := int64(parent.height) + 1
+	if height < 3 || (height >= globals.Config.MAJOR_HF2_HEIGHT && height <= (globals.Config.MAJOR_HF2_HEIGHT+2)) {
+		return genesis, nil
+	}
+
+	block_time := int64(config.BLOCK_TIME_MILLISECS)
+	solve_time := int64(parent.timestamp - parent.parent_timestamp)
+	if solve_time <= 0 {
+		return nil, fmt.Errorf("invalid solve time %d", solve_time)
+	}
+	if solve_time > (block_time * 2) { // there should not be sudden decreases
+		solve_time = block_time * 2
+	}
+
+	M := int64(8)
+	easypart := int64(math.Pow(2.71828182845905, ((1-float64(solve_time)/float64(block_time))/float64(M))) * 10000)
+	difficulty := new(big.Int).Mul(parent.difficulty, new(big.Int).SetInt64(easypart))
+	difficulty.Div(difficulty, new(big.Int).SetUint64(10000))
+
+	if difficulty.Cmp(minimum) < 0 { // we can never be below minimum difficulty
+		difficulty.Set(minimum)
+	}
+	return difficulty, nil
+}
+
+// whether the pow hash meets difficulty
+func light_check_pow(pow_hash crypto.Hash, difficulty *big.Int) bool {
+	if difficulty.Sign() <= 0 {
+		return false
+	}
+	target := new(big.Int).Div(light_one_lsh256, difficulty)
+
+	var reversed [32]byte // hash is interpreted as little endian number
+	for i := range pow_hash {
+		reversed[i] = pow_hash[len(pow_hash)-1-i]
+	}
+	return new(big.Int).SetBytes(reversed[:]).Cmp(target) <= 0
+}
+
+// verify a header built on top of an already verified parent
+func light_verify_header(bl *block.Block, parent_hash crypto.Hash, parent *light_header) (*light_header, error) {
+	if len(bl.Tips) != 1 || bl.Tips[0] != parent_hash {
+		return nil, fmt.Errorf("block does not build on expected parent %s", parent_hash)
+	}
+	if bl.Height != parent.height+1 {
+		return nil, fmt.Errorf("block height %d does not follow parent height %d", bl.Height, parent.height)
+	}
+	if bl.Timestamp < parent.timestamp {
+		return nil, fmt.Errorf("block timestamp is less than its parent")
+	}
+
+	if len(bl.MiniBlocks) == 0 {
+		return nil, fmt.Errorf("block has no miniblocks")
+	}
+	if !globals.IsSimulator() && uint64(len(bl.MiniBlocks)) != (config.BLOCK_TIME-config.MINIBLOCK_HIGHDIFF+1) {
+		return nil, fmt.Errorf("incorrect number of miniblocks %d", len(bl.MiniBlocks))
+	}
+
+	// final miniblock binds the PoW to this header
+	final := bl.MiniBlocks[len(bl.MiniBlocks)-1]
+	if !final.Final || !final.HighDiff {
+		return nil, fmt.Errorf("block has no final miniblock")
+	}
+	header_hash := sha3.Sum256(bl.SerializeWithoutLastMiniBlock())
+	if !bytes.Equal(final.KeyHash[:16], header_hash[:16]) {
+		return nil, fmt.Errorf("final miniblock does not commit to block header")
+	}
+
+	difficulty, err := light_difficulty(parent)
+	if err != nil {
+		return nil, err
+	}
+	for i, mbl := range bl.MiniBlocks {
+		if mbl.Height != bl.Height || mbl.PastCount != 1 {
+			return nil, fmt.Errorf("miniblock %d has invalid height or tips", i)
+		}
+		mbl_difficulty := difficulty
+		if mbl.HighDiff {
+			mbl_difficulty = new(big.Int).Mul(difficulty, new(big.Int).SetUint64(config.MINIBLOCK_HIGHDIFF))
+		}
+		if !globals.IsSimulator() && !light_check_pow(mbl.GetPoWHash(), mbl_difficulty) { // simulator does not do PoW
+			return nil, fmt.Errorf("miniblock %d has invalid PoW", i)
+		}
+	}
+
+	return &light_header{height: bl.Height, timestamp: bl.Timestamp, parent_timestamp: parent.timestamp, difficulty: difficulty}, nil
+}
+
+// fetch a block header from daemon, make sure it matches the requested hash
+func light_fetch_block(hash crypto.Hash) (bl *block.Block, r rpc.GetBlock_Result, err error) {
+	if err = rpc_client.Call("DERO.GetBlock", rpc.GetBlock_Params{Hash: hash.String()}, &r); err != nil {
+		return
+	}
+	blob, err := hex.DecodeString(r.Blob)
+	if err != nil {
+		return
+	}
+	bl = &block.Block{}
+	if err = bl.Deserialize(blob); err != nil {
+		return
+	}
+	if bl.GetHash() != hash {
+		err = fmt.Errorf("daemon returned block %s instead of %s", bl.GetHash(), hash)
+	}
+	return
+}
+
+// forget all verified headers if daemon or network has changed, next header will be trusted on first use
+func (lc *light_client) select_daemon(daemon, network string) {
+	if lc.headers != nil && lc.daemon == daemon && lc.network == network {
+		return
+	}
+	if lc.headers != nil {
+		logger.V(1).Info("light mode forgetting verified headers", "daemon", lc.daemon, "network", lc.network)
+	}
+	lc.daemon = daemon
+	lc.network = network
+	lc.headers = map[crypto.Hash]*light_header{}
+	lc.top = 0
+}
+
+// add a header obtained from daemon, verifying it and any missing ancestors till a verified header is found
+func (lc *light_client) add_header(bl *block.Block, daemon, network string) error {
+	lc.Lock()
+	defer lc.Unlock()
+
+	lc.select_daemon(daemon, network)
+
+	hash := bl.GetHash()
+	if _, ok := lc.headers[hash]; ok {
+		return nil
+	}
+	if len(bl.Tips) != 1 {
+		return fmt.Errorf("block must have exactly 1 tip")
+	}
+
+	pending := []*block.Block{bl}
+	for {
+		parent_hash := pending[len(pending)-1].Tips[0]
+		if _, ok := lc.headers[parent_hash]; ok {
+			break
+		}
+
+		if len(pending) > light_max_link {
+			return fmt.Errorf("could not link block %s to verified headers", hash)
+		}
+
+		parent, r, err := light_fetch_block(parent_hash)
+		if err != nil {
+			return err
+		}
+
+		if len(lc.headers) == 0 { // nothing verified yet, trust this header
+			if err = lc.anchor(parent_hash, parent, r.Block_Header.Difficulty); err != nil {
+				return err
+			}
+			break
+		}
+		if len(parent.Tips) != 1 {
+			return fmt.Errorf("block %s must have exactly 1 tip", parent_hash)
+		}
+		pending = append(pending, parent)
+	}
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		parent_hash := pending[i].Tips[0]
+		header, err := light_verify_header(pending[i], parent_hash, lc.headers[parent_hash])
+		if err != nil {
+			return err
+		}
+		lc.insert(pending[i].GetHash(), header)
+	}
+	return nil
+}
+
+// trust a header as starting point, its parent is fetched for the timestamp used in difficulty
+func (lc *light_client) anchor(hash crypto.Hash, bl *block.Block, difficulty string) error {
+	diff, ok := new(big.Int).SetString(difficulty, 10)
+	if !ok || diff.Sign() <= 0 {
+		return fmt.Errorf("invalid difficulty '%s' for block %s", difficulty, hash)
+	}
+
+	header := &light_header{height: bl.Height, timestamp: bl.Timestamp, difficulty: diff}
+	if len(bl.Tips) >= 1 {
+		parent, _, err := light_fetch_block(bl.Tips[0])
+		if err != nil {
+			return err
+		}
+		header.parent_timestamp = parent.Timestamp
+	}
+
+	logger.V(1).Info("light mode trusting header", "blid", hash, "height", bl.Height)
+	lc.insert(hash, header)
+	return nil
+}
+
+func (lc *light_client) insert(hash crypto.Hash, header *light_header) {
+	lc.headers[hash] = header
+	if header.height <= lc.top {
+		return
+	}
+	lc.top = header.height
+
+	if len(lc.headers) > 2*light_header_window {
+		for k, v := range lc.headers {
+			if v.height+light_header_window < lc.top {
+				delete(lc.headers, k)
+			}
+		}
+	}
+}
+
+// fetch balance along with proof from daemon and verify it completely
+func (w *Wallet_Memory) get_verified_balance(scid crypto.Hash, topoheight int64, accountaddr string) (result rpc.GetEncryptedBalance_Result, err error) {
+	if !scid.IsZero() {
+		err = fmt.Errorf("light mode cannot verify token balances, sc trees are not committed in blocks")
+		return
+	}
+
+	var r rpc.GetEncryptedBalanceProof_Result
+	if err = rpc_client.Call("DERO.GetEncryptedBalanceProof", rpc.GetEncryptedBalanceProof_Params{SCID: scid, Address: accountaddr, TopoHeight: topoheight}, &r); err != nil {
+		return
+	}
+	if err = verify_balance_proof(accountaddr, r); err != nil {
+		return
+	}
+
+	blob, _ := hex.DecodeString(r.Block)
+	var bl block.Block
+	if err = bl.Deserialize(blob); err != nil {
+		return
+	}
+	if err = w.light.add_header(&bl, Daemon_Endpoint_Active, globals.Config.Name); err != nil {
+		return
+	}
+	return r.Balance, nil
+}
+
+// verifies the proof and that the committing block refers to state of claimed block
+// header chain itself is verified separately
+func verify_balance_proof(accountaddr string, r rpc.GetEncryptedBalanceProof_Result) error {
+	addr, err := rpc.NewAddress(accountaddr)
+	if err != nil {
+		return err
+	}
+
+	blob, err := hex.DecodeString(r.Block)
+	if err != nil {
+		return err
+	}
+	var bl block.Block
+	if err = bl.Deserialize(blob); err != nil {
+		return err
+	}
+	if len(bl.Tips) != 1 || bl.Tips[0] != r.Balance.BlockHash {
+		return fmt.Errorf("committing block does not build on block %s", r.Balance.BlockHash)
+	}
+	if bl.Height != uint64(r.Balance.Height)+1 {
+		return fmt.Errorf("committing block height %d does not follow %d", bl.Height, r.Balance.Height)
+	}
+
+	var balance_root, meta_root, merkle_hash crypto.Hash
+	if err = decode_hash(r.BalanceTreeHash, &balance_root); err != nil {
+		return err
+	}
+	if err = decode_hash(r.MetaTreeHash, &meta_root); err != nil {
+		return err
+	}
+	if state_root := block.StateRoot(balance_root, meta_root); bl.Proof == [32]byte{} || state_root != crypto.Hash(bl.Proof) {
+		return fmt.Errorf("state root %s is not committed in block %s", state_root, bl.GetHash())
+	}
+	for i := range merkle_hash {
+		merkle_hash[i] = balance_root[i] ^ meta_root[i]
+	}
+	if r.Balance.Merkle_Balance_TreeHash != fmt.Sprintf("%x", merkle_hash[:]) {
+		return fmt.Errorf("daemon reported tree hash does not match committed state root")
+	}
+
+	proof_raw, err := hex.DecodeString(r.Proof)
+	if err != nil {
+		return err
+	}
+	proof := graviton.NewProof()
+	if err = proof.Unmarshal(proof_raw); err != nil {
+		return err
+	}
+
+	key := addr.Compressed()
+	if r.Balance.Status == errormsg.ErrAccountUnregistered.Error() {
+		if !proof.VerifyNonMembership(balance_root, key) {
+			return fmt.Errorf("invalid non-membership proof for %s", accountaddr)
+		}
+		return nil
+	}
+
+	if !proof.VerifyMembership(balance_root, key) {
+		return fmt.Errorf("invalid balance proof for %s", accountaddr)
+	}
+	if fmt.Sprintf("%x", proof.Value()) != r.Balance.Data {
+		return fmt.Errorf("daemon reported balance does not match proof")
+	}
+	return nil
+}
+
+func decode_hash(s string, h *crypto.Hash) error {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(raw) != len(h) {
+		return fmt.Errorf("hash '%s' is not of 32 bytes", s)
+	}
+	copy(h[:], raw)
+	return nil
+}
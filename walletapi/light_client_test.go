@@ -0,0 +1,232 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi
+
+import "os"
+import "fmt"
+import "time"
+import "context"
+import "testing"
+import "path/filepath"
+import "math/big"
+import "encoding/hex"
+
+import derodrpc "github.com/deroproject/derohe/cmd/derod/rpc"
+
+import "github.com/deroproject/derohe/globals"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/block"
+import "github.com/deroproject/derohe/errormsg"
+import "github.com/deroproject/derohe/blockchain"
+import "github.com/deroproject/derohe/transaction"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// mine a complete block, so that final miniblock commits to header
+func light_chain_mineblock(chain *blockchain.Blockchain, miner_address rpc.Address, t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		bl, mbl, _, _, err := chain.Create_new_block_template_mining(miner_address)
+		if err != nil {
+			t.Fatalf("error creating template %s", err)
+		}
+		_, blid, _, err := chain.Accept_new_block(bl.Timestamp, mbl.Serialize())
+		if err != nil {
+			t.Fatalf("error submitting miniblock %s", err)
+		}
+		if !blid.IsZero() {
+			return
+		}
+		time.Sleep(15 * time.Millisecond) // let template pick our miniblocks
+	}
+	t.Fatalf("could not mine a block")
+}
+
+func Test_Light_Balance_Proof(t *testing.T) {
+	wsrc_temp_db := filepath.Join(os.TempDir(), "dero_temporary_test_wallet_light_src.db")
+	wdst_temp_db := filepath.Join(os.TempDir(), "dero_temporary_test_wallet_light_dst.db")
+	os.Remove(wsrc_temp_db)
+	os.Remove(wdst_temp_db)
+	defer os.Remove(wsrc_temp_db)
+	defer os.Remove(wdst_temp_db)
+
+	wsrc, err := Create_Encrypted_Wallet_From_Recovery_Words(wsrc_temp_db, "QWER", "sequence atlas unveil summon pebbles tuesday beer rudely snake rockets different fuselage woven tagged bested dented vegan hover rapid fawns obvious muppet randomly seasons randomly")
+	if err != nil {
+		t.Fatalf("Cannot create encrypted wallet, err %s", err)
+	}
+	wdst, err := Create_Encrypted_Wallet_From_Recovery_Words(wdst_temp_db, "QWER", "Dekade Spagat Bereich Radclub Yeti Dialekt Unimog Nomade Anlage Hirte Besitz Märzluft Krabbe Nabel Halsader Chefarzt Hering tauchen Neuerung Reifen Umgang Hürde Alchimie Amnesie Reifen")
+	if err != nil {
+		t.Fatalf("Cannot create encrypted wallet, err %s", err)
+	}
+
+	genesis_tx := transaction.Transaction{Transaction_Prefix: transaction.Transaction_Prefix{Version: 1, Value: 2012345}}
+	copy(genesis_tx.MinerAddress[:], wsrc.account.Keys.Public.EncodeCompressed())
+	config.Testnet.Genesis_Tx = fmt.Sprintf("%x", genesis_tx.Serialize())
+	config.Mainnet.Genesis_Tx = fmt.Sprintf("%x", genesis_tx.Serialize())
+	genesis_block := blockchain.Generate_Genesis_Block()
+	config.Testnet.Genesis_Block_Hash = genesis_block.GetHash()
+	config.Mainnet.Genesis_Block_Hash = genesis_block.GetHash()
+
+	chain, rpcserver, _ := simulator_chain_start()
+	defer simulator_chain_stop(chain, rpcserver)
+
+	globals.Arguments["--simulator"] = true // light client uses simulator difficulty
+	defer delete(globals.Arguments, "--simulator")
+
+	for i := 0; i < 3; i++ {
+		light_chain_mineblock(chain, wsrc.GetAddress(), t)
+	}
+
+	// blocks must commit to the state of their tip
+	top, _ := chain.Load_BL_FROM_ID(chain.Get_Top_ID())
+	if state, err := chain.Load_Tips_State_Root(top.Tips); err != nil || state != top.Proof {
+		t.Fatalf("block does not commit to state of tip err %v", err)
+	}
+
+	r, err := derodrpc.GetEncryptedBalanceProof(context.Background(), rpc.GetEncryptedBalanceProof_Params{Address: wsrc.GetAddress().String(), TopoHeight: -1})
+	if err != nil {
+		t.Fatalf("proof rpc failed err %s", err)
+	}
+	if r.Balance.Status != "OK" || r.Balance.Topoheight >= chain.Load_TOPO_HEIGHT() {
+		t.Fatalf("unexpected balance result %+v", r.Balance)
+	}
+	if err = verify_balance_proof(wsrc.GetAddress().String(), r); err != nil {
+		t.Fatalf("valid proof rejected err %s", err)
+	}
+
+	// daemon lying about balance or roots must be caught
+	tampered := r
+	tampered.Balance.Data = r.Balance.Data[:len(r.Balance.Data)-2] + "00"
+	if tampered.Balance.Data == r.Balance.Data {
+		tampered.Balance.Data = r.Balance.Data[:len(r.Balance.Data)-2] + "01"
+	}
+	if err = verify_balance_proof(wsrc.GetAddress().String(), tampered); err == nil {
+		t.Fatalf("tampered balance accepted")
+	}
+	tampered = r
+	tampered.BalanceTreeHash = r.MetaTreeHash
+	if err = verify_balance_proof(wsrc.GetAddress().String(), tampered); err == nil {
+		t.Fatalf("tampered root accepted")
+	}
+
+	// a made up balance root paired with a crafted meta root must not match the commitment
+	var proof_root, fake_root, meta_root crypto.Hash
+	commit_blob, _ := hex.DecodeString(r.Block)
+	var commit_bl block.Block
+	if err = commit_bl.Deserialize(commit_blob); err != nil {
+		t.Fatalf("cannot deserialize block err %s", err)
+	}
+	proof_root = crypto.Hash(commit_bl.Proof)
+	fake_root[0] = 1
+	for i := range meta_root {
+		meta_root[i] = proof_root[i] ^ fake_root[i]
+	}
+	tampered = r
+	tampered.BalanceTreeHash = fmt.Sprintf("%x", fake_root[:])
+	tampered.MetaTreeHash = fmt.Sprintf("%x", meta_root[:])
+	tampered.Balance.Merkle_Balance_TreeHash = fmt.Sprintf("%x", proof_root[:])
+	if err = verify_balance_proof(wsrc.GetAddress().String(), tampered); err == nil {
+		t.Fatalf("forged root pair accepted")
+	}
+
+	tampered = r
+	tampered.Balance.Status = errormsg.ErrAccountUnregistered.Error()
+	if err = verify_balance_proof(wsrc.GetAddress().String(), tampered); err == nil {
+		t.Fatalf("registered account accepted as unregistered")
+	}
+
+	// unregistered accounts come with non-membership proof
+	u, err := derodrpc.GetEncryptedBalanceProof(context.Background(), rpc.GetEncryptedBalanceProof_Params{Address: wdst.GetAddress().String(), TopoHeight: -1})
+	if err != nil {
+		t.Fatalf("proof rpc failed err %s", err)
+	}
+	if u.Balance.Status != errormsg.ErrAccountUnregistered.Error() {
+		t.Fatalf("expected unregistered status, actual %s", u.Balance.Status)
+	}
+	if err = verify_balance_proof(wdst.GetAddress().String(), u); err != nil {
+		t.Fatalf("valid non-membership proof rejected err %s", err)
+	}
+
+	// verify committing header against its parent
+	var bl block.Block
+	blob, _ := hex.DecodeString(r.Block)
+	if err = bl.Deserialize(blob); err != nil {
+		t.Fatalf("cannot deserialize block err %s", err)
+	}
+	parent_bl, err := chain.Load_BL_FROM_ID(bl.Tips[0])
+	if err != nil {
+		t.Fatalf("cannot load parent err %s", err)
+	}
+	parent := &light_header{height: parent_bl.Height, timestamp: parent_bl.Timestamp, difficulty: chain.Load_Block_Difficulty(bl.Tips[0])}
+	if _, err = light_verify_header(&bl, bl.Tips[0], parent); err != nil {
+		t.Fatalf("valid header rejected err %s", err)
+	}
+	bl.Timestamp++
+	if _, err = light_verify_header(&bl, bl.Tips[0], parent); err == nil {
+		t.Fatalf("header not bound to final miniblock accepted")
+	}
+}
+
+// light client must agree with consensus code on difficulty and PoW
+func Test_Light_Difficulty(t *testing.T) {
+	parent := &light_header{height: 600000, timestamp: 1000000 + 15000, parent_timestamp: 1000000, difficulty: new(big.Int).SetUint64(50000000)}
+	expected := blockchain.DiffBig(15000, int64(config.BLOCK_TIME_MILLISECS), 8, parent.difficulty)
+
+	if diff, err := light_difficulty(parent); err != nil || diff.Cmp(expected) != 0 {
+		t.Fatalf("difficulty mismatch expected %s actual %s err %v", expected, diff, err)
+	}
+
+	parent.parent_timestamp = parent.timestamp
+	if _, err := light_difficulty(parent); err == nil {
+		t.Fatalf("zero solve time must fail")
+	}
+
+	for i := 0; i < 64; i++ {
+		var h crypto.Hash
+		h[31] = byte(i * 4)
+		h[0] = byte(i)
+		if light_check_pow(h, expected) != blockchain.CheckPowHashBig(h, expected) {
+			t.Fatalf("pow check mismatch for %s", h)
+		}
+	}
+}
+
+// verified headers are per wallet and forgotten when daemon or network changes
+func Test_Light_Select_Daemon(t *testing.T) {
+	var w1, w2 Wallet_Memory
+	w1.light.select_daemon("127.0.0.1:40402", "testnet")
+	w1.light.insert(crypto.Hash{1}, &light_header{height: 10})
+	w2.light.select_daemon("127.0.0.1:40402", "testnet")
+	if len(w2.light.headers) != 0 {
+		t.Fatalf("headers leaked between wallets")
+	}
+
+	w1.light.select_daemon("127.0.0.1:40402", "testnet")
+	if len(w1.light.headers) != 1 || w1.light.top != 10 {
+		t.Fatalf("headers must be kept for same daemon")
+	}
+
+	w1.light.select_daemon("127.0.0.2:40402", "testnet")
+	if len(w1.light.headers) != 0 || w1.light.top != 0 {
+		t.Fatalf("headers must be forgotten when daemon changes")
+	}
+
+	w1.light.insert(crypto.Hash{1}, &light_header{height: 10})
+	w1.light.select_daemon("127.0.0.2:40402", "mainnet")
+	if len(w1.light.headers) != 0 {
+		t.Fatalf("headers must be forgotten when network changes")
+	}
+}
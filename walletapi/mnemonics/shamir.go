@@ -0,0 +1,406 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package mnemonics
+
+import "fmt"
+import "math/big"
+import "strings"
+import "crypto/hmac"
+import "crypto/rand"
+import "crypto/sha256"
+import "encoding/binary"
+
+// this file implements SLIP-39 style shamir secret sharing of the seed
+// the seed is split into group shares, each group share is split into member shares
+// recovery requires member threshold shares from atleast group threshold groups
+// shares are encoded in the same word lists as seeds, so they can be written down in any language
+// see https://github.com/satoshilabs/slips/blob/master/slip-0039.md for the ideas used here
+
+const SHARE_VERSION = 1
+const SHARE_MAX = 16    // maximum groups and maximum members within a group
+const SHARE_LENGTH = 31 // 41 bytes share = 328 bits, encoded in base 1626 needs 31 words
+const share_bytes = 41  // 2 bytes id + 3 bytes params + 32 bytes value + 4 bytes checksum
+const share_value_len = 32
+const share_checksum_len = 4
+
+const secret_index = 255 // x coordinate where secret is stored
+const digest_index = 254 // x coordinate where digest of secret is stored
+
+var share_customization = []byte("DERO shamir")
+
+// a group of shares, threshold shares out of count are required to recover group share
+type Share_Group struct {
+	Threshold int
+	Count     int
+}
+
+// decoded share
+type share struct {
+	identifier       uint16
+	group_index      int
+	group_threshold  int
+	group_count      int
+	member_index     int
+	member_threshold int
+	value            []byte
+}
+
+type share_point struct {
+	x byte
+	y []byte
+}
+
+var gf_exp [255]byte
+var gf_log [256]byte
+
+// setup GF(256) tables using AES polynomial x^8 + x^4 + x^3 + x + 1 and generator 3
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gf_exp[i] = x
+		gf_log[x] = byte(i)
+
+		xtime := x << 1 // multiply by 2
+		if x&0x80 != 0 {
+			xtime ^= 0x1b
+		}
+		x ^= xtime // multiply by 3
+	}
+}
+
+// lagrange interpolation of all points at x, every byte is processed independently
+func interpolate(points []share_point, x byte) (result []byte, err error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no points to interpolate")
+	}
+	length := len(points[0].y)
+	for i := range points {
+		if points[i].x == x {
+			return append([]byte{}, points[i].y...), nil
+		}
+		if len(points[i].y) != length {
+			return nil, fmt.Errorf("share values have different lengths")
+		}
+		for j := range points[:i] {
+			if points[i].x == points[j].x {
+				return nil, fmt.Errorf("duplicate share index %d", points[i].x)
+			}
+		}
+	}
+
+	// log of product of (x - x_j) for all j
+	log_prod := 0
+	for j := range points {
+		log_prod += int(gf_log[points[j].x^x])
+	}
+
+	result = make([]byte, length)
+	for i := range points {
+		// log of basis polynomial = log_prod - log(x - x_i) - sum log(x_i - x_j)
+		log_basis := log_prod - int(gf_log[points[i].x^x])
+		for j := range points {
+			if i != j {
+				log_basis -= int(gf_log[points[i].x^points[j].x])
+			}
+		}
+		log_basis = ((log_basis % 255) + 255) % 255
+
+		for k := range result {
+			if points[i].y[k] != 0 {
+				result[k] ^= gf_exp[(int(gf_log[points[i].y[k]])+log_basis)%255]
+			}
+		}
+	}
+	return
+}
+
+func share_digest(random_part []byte, secret []byte) []byte {
+	h := hmac.New(sha256.New, random_part)
+	h.Write(secret)
+	return h.Sum(nil)[:4]
+}
+
+// splits the secret into count shares, any threshold of them can recover the secret
+// secret is stored at x=255 and its digest at x=254, so wrong combinations are detected
+func split_secret(threshold, count int, secret []byte) (shares [][]byte, err error) {
+	if threshold < 1 || threshold > count || count > SHARE_MAX {
+		return nil, fmt.Errorf("invalid threshold %d of %d", threshold, count)
+	}
+
+	if threshold == 1 { // every share is the secret itself
+		for i := 0; i < count; i++ {
+			shares = append(shares, append([]byte{}, secret...))
+		}
+		return
+	}
+
+	var points []share_point
+	for i := 0; i < threshold-2; i++ {
+		value := make([]byte, len(secret))
+		if _, err = rand.Read(value); err != nil {
+			return nil, err
+		}
+		shares = append(shares, value)
+		points = append(points, share_point{x: byte(i), y: value})
+	}
+
+	random_part := make([]byte, len(secret)-4)
+	if _, err = rand.Read(random_part); err != nil {
+		return nil, err
+	}
+	digest := append(share_digest(random_part, secret), random_part...)
+
+	points = append(points, share_point{x: digest_index, y: digest}, share_point{x: secret_index, y: secret})
+
+	for i := threshold - 2; i < count; i++ {
+		var value []byte
+		if value, err = interpolate(points, byte(i)); err != nil {
+			return nil, err
+		}
+		shares = append(shares, value)
+	}
+	return
+}
+
+// recovers the secret from the shares and verifies its digest
+func recover_secret(threshold int, points []share_point) (secret []byte, err error) {
+	if len(points) < threshold {
+		return nil, fmt.Errorf("insufficient shares, need %d got %d", threshold, len(points))
+	}
+	points = points[:threshold]
+
+	if threshold == 1 {
+		return append([]byte{}, points[0].y...), nil
+	}
+
+	if secret, err = interpolate(points, secret_index); err != nil {
+		return nil, err
+	}
+	digest, err := interpolate(points, digest_index)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(digest[:4], share_digest(digest[4:], secret)) {
+		return nil, fmt.Errorf("Share digest mismatch, shares do not belong together")
+	}
+	return
+}
+
+// serialize the share including checksum
+func (s *share) encode() []byte {
+	buf := make([]byte, share_bytes)
+	binary.BigEndian.PutUint16(buf[0:], s.identifier)
+	buf[2] = byte(s.group_index<<4 | (s.group_threshold - 1))
+	buf[3] = byte((s.group_count-1)<<4 | s.member_index)
+	buf[4] = byte((s.member_threshold-1)<<4 | SHARE_VERSION)
+	copy(buf[5:], s.value)
+
+	checksum := sha256.Sum256(append(append([]byte{}, share_customization...), buf[:share_bytes-share_checksum_len]...))
+	copy(buf[share_bytes-share_checksum_len:], checksum[:share_checksum_len])
+	return buf
+}
+
+// deserialize the share and verify checksum
+func (s *share) decode(buf []byte) error {
+	if len(buf) != share_bytes {
+		return fmt.Errorf("Invalid share length")
+	}
+
+	checksum := sha256.Sum256(append(append([]byte{}, share_customization...), buf[:share_bytes-share_checksum_len]...))
+	if !hmac.Equal(checksum[:share_checksum_len], buf[share_bytes-share_checksum_len:]) {
+		return fmt.Errorf("Share Checksum failed")
+	}
+
+	if buf[4]&0x0f != SHARE_VERSION {
+		return fmt.Errorf("Unknown share version %d", buf[4]&0x0f)
+	}
+
+	s.identifier = binary.BigEndian.Uint16(buf[0:])
+	s.group_index = int(buf[2] >> 4)
+	s.group_threshold = int(buf[2]&0x0f) + 1
+	s.group_count = int(buf[3]>>4) + 1
+	s.member_index = int(buf[3] & 0x0f)
+	s.member_threshold = int(buf[4]>>4) + 1
+	s.value = append([]byte{}, buf[5:5+share_value_len]...)
+
+	if s.group_threshold > s.group_count || s.group_index >= s.group_count {
+		return fmt.Errorf("Invalid share group parameters")
+	}
+	return nil
+}
+
+// encode share bytes as words from the language, most significant word first
+func share_to_words(buf []byte, l_index int) string {
+	word_list_length := big.NewInt(int64(len(Languages[l_index].Words)))
+	value := new(big.Int).SetBytes(buf)
+
+	words := make([]string, SHARE_LENGTH)
+	digit := new(big.Int)
+	for i := SHARE_LENGTH - 1; i >= 0; i-- {
+		value.DivMod(value, word_list_length, digit)
+		words[i] = Languages[l_index].Words[digit.Int64()]
+	}
+	return strings.Join(words, " ")
+}
+
+// decode share words, returns language index
+func words_to_share(words_line string) (s share, l_index int, err error) {
+	words := strings.Fields(words_line)
+	if len(words) != SHARE_LENGTH {
+		err = fmt.Errorf("Invalid share, expected %d words got %d", SHARE_LENGTH, len(words))
+		return
+	}
+
+	indices, l_index, word_list_length, found := Find_indices(words)
+	if !found {
+		err = fmt.Errorf("Share not found in any Language")
+		return
+	}
+
+	value := new(big.Int)
+	for _, index := range indices {
+		value.Mul(value, new(big.Int).SetUint64(word_list_length))
+		value.Add(value, new(big.Int).SetUint64(index))
+	}
+	if value.BitLen() > share_bytes*8 {
+		err = fmt.Errorf("Share Checksum failed")
+		return
+	}
+
+	err = s.decode(value.FillBytes(make([]byte, share_bytes)))
+	return
+}
+
+// splits key into shares, group_threshold of the groups are required to recover the key
+// returned shares are arranged per group, every share is a line of SHARE_LENGTH words
+// language must exist, if not we use english
+func Key_To_Shares(keybig *big.Int, group_threshold int, groups []Share_Group, language string) (shares [][]string, err error) {
+	if len(groups) < 1 || len(groups) > SHARE_MAX {
+		return nil, fmt.Errorf("number of groups must be between 1 and %d", SHARE_MAX)
+	}
+	if group_threshold < 1 || group_threshold > len(groups) {
+		return nil, fmt.Errorf("group threshold must be between 1 and %d", len(groups))
+	}
+	if keybig.BitLen() > share_value_len*8 {
+		return nil, fmt.Errorf("key too large")
+	}
+	for i := range groups {
+		if groups[i].Threshold == 1 && groups[i].Count > 1 {
+			return nil, fmt.Errorf("group %d: 1 of %d shares is not secure, use 1 of 1 instead", i+1, groups[i].Count)
+		}
+	}
+
+	l_index := 0
+	for i := range Languages {
+		if Languages[i].Name == language {
+			l_index = i
+			break
+		}
+	}
+
+	var id [2]byte
+	if _, err = rand.Read(id[:]); err != nil {
+		return
+	}
+	identifier := binary.BigEndian.Uint16(id[:])
+
+	group_secrets, err := split_secret(group_threshold, len(groups), keybig.FillBytes(make([]byte, share_value_len)))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		var member_values [][]byte
+		if member_values, err = split_secret(groups[i].Threshold, groups[i].Count, group_secrets[i]); err != nil {
+			return nil, fmt.Errorf("group %d: %s", i+1, err)
+		}
+
+		var group_shares []string
+		for j := range member_values {
+			s := share{identifier: identifier, group_index: i, group_threshold: group_threshold, group_count: len(groups),
+				member_index: j, member_threshold: groups[i].Threshold, value: member_values[j]}
+			group_shares = append(group_shares, share_to_words(s.encode(), l_index))
+		}
+		shares = append(shares, group_shares)
+	}
+	return
+}
+
+// recovers key from shares, every share is a line of SHARE_LENGTH words
+// shares may be supplied in any order, surplus shares are ignored
+func Shares_To_Key(shares []string) (language_name string, keybig *big.Int, err error) {
+	if len(shares) < 1 {
+		return "", nil, fmt.Errorf("no shares provided")
+	}
+
+	var first share
+	group_points := map[int][]share_point{}
+	group_thresholds := map[int]int{}
+
+	for i := range shares {
+		s, l_index, err := words_to_share(shares[i])
+		if err != nil {
+			return "", nil, fmt.Errorf("share %d: %s", i+1, err)
+		}
+
+		if i == 0 {
+			first = s
+			language_name = Languages[l_index].Name
+		} else if s.identifier != first.identifier || s.group_threshold != first.group_threshold || s.group_count != first.group_count {
+			return "", nil, fmt.Errorf("share %d does not belong to the same set", i+1)
+		}
+
+		if threshold, ok := group_thresholds[s.group_index]; ok && threshold != s.member_threshold {
+			return "", nil, fmt.Errorf("share %d has mismatching member threshold", i+1)
+		}
+		group_thresholds[s.group_index] = s.member_threshold
+
+		duplicate := false
+		for _, p := range group_points[s.group_index] {
+			if int(p.x) == s.member_index {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			group_points[s.group_index] = append(group_points[s.group_index], share_point{x: byte(s.member_index), y: s.value})
+		}
+	}
+
+	var points []share_point
+	for group_index, members := range group_points {
+		if len(members) < group_thresholds[group_index] {
+			continue // not enough shares for this group
+		}
+		var group_secret []byte
+		if group_secret, err = recover_secret(group_thresholds[group_index], members); err != nil {
+			return "", nil, fmt.Errorf("group %d: %s", group_index+1, err)
+		}
+		points = append(points, share_point{x: byte(group_index), y: group_secret})
+	}
+
+	if len(points) < first.group_threshold {
+		return "", nil, fmt.Errorf("insufficient shares, need %d complete groups got %d", first.group_threshold, len(points))
+	}
+
+	secret, err := recover_secret(first.group_threshold, points)
+	if err != nil {
+		return "", nil, err
+	}
+	keybig = new(big.Int).SetBytes(secret)
+	return
+}
@@ -0,0 +1,112 @@
+// Copyright 2017-2018 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package mnemonics
+
+import "strings"
+import "testing"
+import "math/big"
+
+// split a key into shares and recover it using various combinations
+func Test_Shamir_Shares(t *testing.T) {
+	key, _ := new(big.Int).SetString("0fb0ef6bd527b9b23b9ceef70dc8b4cd1ee83ca14541964e764ad23f5151204f", 16)
+
+	// single group, any 2 of 3
+	shares, err := Key_To_Shares(key, 1, []Share_Group{{Threshold: 2, Count: 3}}, "Deutsch")
+	if err != nil {
+		t.Fatalf("splitting key failed err %s", err)
+	}
+	if len(shares) != 1 || len(shares[0]) != 3 || len(strings.Fields(shares[0][0])) != SHARE_LENGTH {
+		t.Fatalf("unexpected shares %+v", shares)
+	}
+
+	combinations := [][]int{{0, 1}, {1, 2}, {2, 0}, {0, 1, 2}}
+	for _, c := range combinations {
+		var input []string
+		for _, i := range c {
+			input = append(input, shares[0][i])
+		}
+		language, recovered, err := Shares_To_Key(input)
+		if err != nil || recovered.Cmp(key) != 0 || language != "Deutsch" {
+			t.Fatalf("recovering key from shares %v failed err %s language %s", c, err, language)
+		}
+	}
+
+	if _, _, err := Shares_To_Key([]string{shares[0][1]}); err == nil {
+		t.Fatalf("recovering key from single share must fail")
+	}
+
+	if _, _, err := Shares_To_Key([]string{shares[0][1], shares[0][1]}); err == nil {
+		t.Fatalf("recovering key from duplicate share must fail")
+	}
+
+	// corrupt a single word, checksum must catch it
+	words := strings.Fields(shares[0][0])
+	if words[5] == Mnemonics_German.Words[0] {
+		words[5] = Mnemonics_German.Words[1]
+	} else {
+		words[5] = Mnemonics_German.Words[0]
+	}
+	if _, _, err := Shares_To_Key([]string{strings.Join(words, " "), shares[0][1]}); err == nil {
+		t.Fatalf("corrupted share must fail")
+	}
+
+	// shares from a different split must not be mixed
+	other, err := Key_To_Shares(key, 1, []Share_Group{{Threshold: 2, Count: 3}}, "English")
+	if err != nil {
+		t.Fatalf("splitting key failed err %s", err)
+	}
+	if _, _, err := Shares_To_Key([]string{shares[0][0], other[0][1]}); err == nil {
+		t.Fatalf("mixing shares of different sets must fail")
+	}
+}
+
+// two level sharing, 2 groups out of 3 are required
+func Test_Shamir_Groups(t *testing.T) {
+	key, _ := new(big.Int).SetString("b0ef6bd527b9b23b9ceef70dc8b4cd1ee83ca14541964e764ad23f5151204f0f", 16)
+
+	groups := []Share_Group{{Threshold: 1, Count: 1}, {Threshold: 2, Count: 3}, {Threshold: 3, Count: 5}}
+	shares, err := Key_To_Shares(key, 2, groups, "English")
+	if err != nil {
+		t.Fatalf("splitting key failed err %s", err)
+	}
+
+	inputs := [][]string{
+		{shares[0][0], shares[1][0], shares[1][2]},
+		{shares[1][1], shares[1][2], shares[2][4], shares[2][0], shares[2][2]},
+		{shares[2][1], shares[2][3], shares[2][4], shares[0][0], shares[1][0]}, // incomplete group is ignored
+	}
+	for i := range inputs {
+		if _, recovered, err := Shares_To_Key(inputs[i]); err != nil || recovered.Cmp(key) != 0 {
+			t.Fatalf("recovering key from input %d failed err %s", i, err)
+		}
+	}
+
+	if _, _, err := Shares_To_Key([]string{shares[0][0], shares[1][0], shares[2][0], shares[2][1]}); err == nil {
+		t.Fatalf("recovering key from single complete group must fail")
+	}
+
+	// invalid parameters
+	if _, err := Key_To_Shares(key, 3, groups[:2], "English"); err == nil {
+		t.Fatalf("group threshold more than groups must fail")
+	}
+	if _, err := Key_To_Shares(key, 1, []Share_Group{{Threshold: 1, Count: 3}}, "English"); err == nil {
+		t.Fatalf("1 of 3 shares must fail")
+	}
+	if _, err := Key_To_Shares(key, 1, []Share_Group{{Threshold: 2, Count: 17}}, "English"); err == nil {
+		t.Fatalf("more than %d shares must fail", SHARE_MAX)
+	}
+}
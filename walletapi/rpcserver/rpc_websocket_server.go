@@ -309,6 +309,10 @@ var wallet_handler = handler.Map{
 	"GetTransferbyTXID":        handler.New(GetTransferbyTXID),
 	"get_transfers":            handler.New(GetTransfers),
 	"GetTransfers":             handler.New(GetTransfers),
+	"get_tx_proof":             handler.New(GetTxProof),
+	"GetTxProof":               handler.New(GetTxProof),
+	"get_outbox":               handler.New(GetOutbox),
+	"GetOutbox":                handler.New(GetOutbox),
 	"make_integrated_address":  handler.New(MakeIntegratedAddress),
 	"MakeIntegratedAddress":    handler.New(MakeIntegratedAddress),
 	"split_integrated_address": handler.New(SplitIntegratedAddress),
@@ -30,6 +30,7 @@ import (
 
 	"github.com/deroproject/derohe/cryptography/bn256"
 	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/proof"
 	"github.com/deroproject/derohe/rpc"
 	"github.com/deroproject/derohe/transaction"
 	"github.com/deroproject/derohe/walletapi/mnemonics"
@@ -51,6 +52,7 @@ type Account struct {
 	Keys           _Keys   `json:"keys"`
 	SeedLanguage   string  `json:"seedlanguage"`
 	FeesMultiplier float32 `json:"feesmultiplier"` // fees multiplier accurate to 2 decimals
+	FeesAuto       bool    `json:"feesauto"`       // multiplier is derived from daemon fee estimate
 	Ringsize       int     `json:"ringsize"`       // default mixn to use for txs
 	mainnet        bool
 	Registered     bool `json:"registered"`
@@ -70,6 +72,8 @@ type Account struct {
 
 	RingMembers map[string]int64 `json:"ring_members"` // ring members
 
+	Outbox []Outbox_Record `json:"outbox,omitempty"` // sent txs, tracked till they become stable
+
 	SaveChangesEvery time.Duration `json:"-"` // default is zero
 	lastsaved        time.Time
 
@@ -161,10 +165,24 @@ func Generate_Keys_From_Seed(Seed *crypto.BNRed) (keys _Keys) {
 	return
 }
 
+// convert recovery words to key, words may either be a seed or a set of shares
+// shares are detected by their length and may be supplied one after another
+func recovery_words_to_key(words string) (language string, seed *big.Int, err error) {
+	fields := strings.Fields(words)
+	if len(fields) > 0 && len(fields)%mnemonics.SHARE_LENGTH == 0 {
+		var shares []string
+		for i := 0; i < len(fields); i += mnemonics.SHARE_LENGTH {
+			shares = append(shares, strings.Join(fields[i:i+mnemonics.SHARE_LENGTH], " "))
+		}
+		return mnemonics.Shares_To_Key(shares)
+	}
+	return mnemonics.Words_To_Key(words)
+}
+
 // generate user account using recovery seeds
 func Generate_Account_From_Recovery_Words(words string) (user *Account, err error) {
 	user = &Account{Ringsize: 16, FeesMultiplier: 2.0}
-	language, seed, err := mnemonics.Words_To_Key(words)
+	language, seed, err := recovery_words_to_key(words)
 	if err != nil {
 		return
 	}
@@ -189,6 +207,12 @@ func (w *Wallet_Memory) GetSeed() (str string) {
 	return mnemonics.Key_To_Words(w.account.Keys.Secret.BigInt(), w.account.SeedLanguage)
 }
 
+// split seed into shares, group_threshold of the groups are needed to recover the seed
+// shares are in the seed language
+func (w *Wallet_Memory) GetSeedShares(group_threshold int, groups []mnemonics.Share_Group) (shares [][]string, err error) {
+	return mnemonics.Key_To_Shares(w.account.Keys.Secret.BigInt(), group_threshold, groups, w.GetSeedLanguage())
+}
+
 // convert key to seed using language
 func (w *Wallet_Memory) GetSeedinLanguage(lang string) (str string) {
 	return mnemonics.Key_To_Words(w.account.Keys.Secret.BigInt(), lang)
@@ -419,6 +443,20 @@ func (w *Wallet_Memory) GetMode() bool {
 	return w.wallet_online_mode
 }
 
+// in light mode, daemon is not trusted for balances, they are verified using merkle proofs
+// against state roots committed in block headers, whose PoW is checked by the wallet
+// token balances cannot be verified and will return error in this mode
+func (w *Wallet_Memory) SetLightMode(light bool) bool {
+	current_mode := w.light_mode
+	w.light_mode = light
+	return current_mode
+}
+
+// whether wallet is in light mode
+func (w *Wallet_Memory) GetLightMode() bool {
+	return w.light_mode
+}
+
 // use the endpoint set  by the program
 func (w *Wallet_Memory) SetDaemonAddress(endpoint string) string {
 	Daemon_Endpoint = endpoint
@@ -464,10 +502,11 @@ func (w *Wallet_Memory) GetRingSize() int {
 	return w.account.Ringsize
 }
 
-// sets a fee multiplier
+// sets a fee multiplier, this also disables auto mode
 func (w *Wallet_Memory) SetFeeMultiplier(x float32) float32 {
 	defer w.save_if_disk() // save wallet
-	if x < 1.0 {           // fee cannot be less than 1.0, base fees
+	w.account.FeesAuto = false
+	if x < 1.0 { // fee cannot be less than 1.0, base fees
 		w.account.FeesMultiplier = 2.0
 	} else {
 		w.account.FeesMultiplier = x
@@ -475,8 +514,26 @@ func (w *Wallet_Memory) SetFeeMultiplier(x float32) float32 {
 	return w.account.FeesMultiplier
 }
 
+// in auto mode, multiplier follows the fee per KB daemon suggests for next block
+func (w *Wallet_Memory) SetFeeMultiplierAuto(auto bool) bool {
+	defer w.save_if_disk() // save wallet
+	w.account.FeesAuto = auto
+	return w.account.FeesAuto
+}
+
+func (w *Wallet_Memory) GetFeeMultiplierAuto() bool {
+	return w.account.FeesAuto
+}
+
 // gets current fee multiplier
 func (w *Wallet_Memory) GetFeeMultiplier() float32 {
+	if w.account.FeesAuto {
+		if multiplier, err := w.auto_fee_multiplier(); err == nil {
+			return multiplier
+		}
+		logger.V(1).Info("fee estimate not available, using default priority")
+		return 2.0
+	}
 	if w.account.FeesMultiplier < 1.0 {
 		return 1.0
 	}
@@ -593,6 +650,32 @@ func (w *Wallet_Memory) GetTXKey(txhash string) string {
 	return ""
 }
 
+// retrieve self-contained proofs for any tx we have sent or received, one per transfer
+// these proofs can be verified by daemon without any other information
+func (w *Wallet_Memory) GetTXProofs(txhash string) (proofs []string, err error) {
+	w.Lock()
+	defer w.Unlock()
+
+	txid := crypto.HashHexToHash(txhash)
+	for _, entries := range w.account.EntriesNative {
+		for _, e := range entries {
+			if e.TXID != txhash || e.Proof == "" {
+				continue
+			}
+			var p string
+			if p, err = proof.NewTxProof(e.Proof, txid); err != nil {
+				return
+			}
+			proofs = append(proofs, p)
+		}
+	}
+
+	if len(proofs) == 0 {
+		err = fmt.Errorf("TX not found in database")
+	}
+	return
+}
+
 // never do any division operation on money due to floating point issues
 // newbies, see type the next in python interpretor "3.33-3.13"
 func FormatMoney(amount uint64) string {
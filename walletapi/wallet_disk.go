@@ -23,7 +23,6 @@ import "sync"
 import "io/ioutil"
 
 import "github.com/deroproject/derohe/cryptography/crypto"
-import "github.com/deroproject/derohe/walletapi/mnemonics"
 
 // this is stored in disk in encrypted form
 type Wallet_Disk struct {
@@ -62,7 +61,7 @@ func Create_Encrypted_Wallet(filename string, password string, seed *crypto.BNRe
 func Create_Encrypted_Wallet_From_Recovery_Words(filename string, password string, electrum_seed string) (wd *Wallet_Disk, err error) {
 	wd = &Wallet_Disk{filename: filename}
 
-	language, seed, err := mnemonics.Words_To_Key(electrum_seed)
+	language, seed, err := recovery_words_to_key(electrum_seed)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,209 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/rpc"
+)
+
+// this file implements export of transfer history in formats suitable for accounting tools
+
+const (
+	EXPORT_CSV   = "csv"
+	EXPORT_JSONL = "jsonl"
+)
+
+// filters applied while exporting, zero values disable the respective filter
+type Export_Filter struct {
+	SCIDs           []crypto.Hash // if empty, DERO and all tracked tokens are exported
+	Coinbase        bool
+	In              bool
+	Out             bool
+	Min_Height      uint64
+	Max_Height      uint64
+	Min_Time        time.Time
+	Max_Time        time.Time
+	DestinationPort uint64
+}
+
+// a single exported record, basically an entry along with accounting fields
+type Export_Entry struct {
+	SCID      crypto.Hash `json:"scid"`
+	Direction string      `json:"direction"` // coinbase, in or out
+	Balance   uint64      `json:"balance"`   // running balance after this entry was applied
+	rpc.Entry
+}
+
+// returns the direction of an entry as used in exports
+func entry_direction(e rpc.Entry) string {
+	switch {
+	case e.Coinbase:
+		return "coinbase"
+	case e.Incoming:
+		return "in"
+	}
+	return "out"
+}
+
+// checks whether the entry passes the filter
+func (f *Export_Filter) match(e rpc.Entry) bool {
+	switch {
+	case e.Coinbase && !f.Coinbase:
+		return false
+	case e.Incoming && !e.Coinbase && !f.In:
+		return false
+	case !(e.Incoming || e.Coinbase) && !f.Out:
+		return false
+	}
+
+	if e.Height < f.Min_Height || (f.Max_Height != 0 && e.Height > f.Max_Height) {
+		return false
+	}
+	if (!f.Min_Time.IsZero() && e.Time.Before(f.Min_Time)) || (!f.Max_Time.IsZero() && e.Time.After(f.Max_Time)) {
+		return false
+	}
+	if f.DestinationPort != 0 && e.DestinationPort != f.DestinationPort {
+		return false
+	}
+	return true
+}
+
+// collects all entries matching the filter, DERO is always exported first followed by tokens
+// running balance is calculated over the complete history, so it stays correct even when filtering
+// if wallet only tracks recent blocks, running balance starts from the oldest tracked entry
+func (w *Wallet_Memory) Export_Transfers(f Export_Filter) (records []Export_Entry) {
+	w.Lock()
+	defer w.Unlock()
+
+	scids := f.SCIDs
+	if len(scids) == 0 {
+		for scid := range w.account.EntriesNative {
+			scids = append(scids, scid)
+		}
+		sort.Slice(scids, func(i, j int) bool {
+			if scids[i].IsZero() != scids[j].IsZero() {
+				return scids[i].IsZero()
+			}
+			return scids[i].String() < scids[j].String()
+		})
+	}
+
+	for _, scid := range scids {
+		var balance uint64
+		for _, e := range w.account.EntriesNative[scid] {
+			if e.Coinbase || e.Incoming {
+				balance += e.Amount
+			} else if e.Amount+e.Fees <= balance { // burn is already included in amount
+				balance -= e.Amount + e.Fees
+			} else { // history is incomplete
+				balance = 0
+			}
+
+			if len(e.Payload_RPC) == 0 && len(e.Payload) > 0 && e.PayloadError == "" {
+				e.ProcessPayload()
+			}
+
+			if !e.Coinbase && e.Incoming {
+				e.Fees = 0 // fees are paid by the sender
+			}
+
+			if f.match(e) {
+				records = append(records, Export_Entry{SCID: scid, Direction: entry_direction(e), Balance: balance, Entry: e})
+			}
+		}
+	}
+	return
+}
+
+var export_csv_header = []string{"scid", "height", "topoheight", "time", "blockhash", "txid", "direction", "amount", "fees", "burn", "balance", "destination", "sender", "dstport", "srcport", "proof", "payload_rpc", "payloaderror"}
+
+// writes records one per line as csv, amounts are in atomic units
+func Write_Transfers_CSV(out io.Writer, records []Export_Entry) (err error) {
+	cw := csv.NewWriter(out)
+	if err = cw.Write(export_csv_header); err != nil {
+		return
+	}
+
+	for _, r := range records {
+		args := ""
+		if len(r.Payload_RPC) > 0 {
+			var args_json []byte
+			if args_json, err = json.Marshal(r.Payload_RPC); err != nil {
+				return
+			}
+			args = string(args_json)
+		}
+
+		row := []string{
+			r.SCID.String(),
+			fmt.Sprintf("%d", r.Height),
+			fmt.Sprintf("%d", r.TopoHeight),
+			r.Time.UTC().Format(time.RFC3339),
+			r.BlockHash,
+			r.TXID,
+			r.Direction,
+			fmt.Sprintf("%d", r.Amount),
+			fmt.Sprintf("%d", r.Fees),
+			fmt.Sprintf("%d", r.Burn),
+			fmt.Sprintf("%d", r.Balance),
+			r.Destination,
+			r.Sender,
+			fmt.Sprintf("%d", r.DestinationPort),
+			fmt.Sprintf("%d", r.SourcePort),
+			r.Proof,
+			args,
+			r.PayloadError,
+		}
+		if err = cw.Write(row); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writes records as json lines, one json object per line
+func Write_Transfers_JSONL(out io.Writer, records []Export_Entry) (err error) {
+	enc := json.NewEncoder(out)
+	for _, r := range records {
+		if err = enc.Encode(r); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// export transfers matching the filter in the requested format
+func (w *Wallet_Memory) Export_Transfers_To(out io.Writer, format string, f Export_Filter) (err error) {
+	records := w.Export_Transfers(f)
+	switch strings.ToLower(format) {
+	case EXPORT_CSV:
+		return Write_Transfers_CSV(out, records)
+	case EXPORT_JSONL, "json":
+		return Write_Transfers_JSONL(out, records)
+	}
+	return fmt.Errorf("unknown export format '%s'", format)
+}
@@ -0,0 +1,110 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi
+
+import "bytes"
+import "strings"
+import "testing"
+import "time"
+import "encoding/csv"
+import "encoding/json"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// running balance must be calculated over complete history, even if entries are filtered
+func Test_Export_Transfers(t *testing.T) {
+	var zeroscid crypto.Hash
+	token := crypto.HashHexToHash("0000000000000000000000000000000000000000000000000000000000000001")
+
+	payload, err := rpc.Arguments{{Name: rpc.RPC_DESTINATION_PORT, DataType: rpc.DataUint64, Value: uint64(1337)}}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("cannot pack arguments err %s", err)
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := &Wallet_Memory{account: &Account{EntriesNative: map[crypto.Hash][]rpc.Entry{
+		zeroscid: {
+			{Height: 10, TopoHeight: 10, TransactionPos: -1, Coinbase: true, Amount: 1000, Time: start},
+			{Height: 20, TopoHeight: 20, Incoming: true, Amount: 500, Fees: 90, TXID: "aa", Payload: payload, Time: start.Add(24 * time.Hour)},
+			{Height: 30, TopoHeight: 30, Amount: 300, Burn: 100, Fees: 50, TXID: "bb", Status: 1, Time: start.Add(48 * time.Hour)},
+		},
+		token: {
+			{Height: 25, TopoHeight: 25, Incoming: true, Amount: 7, TXID: "cc", Time: start.Add(24 * time.Hour)},
+		},
+	}}}
+
+	records := w.Export_Transfers(Export_Filter{Coinbase: true, In: true, Out: true})
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d", len(records))
+	}
+	if records[0].SCID != zeroscid || records[3].SCID != token {
+		t.Fatalf("DERO must be exported before tokens")
+	}
+
+	expected_balances := []uint64{1000, 1500, 1150, 7}
+	for i := range records {
+		if records[i].Balance != expected_balances[i] {
+			t.Fatalf("record %d expected balance %d actual %d", i, expected_balances[i], records[i].Balance)
+		}
+	}
+	if records[1].Fees != 0 || records[1].DestinationPort != 1337 || len(records[1].Payload_RPC) != 1 {
+		t.Fatalf("incoming record not processed correctly %+v", records[1])
+	}
+
+	// filter by direction, running balance must stay intact
+	records = w.Export_Transfers(Export_Filter{Out: true})
+	if len(records) != 1 || records[0].Direction != "out" || records[0].Balance != 1150 {
+		t.Fatalf("out filter failed %+v", records)
+	}
+
+	records = w.Export_Transfers(Export_Filter{Coinbase: true, In: true, Out: true, Min_Time: start.Add(time.Hour), Max_Time: start.Add(25 * time.Hour), SCIDs: []crypto.Hash{zeroscid}})
+	if len(records) != 1 || records[0].TXID != "aa" {
+		t.Fatalf("time filter failed %+v", records)
+	}
+
+	records = w.Export_Transfers(Export_Filter{Coinbase: true, In: true, Out: true, DestinationPort: 1337})
+	if len(records) != 1 || records[0].TXID != "aa" {
+		t.Fatalf("dstport filter failed %+v", records)
+	}
+
+	var buf bytes.Buffer
+	if err := w.Export_Transfers_To(&buf, EXPORT_CSV, Export_Filter{Coinbase: true, In: true, Out: true}); err != nil {
+		t.Fatalf("csv export failed err %s", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 5 || len(rows[0]) != len(export_csv_header) {
+		t.Fatalf("csv export is invalid err %s rows %d", err, len(rows))
+	}
+
+	buf.Reset()
+	if err := w.Export_Transfers_To(&buf, EXPORT_JSONL, Export_Filter{Coinbase: true, In: true, Out: true}); err != nil {
+		t.Fatalf("jsonl export failed err %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 json lines, got %d", len(lines))
+	}
+	var record Export_Entry
+	if err := json.Unmarshal([]byte(lines[2]), &record); err != nil || record.Balance != 1150 || record.Burn != 100 {
+		t.Fatalf("jsonl record is invalid err %s record %+v", err, record)
+	}
+
+	if err := w.Export_Transfers_To(&buf, "xml", Export_Filter{}); err == nil {
+		t.Fatalf("unknown format must fail")
+	}
+}
@@ -35,7 +35,6 @@ import "github.com/deroproject/derohe/rpc"
 import "github.com/deroproject/derohe/config"
 import "github.com/deroproject/derohe/globals"
 import "github.com/deroproject/derohe/cryptography/crypto"
-import "github.com/deroproject/derohe/walletapi/mnemonics"
 
 // address book will have random number based entries
 
@@ -68,10 +67,14 @@ type Wallet_Memory struct {
 	wallet_online_mode bool // set whether the mode is online or offline
 	// an offline wallet can be converted to online mode, calling.
 	// SetOffline() and vice versa using SetOnline
-	// used to create transaction with this fee rate,
-	//if this is lower than network, then created transaction will be rejected by network
+
+	light_mode bool         // verify balances using merkle proofs and block headers
+	light      light_client // headers verified in light mode
+	// fee per KB daemon estimates for next block, in auto mode fee multiplier is derived from this
 	dynamic_fees_per_kb uint64
-	Quit                chan bool `json:"-"` // channel to quit any processing go routines
+	dynamic_fees_time   time.Time  // when dynamic_fees_per_kb was obtained from daemon
+	dynamic_fees_mutex  sync.Mutex // protects above 2
+	Quit                chan bool  `json:"-"` // channel to quit any processing go routines
 
 	db_memory   []byte       // all data is stored here
 	wallet_disk *Wallet_Disk // a loopback pointer for some operations
@@ -81,6 +84,8 @@ type Wallet_Memory struct {
 	Error error `json:"-"`
 
 	transfer_mutex sync.Mutex // to avoid races within the transfer
+
+	outbox_builds map[crypto.Hash]Outbox_Build // build params of txs not yet sent
 	//sync.Mutex  // used to syncronise access
 	sync.RWMutex
 
@@ -129,7 +134,7 @@ func Create_Encrypted_Wallet_Memory(password string, seed *crypto.BNRed) (w *Wal
 // create an encrypted wallet using electrum recovery words
 func Create_Encrypted_Wallet_From_Recovery_Words_Memory(password string, electrum_seed string) (w *Wallet_Memory, err error) {
 
-	language, seed, err := mnemonics.Words_To_Key(electrum_seed)
+	language, seed, err := recovery_words_to_key(electrum_seed)
 	if err != nil {
 		return
 	}
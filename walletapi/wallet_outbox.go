@@ -0,0 +1,268 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/deroproject/derohe/config"
+	"github.com/deroproject/derohe/cryptography/crypto"
+	"github.com/deroproject/derohe/rpc"
+	"github.com/deroproject/derohe/transaction"
+)
+
+// this file implements an outbox of sent txs, which are tracked till they become stable
+// txs dropped by the daemon are rebroadcast, if rebroadcast fails they are rebuilt with fresh ring members
+
+const (
+	OUTBOX_PENDING = "pending"
+	OUTBOX_MEMPOOL = "in-mempool"
+	OUTBOX_MINED   = "mined"
+	OUTBOX_STABLE  = "stable"
+	OUTBOX_FAILED  = "failed"
+)
+
+const OUTBOX_MAX_REBUILDS = 3                                                // tx is marked failed after these many rebuilds
+const OUTBOX_DROP_GRACE = 2 * time.Duration(config.BLOCK_TIME) * time.Second // tx must be missing this long before it is considered dropped
+const OUTBOX_MAX_FINISHED = 100                                              // only these many stable/failed entries are kept
+const outbox_max_builds = 32                                                 // build params are remembered for these many unsent txs
+
+// parameters used to build a tx, so it can be rebuilt with fresh ring members
+type Outbox_Build struct {
+	Transfers []rpc.Transfer `json:"transfers"`
+	Ringsize  uint64         `json:"ringsize"`
+	SCDATA    rpc.Arguments  `json:"scdata"`
+	Fees      uint64         `json:"fees"`
+}
+
+// persisted outbox record
+type Outbox_Record struct {
+	rpc.Outbox_Entry
+	TX_Hex string        `json:"tx_hex"`
+	Build  *Outbox_Build `json:"build,omitempty"` // nil if tx cannot be rebuilt
+}
+
+func (r *Outbox_Record) finished() bool {
+	return r.State == OUTBOX_STABLE || r.State == OUTBOX_FAILED
+}
+
+// remember how a tx was built, it is moved to outbox once tx is sent
+func (w *Wallet_Memory) outbox_remember(txid crypto.Hash, build Outbox_Build) {
+	w.Lock()
+	defer w.Unlock()
+	if w.outbox_builds == nil || len(w.outbox_builds) >= outbox_max_builds {
+		w.outbox_builds = map[crypto.Hash]Outbox_Build{}
+	}
+	w.outbox_builds[txid] = build
+}
+
+// add a successfully sent tx to the outbox
+func (w *Wallet_Memory) outbox_add(tx *transaction.Transaction) {
+	txid := tx.GetHash()
+	now := time.Now()
+
+	w.Lock()
+	defer w.Unlock()
+
+	for i := range w.account.Outbox { // already being tracked
+		if w.account.Outbox[i].TXID == txid.String() {
+			return
+		}
+	}
+
+	r := Outbox_Record{Outbox_Entry: rpc.Outbox_Entry{TXID: txid.String(), State: OUTBOX_PENDING, Created: now, LastSent: now}, TX_Hex: hex.EncodeToString(tx.Serialize())}
+	if build, ok := w.outbox_builds[txid]; ok {
+		r.Build = &build
+		delete(w.outbox_builds, txid)
+	}
+	w.account.Outbox = append(w.account.Outbox, r)
+
+	// prune oldest finished entries
+	finished := 0
+	for i := range w.account.Outbox {
+		if w.account.Outbox[i].finished() {
+			finished++
+		}
+	}
+	for i := 0; finished > OUTBOX_MAX_FINISHED && i < len(w.account.Outbox); {
+		if w.account.Outbox[i].finished() {
+			w.account.Outbox = append(w.account.Outbox[:i], w.account.Outbox[i+1:]...)
+			finished--
+			continue
+		}
+		i++
+	}
+}
+
+// returns outbox entries, optionally filtered by state
+func (w *Wallet_Memory) GetOutbox(state string) (entries []rpc.Outbox_Entry) {
+	w.RLock()
+	defer w.RUnlock()
+	for _, r := range w.account.Outbox {
+		if state == "" || state == r.State {
+			entries = append(entries, r.Outbox_Entry)
+		}
+	}
+	return
+}
+
+// update record as per daemon response for hashes, which are current and previous txids
+// returns true if tx is not known to daemon since grace period and needs rebroadcast
+func (r *Outbox_Record) update(hashes []string, result rpc.GetTransaction_Result, topoheight int64, now time.Time) (dropped bool) {
+	if len(result.Txs) != len(hashes) || len(result.Txs_as_hex) != len(hashes) {
+		return false // malformed response, try next time
+	}
+
+	in_pool := false
+	for i := range hashes {
+		if result.Txs_as_hex[i] == "" {
+			continue
+		}
+		if result.Txs[i].ValidBlock != "" && result.Txs[i].Block_Height >= 0 { // mined, possibly an earlier attempt
+			if hashes[i] != r.TXID {
+				r.Previous = append(r.Previous, r.TXID)
+				for j := range r.Previous {
+					if r.Previous[j] == hashes[i] {
+						r.Previous = append(r.Previous[:j], r.Previous[j+1:]...)
+						break
+					}
+				}
+				r.TXID = hashes[i]
+			}
+			r.TopoHeight = result.Txs[i].Block_Height
+			r.State = OUTBOX_MINED
+			if topoheight-r.TopoHeight >= config.STABLE_LIMIT {
+				r.State = OUTBOX_STABLE
+			}
+			r.Error = ""
+			return false
+		}
+		if result.Txs[i].In_pool {
+			in_pool = true
+		}
+	}
+
+	if in_pool {
+		r.State = OUTBOX_MEMPOOL
+		r.TopoHeight = 0
+		return false
+	}
+
+	// not in pool, not in main chain, tx was dropped or reorganised out
+	r.TopoHeight = 0
+	if r.State != OUTBOX_PENDING {
+		r.State = OUTBOX_PENDING
+		r.LastSent = now // give it grace period before rebroadcasting
+	}
+	return now.Sub(r.LastSent) >= OUTBOX_DROP_GRACE
+}
+
+// check status of all unfinished txs, rebroadcast or rebuild as required
+func (w *Wallet_Memory) outbox_process() {
+	if !IsDaemonOnline() {
+		return
+	}
+
+	w.RLock()
+	var records []Outbox_Record
+	for _, r := range w.account.Outbox {
+		if !r.finished() {
+			records = append(records, r)
+		}
+	}
+	w.RUnlock()
+
+	for _, r := range records {
+		original_txid := r.TXID
+		hashes := append([]string{r.TXID}, r.Previous...)
+
+		var result rpc.GetTransaction_Result
+		if err := rpc_client.Call("DERO.GetTransaction", rpc.GetTransaction_Params{Tx_Hashes: hashes}, &result); err != nil {
+			logger.V(1).Error(err, "outbox DERO.GetTransaction failed", "txid", r.TXID)
+			return
+		}
+
+		if r.update(hashes, result, Get_Daemon_TopoHeight(), time.Now()) {
+			w.outbox_resend(&r)
+		}
+
+		w.Lock()
+		for i := range w.account.Outbox {
+			if w.account.Outbox[i].TXID == original_txid {
+				w.account.Outbox[i] = r
+				break
+			}
+		}
+		w.Unlock()
+	}
+}
+
+// rebroadcast the tx as is, if daemon rejects it, rebuild it with fresh ring members
+func (w *Wallet_Memory) outbox_resend(r *Outbox_Record) {
+	r.LastSent = time.Now()
+
+	err := w.send_raw_transaction(r.TX_Hex)
+	if err == nil {
+		logger.V(1).Info("outbox rebroadcast tx", "txid", r.TXID)
+		return
+	}
+	r.Error = err.Error()
+
+	if r.Build == nil || r.Rebuilds >= OUTBOX_MAX_REBUILDS {
+		r.State = OUTBOX_FAILED
+		logger.Error(err, "outbox tx failed", "txid", r.TXID)
+		return
+	}
+
+	r.Rebuilds++
+	tx, err := w.TransferPayload0(append([]rpc.Transfer{}, r.Build.Transfers...), r.Build.Ringsize, false, r.Build.SCDATA, r.Build.Fees, false)
+	if err == nil {
+		tx_hex := hex.EncodeToString(tx.Serialize())
+		if err = w.send_raw_transaction(tx_hex); err == nil {
+			logger.Info("outbox rebuilt tx with fresh ring members", "txid", tx.GetHash().String(), "previous", r.TXID)
+			r.Previous = append(r.Previous, r.TXID)
+			r.TXID = tx.GetHash().String()
+			r.TX_Hex = tx_hex
+			r.Error = ""
+
+			w.Lock()
+			delete(w.outbox_builds, tx.GetHash())
+			w.Unlock()
+			return
+		}
+	}
+	r.Error = err.Error()
+	logger.V(1).Error(err, "outbox rebuild failed", "txid", r.TXID)
+}
+
+// relay tx to daemon
+func (w *Wallet_Memory) send_raw_transaction(tx_hex string) (err error) {
+	if !IsDaemonOnline() {
+		return fmt.Errorf("offline or not connected. cannot send transaction.")
+	}
+
+	var result rpc.SendRawTransaction_Result
+	if err = rpc_client.Call("DERO.SendRawTransaction", rpc.SendRawTransaction_Params{Tx_as_hex: tx_hex}, &result); err != nil {
+		return
+	}
+	if result.Status != "OK" {
+		err = fmt.Errorf("Err %s", result.Status)
+	}
+	return
+}
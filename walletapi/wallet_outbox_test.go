@@ -0,0 +1,106 @@
+// Copyright 2017-2021 DERO Project. All rights reserved.
+// Use of this source code in any form is governed by RESEARCH license.
+// license can be found in the LICENSE file.
+// GPG: 0F39 E425 8C65 3947 702A  8234 08B2 0360 A03A 9DE8
+//
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND ANY
+// EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL
+// THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package walletapi
+
+import "testing"
+import "time"
+import "encoding/json"
+
+import "github.com/deroproject/derohe/rpc"
+import "github.com/deroproject/derohe/config"
+import "github.com/deroproject/derohe/cryptography/crypto"
+
+// check state transitions of outbox records as per daemon responses
+func Test_Outbox_Update(t *testing.T) {
+	now := time.Now()
+	r := Outbox_Record{Outbox_Entry: rpc.Outbox_Entry{TXID: "aa", Previous: []string{"bb"}, State: OUTBOX_PENDING, LastSent: now}}
+	hashes := []string{"aa", "bb"}
+
+	notfound := rpc.GetTransaction_Result{Txs_as_hex: []string{"", ""}, Txs: make([]rpc.Tx_Related_Info, 2)}
+	if r.update(hashes, notfound, 100, now.Add(time.Second)) || r.State != OUTBOX_PENDING {
+		t.Fatalf("tx must not be considered dropped within grace period %+v", r)
+	}
+	if !r.update(hashes, notfound, 100, now.Add(OUTBOX_DROP_GRACE)) {
+		t.Fatalf("tx must be considered dropped after grace period %+v", r)
+	}
+
+	inpool := rpc.GetTransaction_Result{Txs_as_hex: []string{"00", ""}, Txs: []rpc.Tx_Related_Info{{In_pool: true, Block_Height: -1}, {}}}
+	if r.update(hashes, inpool, 100, now) || r.State != OUTBOX_MEMPOOL {
+		t.Fatalf("tx must be in mempool %+v", r)
+	}
+
+	// mempool was flushed, grace period restarts
+	later := now.Add(time.Hour)
+	if r.update(hashes, notfound, 100, later) || r.State != OUTBOX_PENDING || r.LastSent != later {
+		t.Fatalf("dropped tx must become pending %+v", r)
+	}
+
+	// an earlier attempt got mined
+	mined := rpc.GetTransaction_Result{Txs_as_hex: []string{"", "00"}, Txs: []rpc.Tx_Related_Info{{}, {ValidBlock: "cc", Block_Height: 100}}}
+	if r.update(hashes, mined, 100, now) || r.State != OUTBOX_MINED || r.TXID != "bb" || len(r.Previous) != 1 || r.Previous[0] != "aa" || r.TopoHeight != 100 {
+		t.Fatalf("mined tx not detected %+v", r)
+	}
+
+	hashes = []string{"bb", "aa"}
+	mined = rpc.GetTransaction_Result{Txs_as_hex: []string{"00", ""}, Txs: []rpc.Tx_Related_Info{{ValidBlock: "cc", Block_Height: 100}, {}}}
+	if r.update(hashes, mined, 100+config.STABLE_LIMIT, now) || r.State != OUTBOX_STABLE || !r.finished() {
+		t.Fatalf("stable tx not detected %+v", r)
+	}
+
+	if r.update(hashes, rpc.GetTransaction_Result{}, 100, later) || r.State != OUTBOX_STABLE {
+		t.Fatalf("malformed response must be ignored %+v", r)
+	}
+}
+
+// outbox must survive wallet serialization, including build parameters
+func Test_Outbox_Serialization(t *testing.T) {
+	scid := crypto.HashHexToHash("0000000000000000000000000000000000000000000000000000000000000001")
+	account := Account{Outbox: []Outbox_Record{{
+		Outbox_Entry: rpc.Outbox_Entry{TXID: "aa", State: OUTBOX_PENDING},
+		TX_Hex:       "00",
+		Build: &Outbox_Build{Transfers: []rpc.Transfer{{SCID: scid, Burn: 10}}, Ringsize: 16, SCDATA: rpc.Arguments{
+			{Name: rpc.SCACTION, DataType: rpc.DataUint64, Value: uint64(rpc.SC_CALL)},
+			{Name: rpc.SCID, DataType: rpc.DataHash, Value: scid}}},
+	}, {
+		Outbox_Entry: rpc.Outbox_Entry{TXID: "bb", State: OUTBOX_STABLE},
+	}}}
+
+	data, err := json.Marshal(&account)
+	if err != nil {
+		t.Fatalf("cannot serialize account err %s", err)
+	}
+	var restored Account
+	if err = json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("cannot deserialize account err %s", err)
+	}
+
+	if len(restored.Outbox) != 2 || restored.Outbox[1].Build != nil {
+		t.Fatalf("outbox not restored %+v", restored.Outbox)
+	}
+	build := restored.Outbox[0].Build
+	if build == nil || build.Ringsize != 16 || build.Transfers[0].SCID != scid || build.SCDATA.Value(rpc.SCID, rpc.DataHash).(crypto.Hash) != scid {
+		t.Fatalf("build parameters not restored %+v", build)
+	}
+
+	w := &Wallet_Memory{account: &restored}
+	if entries := w.GetOutbox(OUTBOX_STABLE); len(entries) != 1 || entries[0].TXID != "bb" {
+		t.Fatalf("outbox filter failed %+v", entries)
+	}
+	if entries := w.GetOutbox(""); len(entries) != 2 {
+		t.Fatalf("outbox must return all entries %+v", entries)
+	}
+}
@@ -20,6 +20,8 @@ import "fmt"
 import "testing"
 import "strings"
 
+import "github.com/deroproject/derohe/walletapi/mnemonics"
+
 // we are covering atleast one test case each for all supported languages
 
 func Test_Wallet_Generation_and_Recovery(t *testing.T) {
@@ -158,3 +160,25 @@ func Test_Wallet_Generation_and_Recovery(t *testing.T) {
 	}
 
 }
+
+// shares supplied one after another must recover the same account as the seed
+func Test_Wallet_Recovery_From_Shares(t *testing.T) {
+	seed := "sequence atlas unveil summon pebbles tuesday beer rudely snake rockets different fuselage woven tagged bested dented vegan hover rapid fawns obvious muppet randomly seasons randomly"
+	account, err := Generate_Account_From_Recovery_Words(seed)
+	if err != nil {
+		t.Fatalf("Mnemonics testing failed err %s", err)
+	}
+
+	shares, err := mnemonics.Key_To_Shares(account.Keys.Secret.BigInt(), 1, []mnemonics.Share_Group{{Threshold: 2, Count: 3}}, account.SeedLanguage)
+	if err != nil {
+		t.Fatalf("splitting seed failed err %s", err)
+	}
+
+	recovered, err := Generate_Account_From_Recovery_Words(shares[0][2] + " " + shares[0][0])
+	if err != nil || recovered.SeedLanguage != account.SeedLanguage {
+		t.Fatalf("recovering account from shares failed err %s", err)
+	}
+	if recovered.GetAddress().String() != account.GetAddress().String() {
+		t.Fatalf("recovered address mismatch expected %s actual %s", account.GetAddress().String(), recovered.GetAddress().String())
+	}
+}
@@ -87,6 +87,8 @@ func (w *Wallet_Memory) TransferPayload0(transfers []rpc.Transfer, ringsize uint
 	w.transfer_mutex.Lock()
 	defer w.transfer_mutex.Unlock()
 
+	build := Outbox_Build{Transfers: append([]rpc.Transfer{}, transfers...), Ringsize: ringsize, SCDATA: scdata, Fees: gasstorage}
+
 	//if len(transfers) == 0 {
 	//	return nil,  fmt.Error("transfers is nil, cannot send.")
 	//}
@@ -106,16 +108,7 @@ func (w *Wallet_Memory) TransferPayload0(transfers []rpc.Transfer, ringsize uint
 
 	//ringsize = 2
 
-	// if wallet is online,take the fees from the network itself
-	// otherwise use whatever user has provided
-	//if w.GetMode()  {
-	fees_per_kb := w.dynamic_fees_per_kb // TODO disabled as protection while lots more testing is going on
-	//rlog.Infof("Fees per KB %d\n", fees_per_kb)
-	//}
-
-	if fees_per_kb == 0 {
-		fees_per_kb = config.FEE_PER_KB
-	}
+	// fees are computed while building the tx using GetFeeMultiplier, which follows daemon estimate in auto mode
 
 	// user wants to do an SC call, but doesn't want any transfer, so we will transfer 0 to a random account
 	if len(scdata) >= 1 && len(transfers) == 0 {
@@ -420,6 +413,8 @@ func (w *Wallet_Memory) TransferPayload0(transfers []rpc.Transfer, ringsize uint
 
 	if tx == nil {
 		err = fmt.Errorf("somehow the tx could not be built, please retry")
+	} else {
+		w.outbox_remember(tx.GetHash(), build) // so it can be rebuilt if required
 	}
 
 	return